@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+// runAnalyze implements "analyze [-base URL] [-check-links] [-format F]
+// [-o FILE] [FILE | -]". Unlike diff and report it runs in-process, since
+// analyzing a document never writes the history file.
+func runAnalyze(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	baseURL := flags.String("base", "", "URL the document is served from, used to classify and resolve links")
+	checkLinks := flags.Bool("check-links", false, "check the document's links")
+	modules := flags.String("modules", "", "comma-separated analyzer modules to run, e.g. seo,accessibility")
+	rulesPath := flags.String("rules", "", "JSON rules file to check the document against")
+	format := flags.String("format", "json", "report format: csv, json, junit, markdown or sarif")
+	output := flags.String("o", "", "write the report to this file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: main analyze [-base URL] [-check-links] [-modules LIST] [-rules FILE] [-format F] [-o FILE] [FILE | -]")
+		fmt.Fprintln(stderr, "Analyzes an HTML file, or standard input when FILE is omitted or -.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 2
+	}
+	writer, err := service.ReportWriterFor(*format)
+	if err != nil {
+		fmt.Fprintln(stderr, "analyze:", err)
+		return 2
+	}
+
+	moduleNames := splitList(*modules)
+	if *rulesPath != "" {
+		rules, err := service.LoadRulesFile(*rulesPath)
+		if err != nil {
+			fmt.Fprintln(stderr, "analyze:", err)
+			return 2
+		}
+		service.RegisterRules(rules)
+		moduleNames = append(moduleNames, analyzer.RulesModule)
+	}
+
+	input := stdin
+	if name := flags.Arg(0); name != "" && name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(stderr, "analyze:", err)
+			return 1
+		}
+		defer file.Close()
+		input = file
+	}
+
+	// Keep stdout for the report.
+	logger.GetLogger().SetOutput(stderr)
+	logger.GetLogger().SetLevel(logrus.WarnLevel)
+
+	result, err := service.NewAnalysisService().AnalyzeDocument(context.Background(), input, service.DocumentOptions{
+		BaseURL:    *baseURL,
+		CheckLinks: *checkLinks,
+		Modules:    moduleNames,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "analyze:", err)
+		return 1
+	}
+
+	out := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(stderr, "analyze:", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	if err := writer.Write(out, result); err != nil {
+		fmt.Fprintln(stderr, "analyze:", err)
+		return 1
+	}
+	return 0
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+const defaultServerURL = "http://localhost:8080"
+
+// runDiff implements "diff [-server URL] [-json] <base-id> [<target-id>]". The
+// comparison is requested from a running server so that the history file is
+// only ever written by one process.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	serverURL := flags.String("server", envOr("ANALYZER_URL", defaultServerURL), "base URL of the analyzer server")
+	asJSON := flags.Bool("json", false, "print the diff as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: main diff [-server URL] [-json] <base-id> [<target-id>]")
+		fmt.Fprintln(stderr, "Compares two stored analyses, or a stored analysis with a fresh run when target-id is omitted.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 2
+	}
+
+	// Comparing with a fresh run analyzes the page again, which the API only
+	// does for a POST.
+	endpoint := strings.TrimSuffix(*serverURL, "/") + "/api/history/" + url.PathEscape(flags.Arg(0)) + "/diff"
+	method := http.MethodPost
+	if flags.NArg() == 2 {
+		endpoint += "?with=" + url.QueryEscape(flags.Arg(1))
+		method = http.MethodGet
+	}
+
+	body, err := fetchAPI(method, endpoint)
+	if err != nil {
+		fmt.Fprintln(stderr, "diff:", err)
+		return 1
+	}
+
+	if *asJSON {
+		stdout.Write(body)
+		return 0
+	}
+	var diff service.AnalysisDiff
+	if err := json.Unmarshal(body, &diff); err != nil {
+		fmt.Fprintln(stderr, "diff: invalid response:", err)
+		return 1
+	}
+	printDiff(stdout, &diff)
+	return 0
+}
+
+// printDiff writes a human-readable summary of diff.
+func printDiff(w io.Writer, diff *service.AnalysisDiff) {
+	const layout = "2006-01-02 15:04:05"
+	target := "fresh run"
+	if diff.Target.ID != 0 {
+		target = fmt.Sprintf("#%d", diff.Target.ID)
+	}
+	fmt.Fprintf(w, "%s\n#%d (%s) -> %s (%s)\n", diff.URL,
+		diff.Base.ID, diff.Base.AnalyzedAt.Format(layout), target, diff.Target.AnalyzedAt.Format(layout))
+
+	if diff.Empty() {
+		fmt.Fprintln(w, "No changes.")
+		return
+	}
+	if diff.Title != nil {
+		fmt.Fprintf(w, "Title: %q -> %q\n", diff.Title.From, diff.Title.To)
+	}
+	if diff.HTMLVersion != nil {
+		fmt.Fprintf(w, "HTML version: %s -> %s\n", diff.HTMLVersion.From, diff.HTMLVersion.To)
+	}
+	if diff.HasLoginForm != nil {
+		fmt.Fprintf(w, "Login form: %t -> %t\n", diff.HasLoginForm.From, diff.HasLoginForm.To)
+	}
+	for _, heading := range diff.Headings {
+		fmt.Fprintf(w, "%s headings: %d -> %d\n", heading.Level, heading.From, heading.To)
+	}
+	for _, meta := range diff.Meta {
+		fmt.Fprintf(w, "Meta %s: %q -> %q\n", meta.Name, meta.From, meta.To)
+	}
+	fmt.Fprintf(w, "Links: %d -> %d, inaccessible: %d -> %d\n",
+		diff.LinkCount.From, diff.LinkCount.To, diff.BrokenCount.From, diff.BrokenCount.To)
+
+	for _, section := range []struct {
+		prefix string
+		title  string
+		links  []string
+	}{
+		{"!", "Newly broken", diff.NewlyBroken},
+		{"*", "Newly fixed", diff.NewlyFixed},
+		{"+", "Added", diff.AddedLinks},
+		{"-", "Removed", diff.RemovedLinks},
+	} {
+		if len(section.links) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", section.title)
+		for _, link := range section.links {
+			fmt.Fprintf(w, "  %s %s\n", section.prefix, link)
+		}
+	}
+
+	for _, section := range []struct {
+		prefix string
+		title  string
+		data   []analyzer.StructuredData
+	}{
+		{"+", "Added structured data", diff.AddedStructuredData},
+		{"-", "Removed structured data", diff.RemovedStructuredData},
+	} {
+		if len(section.data) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", section.title)
+		for _, data := range section.data {
+			fmt.Fprintf(w, "  %s %s\n", section.prefix, data.JSON)
+		}
+	}
+	for _, section := range []struct {
+		prefix   string
+		title    string
+		findings []analyzer.Finding
+	}{
+		{"+", "New findings", diff.NewFindings},
+		{"-", "Resolved findings", diff.ResolvedFindings},
+	} {
+		if len(section.findings) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s:\n", section.title)
+		for _, finding := range section.findings {
+			fmt.Fprintf(w, "  %s [%s] %s/%s: %s\n", section.prefix, finding.Severity, finding.Module, finding.Rule, finding.Message)
+		}
+	}
+}
+
+// fetchAPI requests endpoint from the analyzer server with method and returns
+// the body of a successful response, or the error reported by the API.
+func fetchAPI(method, endpoint string) ([]byte, error) {
+	req, err := http.NewRequest(method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 2 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = resp.Status
+		}
+		return nil, errors.New(apiErr.Error)
+	}
+	return body, nil
+}
+
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
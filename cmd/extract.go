@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+// runExtract implements "extract -t NAME [-templates FILE] [-format jsonl|csv]
+// [-o FILE] [-list FILE] [url...]". Pages are fetched in turn; a page that
+// fails is reported and skipped, and the exit status is 1.
+func runExtract(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	templatesPath := flags.String("templates", os.Getenv("EXTRACT_TEMPLATES_PATH"), "JSON extraction templates file")
+	name := flags.String("t", "", "name of the template to extract with")
+	format := flags.String("format", "jsonl", "output format: jsonl or csv")
+	output := flags.String("o", "", "write the records to this file instead of stdout")
+	listPath := flags.String("list", "", "file of URLs to extract from, one per line or as CSV")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: main extract -t NAME [-templates FILE] [-format jsonl|csv] [-o FILE] [-list FILE] [url...]")
+		fmt.Fprintln(stderr, "Extracts structured records from each page with a named template.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" || *templatesPath == "" || (*format != "jsonl" && *format != "csv") {
+		flags.Usage()
+		return 2
+	}
+	if err := service.LoadExtractionTemplatesFile(*templatesPath); err != nil {
+		fmt.Fprintln(stderr, "extract:", err)
+		return 2
+	}
+	template, err := service.ExtractionTemplate(*name)
+	if err != nil {
+		fmt.Fprintln(stderr, "extract:", err)
+		return 2
+	}
+
+	urls := flags.Args()
+	if *listPath != "" {
+		file, err := os.Open(*listPath)
+		if err != nil {
+			fmt.Fprintln(stderr, "extract:", err)
+			return 2
+		}
+		listed, err := service.ParseURLList(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintln(stderr, "extract:", err)
+			return 2
+		}
+		urls = append(urls, listed...)
+	}
+	if len(urls) == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	// Keep stdout for the records.
+	logger.GetLogger().SetOutput(stderr)
+	logger.GetLogger().SetLevel(logrus.WarnLevel)
+
+	analysisService := service.NewAnalysisService()
+	status := 0
+	var extractions []service.PageExtraction
+	for _, pageURL := range urls {
+		extraction, err := analysisService.ExtractPage(context.Background(), pageURL, *name)
+		if err != nil {
+			fmt.Fprintf(stderr, "extract: %s: %v\n", pageURL, err)
+			status = 1
+			continue
+		}
+		extractions = append(extractions, *extraction)
+	}
+
+	out := stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(stderr, "extract:", err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	if *format == "csv" {
+		err = service.WriteExtractionCSV(out, template.FieldNames(), extractions)
+	} else {
+		err = service.WriteExtractionJSONL(out, extractions)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "extract:", err)
+		return 1
+	}
+	return status
+}
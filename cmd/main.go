@@ -1,28 +1,113 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/snpiyasooriya/web-page-analyzer/internal/handler"
 	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/metrics"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "diff":
+			exit(runDiff(os.Args[2:], os.Stdout, os.Stderr))
+		case "analyze":
+			exit(runAnalyze(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "site":
+			exit(runSite(os.Args[2:], os.Stdout, os.Stderr))
+		case "report":
+			exit(runReport(os.Args[2:], os.Stdout, os.Stderr))
+		case "query":
+			exit(runQuery(os.Args[2:], os.Stdin, os.Stdout, os.Stderr))
+		case "extract":
+			exit(runExtract(os.Args[2:], os.Stdout, os.Stderr))
+		}
+	}
+
 	// Initialize logger
 	logger.Init()
 
 	logger.Info("Starting web page analyzer server...")
+	handler.LoadTemplates()
+	service.LoadRules()
+	service.LoadExtractionTemplates()
+
+	tracingConfig := tracing.ConfigFromEnv()
+	tracingConfig.OnError = func(err error) {
+		logger.WithField("error", err).Warn("Failed to export traces")
+	}
+	shutdownTracing, err := tracing.Init(context.Background(), tracingConfig)
+	if err != nil {
+		logger.WithField("error", err).Fatal("Failed to initialize tracing")
+	}
 
 	router := http.NewServeMux()
 
-	router.HandleFunc("GET /", handler.HomePageHandler)
-	router.HandleFunc("POST /analyze", handler.AnalysisHandler)
-	router.HandleFunc("GET /health", handler.HealthHandler)
+	router.HandleFunc("GET /", handler.Instrument(handler.HomePageHandler))
+	router.HandleFunc("POST /analyze", handler.Instrument(handler.AnalysisHandler))
+	router.HandleFunc("GET /health", handler.Instrument(handler.HealthHandler))
+	router.HandleFunc("GET /api/modules", handler.Instrument(handler.ModulesAPIHandler))
+	router.HandleFunc("GET /api/rules", handler.Instrument(handler.RulesAPIHandler))
+	router.HandleFunc("GET /cache/stats", handler.Instrument(handler.CacheStatsHandler))
+	router.HandleFunc("GET /history", handler.Instrument(handler.HistoryHandler))
+	router.HandleFunc("GET /history/{id}", handler.Instrument(handler.HistoryRecordHandler))
+	router.HandleFunc("GET /history/{id}/diff", handler.Instrument(handler.HistoryDiffHandler))
+	router.HandleFunc("POST /history/{id}/diff", handler.Instrument(handler.HistoryDiffHandler))
+	router.HandleFunc("GET /api/history", handler.Instrument(handler.HistoryAPIHandler))
+	router.HandleFunc("GET /api/history/{id}", handler.Instrument(handler.HistoryRecordAPIHandler))
+	router.HandleFunc("GET /api/history/{id}/diff", handler.Instrument(handler.HistoryDiffAPIHandler))
+	router.HandleFunc("POST /api/history/{id}/diff", handler.Instrument(handler.HistoryDiffAPIHandler))
+	router.HandleFunc("GET /api/history/{id}/report", handler.Instrument(handler.HistoryReportAPIHandler))
+	router.HandleFunc("GET /api/report", handler.Instrument(handler.ReportAPIHandler))
+	router.HandleFunc("POST /api/report", handler.Instrument(handler.DocumentReportAPIHandler))
+	router.HandleFunc("GET /api/query", handler.Instrument(handler.QueryAPIHandler))
+	router.HandleFunc("POST /api/query", handler.Instrument(handler.DocumentQueryAPIHandler))
+	router.HandleFunc("GET /api/extract", handler.Instrument(handler.ExtractAPIHandler))
+	router.HandleFunc("POST /api/extract", handler.Instrument(handler.DocumentExtractAPIHandler))
+	router.HandleFunc("GET /api/extract/templates", handler.Instrument(handler.ExtractionTemplatesAPIHandler))
+	router.HandleFunc("GET /batch", handler.Instrument(handler.BatchPageHandler))
+	router.HandleFunc("POST /batch", handler.Instrument(handler.BatchSubmitHandler))
+	router.HandleFunc("GET /batch/{id}", handler.Instrument(handler.BatchStatusHandler))
+	router.HandleFunc("GET /batch/{id}/report", handler.Instrument(handler.BatchReportHandler))
+	router.HandleFunc("POST /api/batches", handler.Instrument(handler.CreateBatchAPIHandler))
+	router.HandleFunc("GET /api/batches/{id}", handler.Instrument(handler.GetBatchAPIHandler))
+	router.HandleFunc("GET /api/monitors", handler.Instrument(handler.ListMonitorsHandler))
+	router.HandleFunc("POST /api/monitors", handler.Instrument(handler.CreateMonitorHandler))
+	router.HandleFunc("GET /api/monitors/{id}", handler.Instrument(handler.GetMonitorHandler))
+	router.HandleFunc("DELETE /api/monitors/{id}", handler.Instrument(handler.DeleteMonitorHandler))
+	router.HandleFunc("POST /api/monitors/{id}/run", handler.Instrument(handler.RunMonitorHandler))
+	router.Handle("GET /metrics", metrics.Handler())
 
-	logger.WithField("port", 8080).Info("Server starting on port 8080")
+	server := &http.Server{Addr: ":8080", Handler: handler.RequestID(router)}
 
-	err := http.ListenAndServe(":8080", router)
-	if err != nil {
+	logger.WithField("port", 8080).Info("Server starting on port 8080")
+	if err := serve(server, service.SharedMonitorRunner().Run); err != nil {
 		logger.WithField("error", err).Fatal("Failed to start server")
 	}
+	if err := service.FlushLinkCache(); err != nil {
+		logger.WithField("error", err).Warn("Failed to persist link cache")
+	}
+
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.WithField("error", err).Warn("Failed to flush traces")
+	}
+	logger.Info("Server stopped")
+}
+
+// exit persists the link cache and ends a subcommand with code.
+func exit(code int) {
+	if err := service.FlushLinkCache(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to persist link cache: %v\n", err)
+	}
+	os.Exit(code)
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+// stringList is a flag that may be repeated.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runQuery implements "query -s SELECTOR... [-limit N] [-json]
+// <url | FILE | ->". Like analyze it runs in-process.
+func runQuery(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("query", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var selectors stringList
+	flags.Var(&selectors, "s", "CSS selector to match; may be repeated")
+	limit := flags.Int("limit", service.DefaultQueryLimit, "matches listed per selector; 0 prints counts only")
+	asJSON := flags.Bool("json", false, "print tags, attributes, text and outer HTML as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: main query -s SELECTOR... [-limit N] [-json] <url | FILE | ->")
+		fmt.Fprintln(stderr, "Prints the elements of a page, an HTML file or standard input matching each selector.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 || len(selectors) == 0 || *limit < 0 {
+		flags.Usage()
+		return 2
+	}
+
+	// Keep stdout for the results.
+	logger.GetLogger().SetOutput(stderr)
+	logger.GetLogger().SetLevel(logrus.WarnLevel)
+
+	analysisService := service.NewAnalysisService()
+	ctx := context.Background()
+	var result *service.QueryResponse
+	var err error
+	switch target := flags.Arg(0); {
+	case strings.Contains(target, "://"):
+		result, err = analysisService.QueryPage(ctx, target, selectors, *limit)
+	case target == "-":
+		result, err = analysisService.QueryDocument(ctx, stdin, selectors, *limit)
+	default:
+		file, openErr := os.Open(target)
+		if openErr != nil {
+			fmt.Fprintln(stderr, "query:", openErr)
+			return 1
+		}
+		defer file.Close()
+		result, err = analysisService.QueryDocument(ctx, file, selectors, *limit)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "query:", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(stderr, "query:", err)
+			return 1
+		}
+		return 0
+	}
+	for _, r := range result.Results {
+		fmt.Fprintf(stdout, "%s: %d\n", r.Selector, r.Count)
+		for _, match := range r.Matches {
+			fmt.Fprintf(stdout, "  <%s> %s\n", match.Tag, match.Text)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// runReport implements "report [-server URL] [-format F] [-o FILE] [-refresh]
+// <url | history-id>". Like diff, it asks a running server for the report.
+func runReport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("report", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	serverURL := flags.String("server", envOr("ANALYZER_URL", defaultServerURL), "base URL of the analyzer server")
+	format := flags.String("format", "json", "report format: csv, json, junit, markdown or sarif")
+	output := flags.String("o", "", "write the report to this file instead of stdout")
+	refresh := flags.Bool("refresh", false, "re-analyze the page instead of using a cached result")
+	modules := flags.String("modules", "", "comma-separated analyzer modules to run, e.g. seo,accessibility")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: main report [-server URL] [-format F] [-o FILE] [-refresh] [-modules LIST] <url | history-id>")
+		fmt.Fprintln(stderr, "Writes a report of a page, analyzing it if needed, or of a stored analysis.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	server := strings.TrimSuffix(*serverURL, "/")
+	query := url.Values{"format": {*format}}
+	var endpoint string
+	if target := flags.Arg(0); strings.Contains(target, "://") {
+		query.Set("url", target)
+		if *refresh {
+			query.Set("refresh", "1")
+		}
+		if *modules != "" {
+			query.Set("modules", *modules)
+		}
+		endpoint = server + "/api/report?" + query.Encode()
+	} else {
+		endpoint = server + "/api/history/" + url.PathEscape(target) + "/report?" + query.Encode()
+	}
+
+	body, err := fetchAPI(http.MethodGet, endpoint)
+	if err != nil {
+		fmt.Fprintln(stderr, "report:", err)
+		return 1
+	}
+	if *output == "" {
+		stdout.Write(body)
+		return 0
+	}
+	if err := os.WriteFile(*output, body, 0o644); err != nil {
+		fmt.Fprintln(stderr, "report:", err)
+		return 1
+	}
+	return 0
+}
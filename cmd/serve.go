@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+// shutdownTimeout is how long in-flight requests may take to finish once a
+// shutdown signal arrives.
+const shutdownTimeout = 15 * time.Second
+
+// serve runs server until SIGINT or SIGTERM, then stops accepting requests
+// and waits for those in flight. background runs alongside the server with a
+// context that is cancelled on shutdown, and serve returns once it is done.
+func serve(server *http.Server, background func(context.Context)) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.WithField("error", err).Error("Failed to shut down server gracefully")
+		}
+	}()
+
+	backgroundDone := make(chan struct{})
+	go func() {
+		defer close(backgroundDone)
+		background(ctx)
+	}()
+
+	// ListenAndServe returns as soon as Shutdown starts, so wait for it to
+	// finish the requests in flight.
+	err := server.ListenAndServe()
+	stop()
+	<-shutdownDone
+	<-backgroundDone
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
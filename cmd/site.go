@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+// runSite implements "site [-base URL] [-check-external] [-json] <dir>". It
+// runs in-process and exits with 1 when any link is broken, so it can gate a
+// deploy. There is no HTTP equivalent, since it reads the server's filesystem.
+func runSite(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("site", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	baseURL := flags.String("base", "", "URL the directory is served at (default http://localhost/)")
+	checkExternal := flags.Bool("check-external", false, "check links outside the site over HTTP")
+	asJSON := flags.Bool("json", false, "print the report as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: main site [-base URL] [-check-external] [-json] <dir>")
+		fmt.Fprintln(stderr, "Checks the links of a static site build against its files and anchors.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	// Keep stdout for the report.
+	logger.GetLogger().SetOutput(stderr)
+	logger.GetLogger().SetLevel(logrus.WarnLevel)
+
+	report, err := service.NewAnalysisService().AnalyzeSite(context.Background(), flags.Arg(0), service.SiteOptions{
+		BaseURL:       *baseURL,
+		CheckExternal: *checkExternal,
+	})
+	if err != nil {
+		fmt.Fprintln(stderr, "site:", err)
+		return 2
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+	} else {
+		printSiteReport(stdout, report)
+	}
+	if len(report.Problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// printSiteReport writes one line per problem followed by a summary.
+func printSiteReport(w io.Writer, report *service.SiteReport) {
+	for _, problem := range report.Problems {
+		fmt.Fprintf(w, "%s: %s: %s", problem.Page, problem.Kind, problem.Link)
+		if problem.Detail != "" {
+			fmt.Fprintf(w, " (%s)", problem.Detail)
+		} else if problem.Target != problem.Link {
+			fmt.Fprintf(w, " (%s)", problem.Target)
+		}
+		fmt.Fprintln(w)
+	}
+	external := "not checked"
+	if report.ExternalChecked {
+		external = "checked"
+	}
+	fmt.Fprintf(w, "%d pages, %d problems, %d external links %s\n",
+		len(report.Pages), len(report.Problems), report.ExternalLinks, external)
+}
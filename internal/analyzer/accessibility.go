@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+func init() {
+	RegisterModule("accessibility", "Common accessibility problems: page language, image alternatives, link, button and form control names, heading order", func() Module {
+		return &accessibilityModule{labelled: make(map[string]bool)}
+	})
+}
+
+// accessibilityModule checks for problems that make a page hard to use with
+// assistive technology.
+type accessibilityModule struct {
+	hasLang     bool
+	lastHeading int
+	findings    []Finding
+	labelled    map[string]bool
+	// unlabelled are the form-label findings that a later <label for> may
+	// still withdraw.
+	unlabelled []deferredLabel
+}
+
+// deferredLabel is the form-label finding at index in findings, for the
+// control with the given id.
+type deferredLabel struct {
+	index int
+	id    string
+}
+
+// labelledInputTypes are the input types that need a label.
+var labelledInputTypes = map[string]bool{
+	"": true, "text": true, "email": true, "password": true, "search": true, "tel": true,
+	"url": true, "number": true, "date": true, "checkbox": true, "radio": true, "file": true,
+}
+
+func (m *accessibilityModule) Visit(n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	add := func(rule, severity, message string) {
+		m.findings = append(m.findings, Finding{Rule: rule, Severity: severity, Message: message, Element: describeElement(n)})
+	}
+
+	switch n.Data {
+	case "html":
+		if lang, _ := attr(n, "lang"); strings.TrimSpace(lang) != "" {
+			m.hasLang = true
+		}
+	case "img":
+		if _, ok := attr(n, "alt"); !ok && !hasAccessibleLabel(n) {
+			add("img-alt", SeverityError, `Image has no alt attribute; use alt="" for decorative images`)
+		}
+	case "a":
+		if _, ok := attr(n, "href"); ok && !hasAccessibleLabel(n) && accessibleText(n) == "" {
+			add("link-name", SeverityWarning, "Link has no text or accessible name")
+		}
+	case "button":
+		if !hasAccessibleLabel(n) && accessibleText(n) == "" {
+			add("button-name", SeverityWarning, "Button has no text or accessible name")
+		}
+	case "label":
+		if id, ok := attr(n, "for"); ok {
+			m.labelled[id] = true
+		}
+	case "input", "select", "textarea":
+		inputType, _ := attr(n, "type")
+		if n.Data == "input" && !labelledInputTypes[strings.ToLower(inputType)] {
+			return
+		}
+		if hasAccessibleLabel(n) || hasAncestor(n, "label") {
+			return
+		}
+		if id, ok := attr(n, "id"); ok && id != "" {
+			// A <label for> may follow the control; decide in Finalize.
+			m.unlabelled = append(m.unlabelled, deferredLabel{index: len(m.findings), id: id})
+		}
+		add("form-label", SeverityWarning, "Form control has no label")
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		if m.lastHeading > 0 && level > m.lastHeading+1 {
+			add("heading-order", SeverityWarning, fmt.Sprintf("Heading level jumps from h%d to h%d", m.lastHeading, level))
+		}
+		m.lastHeading = level
+	}
+}
+
+func (m *accessibilityModule) Finalize(result *AnalysisResult) {
+	if !m.hasLang {
+		result.Findings = append(result.Findings, Finding{
+			Rule: "html-lang", Severity: SeverityError, Message: "The <html> element has no lang attribute", Element: "<html>",
+		})
+	}
+	labelled := make(map[int]bool)
+	for _, control := range m.unlabelled {
+		if m.labelled[control.id] {
+			labelled[control.index] = true
+		}
+	}
+	for i, finding := range m.findings {
+		if !labelled[i] {
+			result.Findings = append(result.Findings, finding)
+		}
+	}
+}
+
+// hasAccessibleLabel reports whether n is named by an ARIA or title attribute.
+func hasAccessibleLabel(n *html.Node) bool {
+	for _, key := range []string{"aria-label", "aria-labelledby", "title"} {
+		if value, ok := attr(n, key); ok && strings.TrimSpace(value) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// accessibleText is the text of n including the alt text of its images.
+func accessibleText(n *html.Node) string {
+	text := textContent(n)
+	for c := range n.Descendants() {
+		if c.Type == html.ElementNode && c.Data == "img" {
+			if alt, _ := attr(c, "alt"); strings.TrimSpace(alt) != "" {
+				text += " " + alt
+			}
+		}
+	}
+	return strings.TrimSpace(text)
+}
+
+func hasAncestor(n *html.Node, tag string) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,10 +1,17 @@
 package analyzer
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/url"
 	"strings"
 
 	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/net/html"
 )
 
@@ -14,54 +21,214 @@ type AnalysisResult struct {
 	Headings     map[string]int
 	HasLoginForm bool
 	Links        []string
+	// Anchors are the fragment targets of the page: every id attribute and
+	// the name attribute of <a> elements.
+	Anchors []string `json:",omitempty"`
+	// Lang is the language declared by <html lang>, and ContentLanguage the
+	// languages the page is served in according to the Content-Language header
+	// or, without one, its <meta http-equiv> equivalent.
+	Lang            string `json:",omitempty"`
+	ContentLanguage string `json:",omitempty"`
+	// Meta maps the lower-cased name or property of each <meta> element with
+	// content to that content. Later elements win.
+	Meta map[string]string `json:",omitempty"`
+	// StructuredData lists the JSON-LD blocks of the page in document order.
+	StructuredData []StructuredData `json:",omitempty"`
+	// Findings are reported by the modules enabled for the analysis.
+	Findings []Finding `json:",omitempty"`
+	// Content measures the visible text of the page.
+	Content *ContentMetrics `json:",omitempty"`
+	// MixedContent lists the plain HTTP subresources and form actions of a
+	// page served over HTTPS, in document order.
+	MixedContent []MixedContent `json:",omitempty"`
+	// ResourceOrigins groups the external scripts and stylesheets of the page
+	// by origin, in order of first use.
+	ResourceOrigins []ResourceOrigin `json:",omitempty"`
 }
 
 func Analyze(body io.Reader) (*AnalysisResult, error) {
-	doc, err := html.Parse(body)
+	return AnalyzeContext(context.Background(), body)
+}
+
+// AnalyzeContext is Analyze with a context used for tracing and logging.
+func AnalyzeContext(ctx context.Context, body io.Reader) (*AnalysisResult, error) {
+	return AnalyzeWithOptions(ctx, body, Options{})
+}
+
+// Options tune an analysis.
+type Options struct {
+	// Modules are the names of the modules to enable. All modules see the
+	// document in the same traversal as the built-in checks.
+	Modules []string
+	// ContentLanguage is the Content-Language header the document was served
+	// with, if any.
+	ContentLanguage string
+	// URL is the address the document was served from, against which its
+	// references are resolved and which tells third-party resources apart.
+	// Mixed content is only looked for when it is an https URL.
+	URL string
+}
+
+// AnalyzeWithOptions is AnalyzeContext with the modules to enable and what is
+// known about the document besides its markup.
+func AnalyzeWithOptions(ctx context.Context, body io.Reader, options Options) (*AnalysisResult, error) {
+	ctx, span := tracing.Start(ctx, "analyzer.Analyze")
+	defer span.End()
+
+	enabled, err := newModules(options.Modules)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	counter := &countingReader{r: body}
+	doc, err := html.Parse(counter)
 	if err != nil {
-		logger.WithField("error", err).Error("Failed to parse HTML")
+		tracing.RecordError(span, err)
+		logger.WithContext(ctx).WithField("phase", "parse").WithField("error", err).Error("Failed to parse HTML")
 		return nil, err
 	}
 
 	result := &AnalysisResult{
-		Headings: make(map[string]int),
+		Headings:        make(map[string]int),
+		ContentLanguage: strings.TrimSpace(options.ContentLanguage),
 	}
 
-	traverseTags(doc, result)
+	content := &contentCollector{}
+	resources := &subresourceCollector{}
+	if page, err := url.Parse(options.URL); err == nil && options.URL != "" {
+		resources.page = page
+	}
+	walk(doc, append([]namedModule{{"core", &coreModule{result: result}}, {"text", content}, {"subresources", resources}}, enabled...))
+	result.Content = content.metrics(counter.n)
+	resources.Finalize(result)
+	for _, m := range enabled {
+		before := len(result.Findings)
+		m.module.Finalize(result)
+		for i := before; i < len(result.Findings); i++ {
+			result.Findings[i].Module = m.name
+		}
+	}
+	span.SetAttributes(attribute.Int("analyzer.links", len(result.Links)))
 
 	return result, nil
 }
 
-func traverseTags(n *html.Node, result *AnalysisResult) {
+// StructuredData is one <script type="application/ld+json"> block.
+type StructuredData struct {
+	// Type is the top-level @type of the block, if any.
+	Type string `json:"type,omitempty"`
+	// JSON is the block, compacted when it is valid JSON and trimmed
+	// otherwise.
+	JSON string `json:"json"`
+}
+
+// parseStructuredData describes the JSON-LD block text.
+func parseStructuredData(text string) StructuredData {
+	data := StructuredData{JSON: strings.TrimSpace(text)}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(data.JSON)); err != nil {
+		return data
+	}
+	data.JSON = compact.String()
+	var typed struct {
+		Type any `json:"@type"`
+	}
+	if json.Unmarshal(compact.Bytes(), &typed) == nil {
+		switch t := typed.Type.(type) {
+		case string:
+			data.Type = t
+		case []any:
+			for i, v := range t {
+				if i > 0 {
+					data.Type += ","
+				}
+				data.Type += fmt.Sprint(v)
+			}
+		}
+	}
+	return data
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// coreModule collects the fields of AnalysisResult itself.
+type coreModule struct {
+	result *AnalysisResult
+}
+
+func (m *coreModule) Visit(n *html.Node) {
+	result := m.result
 	if n.Type == html.DoctypeNode {
 		result.HTMLVersion = "HTML5"
 	}
+	if n.Type != html.ElementNode {
+		return
+	}
 
-	if n.Type == html.ElementNode {
-		switch n.Data {
-		case "title":
-			if n.FirstChild != nil {
-				result.Title = n.FirstChild.Data
+	for _, attr := range n.Attr {
+		if attr.Key == "id" && attr.Val != "" {
+			result.Anchors = append(result.Anchors, attr.Val)
+		}
+	}
+	switch n.Data {
+	case "html":
+		if lang, ok := attr(n, "lang"); ok {
+			result.Lang = strings.TrimSpace(lang)
+		}
+	case "meta":
+		if equiv, _ := attr(n, "http-equiv"); strings.EqualFold(equiv, "content-language") && result.ContentLanguage == "" {
+			content, _ := attr(n, "content")
+			result.ContentLanguage = strings.TrimSpace(content)
+		}
+		name, ok := attr(n, "name")
+		if !ok {
+			name, ok = attr(n, "property")
+		}
+		if content, hasContent := attr(n, "content"); ok && hasContent && name != "" {
+			if result.Meta == nil {
+				result.Meta = make(map[string]string)
 			}
-		case "h1", "h2", "h3", "h4", "h5", "h6":
-			result.Headings[n.Data]++
-		case "a":
-			for _, attr := range n.Attr {
-				if attr.Key == "href" && attr.Val != "" {
-					result.Links = append(result.Links, attr.Val)
-				}
+			result.Meta[strings.ToLower(name)] = strings.TrimSpace(content)
+		}
+	case "script":
+		if typ, _ := attr(n, "type"); strings.EqualFold(strings.TrimSpace(typ), "application/ld+json") && n.FirstChild != nil {
+			result.StructuredData = append(result.StructuredData, parseStructuredData(n.FirstChild.Data))
+		}
+	case "title":
+		if n.FirstChild != nil {
+			result.Title = n.FirstChild.Data
+		}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		result.Headings[n.Data]++
+	case "a":
+		for _, attr := range n.Attr {
+			if attr.Key == "href" && attr.Val != "" {
+				result.Links = append(result.Links, attr.Val)
 			}
-		case "form":
-			if !result.HasLoginForm { // Stop checking once one is found
-				result.HasLoginForm = containsPasswordInput(n)
+			if attr.Key == "name" && attr.Val != "" {
+				result.Anchors = append(result.Anchors, attr.Val)
 			}
 		}
-	}
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		traverseTags(c, result)
+	case "form":
+		if !result.HasLoginForm { // Stop checking once one is found
+			result.HasLoginForm = containsPasswordInput(n)
+		}
 	}
 }
 
+func (m *coreModule) Finalize(*AnalysisResult) {}
+
 // containsPasswordInput is a helper to recursively check for a password field within a form.
 func containsPasswordInput(n *html.Node) bool {
 	if n.Type == html.ElementNode && n.Data == "input" {
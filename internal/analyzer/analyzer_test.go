@@ -687,6 +687,58 @@ func TestAnalyze_LinksWithMultipleAttributes(t *testing.T) {
 	}
 }
 
+func TestAnalyze_AnchorsExtraction(t *testing.T) {
+	html := `<html><body>
+    <h2 id="install">Install</h2>
+    <a name="legacy"></a>
+    <div id="">Empty id</div>
+    <section id="usage"><a href="#install" id="back">Back</a></section>
+</body></html>`
+
+	result, err := Analyze(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+
+	expected := []string{"install", "legacy", "usage", "back"}
+	if fmt.Sprint(result.Anchors) != fmt.Sprint(expected) {
+		t.Errorf("Expected anchors %v, got %v", expected, result.Anchors)
+	}
+}
+
+func TestAnalyze_MetaAndStructuredData(t *testing.T) {
+	html := `<html><head>
+    <meta charset="utf-8">
+    <meta name="Description" content=" About us ">
+    <meta property="og:title" content="Us">
+    <meta name="robots">
+    <script type="application/ld+json">
+        {"@context": "https://schema.org", "@type": "Organization", "name": "Us"}
+    </script>
+    <script type="application/ld+json">{"@type": ["Thing", "Place"]}</script>
+    <script type="application/ld+json">{broken</script>
+    <script>var x = 1;</script>
+</head></html>`
+
+	result, err := Analyze(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+
+	expectedMeta := map[string]string{"description": "About us", "og:title": "Us"}
+	if fmt.Sprint(result.Meta) != fmt.Sprint(expectedMeta) {
+		t.Errorf("Expected meta %v, got %v", expectedMeta, result.Meta)
+	}
+	expectedData := []StructuredData{
+		{Type: "Organization", JSON: `{"@context":"https://schema.org","@type":"Organization","name":"Us"}`},
+		{Type: "Thing,Place", JSON: `{"@type":["Thing","Place"]}`},
+		{JSON: "{broken"},
+	}
+	if fmt.Sprint(result.StructuredData) != fmt.Sprint(expectedData) {
+		t.Errorf("Expected structured data %v, got %v", expectedData, result.StructuredData)
+	}
+}
+
 // Benchmark tests
 func BenchmarkAnalyze_SimpleHTML(b *testing.B) {
 	html := `<!DOCTYPE html>
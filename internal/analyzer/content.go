@@ -0,0 +1,372 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// ContentMetrics describe the visible text of a page: the text of the body
+// outside scripts, styles, templates and hidden elements.
+type ContentMetrics struct {
+	WordCount     int `json:"word_count"`
+	SentenceCount int `json:"sentence_count"`
+	// TextToHTMLRatio is the size of the visible text relative to the size of
+	// the document, between 0 and 1.
+	TextToHTMLRatio float64 `json:"text_to_html_ratio"`
+	// AverageSentenceLength is in words.
+	AverageSentenceLength float64 `json:"average_sentence_length"`
+	// Language is the language detected in the text outside sections that
+	// declare their own language, with its confidence. See DetectLanguage.
+	Language           string  `json:"language,omitempty"`
+	LanguageConfidence float64 `json:"language_confidence,omitempty"`
+	// LanguageSections are the elements declaring a language other than the
+	// page's with enough text to detect one in.
+	LanguageSections []LanguageSection `json:"language_sections,omitempty"`
+	// Readability is only scored for English text.
+	Readability *Readability `json:"readability,omitempty"`
+	// Keywords and Phrases are the most frequent words and two or three word
+	// phrases that occur more than once, ignoring common English words.
+	Keywords []TermFrequency `json:"keywords,omitempty"`
+	Phrases  []TermFrequency `json:"phrases,omitempty"`
+}
+
+// Readability holds English readability scores.
+type Readability struct {
+	// FleschReadingEase is roughly 0 to 100; higher is easier to read.
+	FleschReadingEase float64 `json:"flesch_reading_ease"`
+	// FleschKincaidGrade and GunningFog estimate the US school grade needed to
+	// understand the text.
+	FleschKincaidGrade float64 `json:"flesch_kincaid_grade"`
+	GunningFog         float64 `json:"gunning_fog"`
+}
+
+// TermFrequency counts a keyword or phrase. Density is its share of the words
+// of the page, in percent.
+type TermFrequency struct {
+	Term    string  `json:"term"`
+	Count   int     `json:"count"`
+	Density float64 `json:"density"`
+}
+
+// maxTerms bounds the keywords and phrases reported.
+const maxTerms = 10
+
+// invisibleElements hold no visible text.
+var invisibleElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "math": true, "iframe": true, "object": true, "canvas": true,
+}
+
+// blockElements start a new block of text, which also ends a sentence.
+var blockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"button": true, "caption": true, "dd": true, "details": true, "dialog": true,
+	"div": true, "dl": true, "dt": true, "fieldset": true, "figcaption": true,
+	"figure": true, "footer": true, "form": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "label": true, "legend": true,
+	"li": true, "main": true, "nav": true, "ol": true, "option": true, "p": true,
+	"pre": true, "section": true, "summary": true, "table": true, "td": true,
+	"th": true, "tr": true, "ul": true,
+}
+
+// contentCollector gathers the visible text of the document in blocks, and
+// by the language it is declared in.
+type contentCollector struct {
+	lang   string
+	blocks [][]string
+	block  *html.Node
+	// text is the text in the page's language, and sections the text of
+	// elements declaring their own.
+	text     strings.Builder
+	sections []*languageScope
+	scopes   map[*html.Node]*languageScope
+}
+
+type languageScope struct {
+	element *html.Node
+	lang    string
+	text    strings.Builder
+}
+
+func (c *contentCollector) Visit(n *html.Node) {
+	if n.Type == html.ElementNode && n.Data == "html" {
+		c.lang, _ = attr(n, "lang")
+		return
+	}
+	if n.Type != html.TextNode {
+		return
+	}
+	words := strings.Fields(n.Data)
+	if len(words) == 0 {
+		return
+	}
+	var block, scope *html.Node
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type != html.ElementNode {
+			continue
+		}
+		if isHidden(p) {
+			return
+		}
+		if block == nil && blockElements[p.Data] {
+			block = p
+		}
+		if _, ok := attr(p, "lang"); ok && scope == nil && p.Data != "html" {
+			scope = p
+		}
+	}
+	if block != c.block || len(c.blocks) == 0 {
+		c.blocks = append(c.blocks, nil)
+		c.block = block
+	}
+	last := len(c.blocks) - 1
+	c.blocks[last] = append(c.blocks[last], words...)
+
+	text := &c.text
+	if scope != nil {
+		if c.scopes[scope] == nil {
+			if c.scopes == nil {
+				c.scopes = make(map[*html.Node]*languageScope)
+			}
+			lang, _ := attr(scope, "lang")
+			c.scopes[scope] = &languageScope{element: scope, lang: strings.TrimSpace(lang)}
+			c.sections = append(c.sections, c.scopes[scope])
+		}
+		text = &c.scopes[scope].text
+	}
+	text.WriteString(n.Data)
+	text.WriteByte(' ')
+}
+
+func (c *contentCollector) Finalize(*AnalysisResult) {}
+
+// isHidden reports whether n and its content are not rendered.
+func isHidden(n *html.Node) bool {
+	if invisibleElements[n.Data] {
+		return true
+	}
+	if _, ok := attr(n, "hidden"); ok {
+		return true
+	}
+	if value, _ := attr(n, "aria-hidden"); strings.EqualFold(value, "true") {
+		return true
+	}
+	style, _ := attr(n, "style")
+	style = strings.ToLower(strings.Join(strings.Fields(style), ""))
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+// metrics computes the content metrics of a document of htmlSize bytes.
+func (c *contentCollector) metrics(htmlSize int64) *ContentMetrics {
+	m := &ContentMetrics{}
+	var textSize, syllables, complexWords int
+	keywords := make(map[string]int)
+	phrases := make(map[string]int)
+
+	for _, block := range c.blocks {
+		var sentence []string
+		endSentence := func() {
+			if len(sentence) > 0 {
+				m.SentenceCount++
+				countPhrases(sentence, phrases)
+			}
+			sentence = sentence[:0]
+		}
+		for _, token := range block {
+			textSize += len(token) + 1
+			word := strings.ToLower(strings.TrimFunc(token, isWordEdge))
+			if word == "" {
+				continue
+			}
+			m.WordCount++
+			n := countSyllables(word)
+			syllables += n
+			if n >= 3 {
+				complexWords++
+			}
+			if isKeyword(word) {
+				keywords[word]++
+			}
+			sentence = append(sentence, word)
+			if endsSentence(token) {
+				endSentence()
+			}
+		}
+		endSentence()
+	}
+
+	if textSize > 0 && htmlSize > 0 {
+		m.TextToHTMLRatio = round2(float64(textSize-1) / float64(htmlSize))
+	}
+	if m.WordCount == 0 {
+		return m
+	}
+	m.Language, m.LanguageConfidence = DetectLanguage(c.text.String())
+	for _, section := range c.sections {
+		language, confidence := DetectLanguage(section.text.String())
+		if language != "" && primaryLanguage(section.lang) != primaryLanguage(c.lang) {
+			m.LanguageSections = append(m.LanguageSections, LanguageSection{
+				Element: describeElement(section.element), Lang: section.lang, Language: language, Confidence: confidence,
+			})
+		}
+	}
+
+	wordsPerSentence := float64(m.WordCount) / float64(m.SentenceCount)
+	m.AverageSentenceLength = round2(wordsPerSentence)
+	// Only a confident detection overrides the declared language: short or
+	// nav-heavy English text is often taken for another language.
+	english := primaryLanguage(c.lang) == "en" || c.lang == ""
+	if m.Language != "" && m.LanguageConfidence >= minLanguageConfidence {
+		english = m.Language == "en"
+	}
+	if english {
+		syllablesPerWord := float64(syllables) / float64(m.WordCount)
+		m.Readability = &Readability{
+			FleschReadingEase:  round2(206.835 - 1.015*wordsPerSentence - 84.6*syllablesPerWord),
+			FleschKincaidGrade: round2(0.39*wordsPerSentence + 11.8*syllablesPerWord - 15.59),
+			GunningFog:         round2(0.4 * (wordsPerSentence + 100*float64(complexWords)/float64(m.WordCount))),
+		}
+	}
+	m.Keywords = topTerms(keywords, m.WordCount)
+	m.Phrases = topTerms(phrases, m.WordCount)
+	return m
+}
+
+// countPhrases counts the two and three word phrases of sentence that start
+// and end with a keyword.
+func countPhrases(sentence []string, phrases map[string]int) {
+	for i := range sentence {
+		if !isKeyword(sentence[i]) {
+			continue
+		}
+		for size := 2; size <= 3 && i+size <= len(sentence); size++ {
+			if isKeyword(sentence[i+size-1]) {
+				phrases[strings.Join(sentence[i:i+size], " ")]++
+			}
+		}
+	}
+}
+
+// topTerms returns the most frequent of counts that occur more than once.
+func topTerms(counts map[string]int, words int) []TermFrequency {
+	var terms []TermFrequency
+	for term, count := range counts {
+		if count > 1 {
+			terms = append(terms, TermFrequency{Term: term, Count: count, Density: round2(100 * float64(count) / float64(words))})
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if len(terms) > maxTerms {
+		terms = terms[:maxTerms]
+	}
+	return terms
+}
+
+func isWordEdge(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// endsSentence reports whether token ends with sentence punctuation, possibly
+// followed by closing quotes or brackets.
+func endsSentence(token string) bool {
+	token = strings.TrimRight(token, `"')]»”’`)
+	return strings.HasSuffix(token, ".") || strings.HasSuffix(token, "!") || strings.HasSuffix(token, "?") || strings.HasSuffix(token, "…")
+}
+
+// isKeyword reports whether word can be a keyword: it has at least three
+// letters and is not a common English word.
+func isKeyword(word string) bool {
+	letters := 0
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters >= 3 && !stopWords[word]
+}
+
+// countSyllables estimates the syllables of an English word by its groups of
+// vowels, less a silent final e.
+func countSyllables(word string) int {
+	count := 0
+	previousVowel := false
+	for _, r := range word {
+		vowel := strings.ContainsRune("aeiouy", r)
+		if vowel && !previousVowel {
+			count++
+		}
+		previousVowel = vowel
+	}
+	if count > 1 && strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "le") && !strings.HasSuffix(word, "ee") {
+		count--
+	}
+	return max(count, 1)
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// stopWords are common English words that are not keywords.
+var stopWords = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`about above after again against all also and any are aren't because been
+		before being below between both but can can't cannot could couldn't did didn't does doesn't doing don't
+		down during each few for from further had hadn't has hasn't have haven't having her here hers herself him
+		himself his how into isn't its itself just let's more most mustn't myself nor not now off once only other
+		our ours ourselves out over own same she should shouldn't some such than that that's the their theirs them
+		themselves then there there's these they this those through too under until very was wasn't were weren't
+		what what's when where which while who whom why will with won't would wouldn't you you'll you're your
+		yours yourself yourselves`) {
+		stopWords[word] = true
+	}
+
+	RegisterModule("content", "Content quality: thin content, little text for the markup, long sentences and hard-to-read text", func() Module {
+		return contentModule{}
+	})
+}
+
+const (
+	minContentWords      = 300
+	minTextToHTMLRatio   = 0.1
+	maxSentenceLength    = 25
+	minFleschReadingEase = 30
+)
+
+// contentModule reports problems with the content metrics of the page.
+type contentModule struct{}
+
+func (contentModule) Visit(*html.Node) {}
+
+func (contentModule) Finalize(result *AnalysisResult) {
+	m := result.Content
+	if m == nil {
+		return
+	}
+	report := func(rule, severity, format string, args ...any) {
+		result.Findings = append(result.Findings, Finding{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+	if m.WordCount < minContentWords {
+		report("thin-content", SeverityWarning, "Page has %d words of visible text; at least %d are recommended", m.WordCount, minContentWords)
+	}
+	if m.TextToHTMLRatio < minTextToHTMLRatio {
+		report("low-text-ratio", SeverityInfo, "Visible text is %.0f%% of the HTML; at least %.0f%% is recommended", 100*m.TextToHTMLRatio, 100*minTextToHTMLRatio)
+	}
+	if m.AverageSentenceLength > maxSentenceLength {
+		report("long-sentences", SeverityInfo, "Sentences average %.1f words; at most %d are recommended", m.AverageSentenceLength, maxSentenceLength)
+	}
+	if m.Readability != nil && m.Readability.FleschReadingEase < minFleschReadingEase {
+		report("hard-to-read", SeverityInfo, "Flesch reading ease is %.1f; text scoring below %d is hard to read", m.Readability.FleschReadingEase, minFleschReadingEase)
+	}
+}
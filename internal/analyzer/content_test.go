@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const articleHTML = `<!DOCTYPE html>
+<html lang="en-GB">
+<head><title>Not counted</title><style>body { color: red }</style></head>
+<body>
+	<nav aria-hidden="true">Skip these words</nav>
+	<h1>Garden tools</h1>
+	<p>Garden tools make garden work easy. Choose garden tools with care!</p>
+	<p hidden>Hidden paragraph text</p>
+	<div style="display: none">Also hidden</div>
+	<script>var notText = "garden tools";</script>
+	<noscript>Enable scripts</noscript>
+	<p>Sharp tools last "longer."</p>
+</body>
+</html>`
+
+func TestAnalyze_ContentMetrics(t *testing.T) {
+	result, err := Analyze(strings.NewReader(articleHTML))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+	content := result.Content
+	if content == nil {
+		t.Fatal("Expected content metrics")
+	}
+	// "Garden tools" | "Garden tools make garden work easy." | "Choose garden
+	// tools with care!" | "Sharp tools last "longer.""
+	if content.WordCount != 17 || content.SentenceCount != 4 {
+		t.Errorf("Expected 17 words in 4 sentences, got %d in %d", content.WordCount, content.SentenceCount)
+	}
+	if content.AverageSentenceLength != 4.25 {
+		t.Errorf("Expected 4.25 words per sentence, got %v", content.AverageSentenceLength)
+	}
+	if content.TextToHTMLRatio <= 0 || content.TextToHTMLRatio >= 0.5 {
+		t.Errorf("Expected a small text to HTML ratio, got %v", content.TextToHTMLRatio)
+	}
+	if content.Readability == nil || content.Readability.FleschReadingEase < 50 {
+		t.Errorf("Expected short sentences of short words to be easy to read, got %+v", content.Readability)
+	}
+
+	// Ties are ordered by term; words seen once are left out.
+	if len(content.Keywords) != 2 || content.Keywords[0] != (TermFrequency{Term: "garden", Count: 4, Density: 23.53}) || content.Keywords[1].Term != "tools" {
+		t.Errorf("Expected garden and tools as keywords, got %+v", content.Keywords)
+	}
+	if len(content.Phrases) != 1 || content.Phrases[0].Term != "garden tools" || content.Phrases[0].Count != 3 {
+		t.Errorf("Expected the phrase \"garden tools\" 3 times, got %+v", content.Phrases)
+	}
+}
+
+func TestAnalyze_ContentReadabilityOnlyInEnglish(t *testing.T) {
+	result, err := Analyze(strings.NewReader(`<html lang="de"><body><p>Ein kurzer Satz.</p></body></html>`))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+	if result.Content.WordCount != 3 || result.Content.Readability != nil {
+		t.Errorf("Expected words counted without readability scores, got %+v", result.Content)
+	}
+
+	// A low-confidence detection does not override the declared language.
+	result, err = Analyze(strings.NewReader(`<html lang="en"><body><p>Tapas Paella Chorizo Salsa Nachos Tacos Burritos Menu Order Online</p></body></html>`))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+	if content := result.Content; content.Language == "en" || content.LanguageConfidence >= minLanguageConfidence || content.Readability == nil {
+		t.Errorf("Expected readability scores for an English page detected as another language at low confidence, got %+v", content)
+	}
+
+	result, err = Analyze(strings.NewReader(`<html><body></body></html>`))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+	if result.Content.WordCount != 0 || result.Content.Readability != nil || result.Content.TextToHTMLRatio != 0 {
+		t.Errorf("Expected empty metrics for a page without text, got %+v", result.Content)
+	}
+}
+
+func TestCountSyllables(t *testing.T) {
+	for word, expected := range map[string]int{
+		"cat": 1, "make": 1, "table": 2, "garden": 2, "readability": 5, "free": 1, "rhythm": 1, "2024": 1,
+	} {
+		if got := countSyllables(word); got != expected {
+			t.Errorf("countSyllables(%q) = %d, expected %d", word, got, expected)
+		}
+	}
+}
+
+func TestContentModule(t *testing.T) {
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(articleHTML), Options{Modules: []string{"content"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	rules := make(map[string]Finding)
+	for _, finding := range result.Findings {
+		rules[finding.Rule] = finding
+	}
+	if finding, ok := rules["thin-content"]; !ok || finding.Module != "content" || finding.Severity != SeverityWarning {
+		t.Errorf("Expected a thin content warning, got %+v", result.Findings)
+	}
+	if _, ok := rules["long-sentences"]; ok {
+		t.Errorf("Expected no long sentences finding, got %+v", result.Findings)
+	}
+
+	long := "<p>" + strings.Repeat("The quarterly organizational restructuring initiative consistently necessitates comprehensive interdepartmental communication ", 40) + "</p>"
+	result, err = AnalyzeWithOptions(context.Background(), strings.NewReader(long), Options{Modules: []string{"content"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	rules = make(map[string]Finding)
+	for _, finding := range result.Findings {
+		rules[finding.Rule] = finding
+	}
+	for _, rule := range []string{"long-sentences", "hard-to-read"} {
+		if _, ok := rules[rule]; !ok {
+			t.Errorf("Expected a %s finding, got %+v", rule, result.Findings)
+		}
+	}
+	if _, ok := rules["thin-content"]; ok {
+		t.Errorf("Expected no thin content warning for %d words", result.Content.WordCount)
+	}
+
+	markup := strings.Repeat(`<div class="wrapper"><span class="icon"></span></div>`, 20) + "<p>Hello.</p>"
+	result, err = AnalyzeWithOptions(context.Background(), strings.NewReader(markup), Options{Modules: []string{"content"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	if len(result.Findings) != 2 || result.Findings[1].Rule != "low-text-ratio" {
+		t.Errorf("Expected thin content and low text ratio findings, got %+v", result.Findings)
+	}
+}
@@ -0,0 +1,246 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/html"
+)
+
+// ExtractionTemplate maps field names to the values to pull out of a page.
+// Without Items it yields one record per page; with Items it yields one
+// record per element matching Items, such as each row of a product list, and
+// field selectors are evaluated within that element.
+type ExtractionTemplate struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Items       string            `json:"items,omitempty"`
+	Fields      []ExtractionField `json:"fields"`
+
+	items    *Selector
+	compiled bool
+}
+
+// ExtractionField is one field of an extraction template. Its value is taken
+// from the first element matching Selector that yields one, or from the item
+// itself when Selector is empty:
+//   - Attribute names the attribute to read; the element's text is used when
+//     it is empty.
+//   - URL resolves the value against the page URL, for href and src
+//     attributes.
+//   - Regex post-processes the value: the first capture group, or the whole
+//     match if there is none, replaces it, and an element whose value does not
+//     match yields nothing.
+//   - All collects the values of every matching element into a list.
+type ExtractionField struct {
+	Name      string `json:"name"`
+	Selector  string `json:"selector,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+	URL       bool   `json:"url,omitempty"`
+	Regex     string `json:"regex,omitempty"`
+	All       bool   `json:"all,omitempty"`
+
+	selector *Selector
+	regex    *regexp.Regexp
+}
+
+// Record is the data extracted for one page or item, keyed by field name.
+// Values are strings, lists of strings for All fields, or nil when nothing
+// was found.
+type Record map[string]any
+
+// ParseExtractionTemplates reads a JSON templates file of the form
+// {"templates": [...]} and compiles its templates.
+func ParseExtractionTemplates(r io.Reader) ([]*ExtractionTemplate, error) {
+	var file struct {
+		Templates []*ExtractionTemplate `json:"templates"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid templates file: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i, t := range file.Templates {
+		if err := t.Compile(); err != nil {
+			if t.Name == "" {
+				return nil, fmt.Errorf("template %d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("template %q: %w", t.Name, err)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("template %q: duplicate name", t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return file.Templates, nil
+}
+
+// Compile validates the template and compiles its selectors and regular
+// expressions. Extract compiles templates that have not been compiled, but a
+// template shared between goroutines must be compiled first.
+func (t *ExtractionTemplate) Compile() error {
+	if t.Name == "" {
+		return errors.New("missing name")
+	}
+	if len(t.Fields) == 0 {
+		return errors.New("no fields")
+	}
+	var err error
+	if t.Items != "" {
+		if t.items, err = ParseSelector(t.Items); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	seen := make(map[string]bool)
+	for i := range t.Fields {
+		field := &t.Fields[i]
+		if field.Name == "" {
+			return fmt.Errorf("field %d: missing name", i+1)
+		}
+		if seen[field.Name] {
+			return fmt.Errorf("field %q: duplicate name", field.Name)
+		}
+		seen[field.Name] = true
+		if field.Selector == "" && t.Items == "" {
+			return fmt.Errorf("field %q: missing selector", field.Name)
+		}
+		if field.Selector != "" {
+			if field.selector, err = ParseSelector(field.Selector); err != nil {
+				return fmt.Errorf("field %q: %w", field.Name, err)
+			}
+		}
+		if field.Regex != "" {
+			if field.regex, err = regexp.Compile(field.Regex); err != nil {
+				return fmt.Errorf("field %q: invalid regex: %w", field.Name, err)
+			}
+		}
+	}
+	t.compiled = true
+	return nil
+}
+
+// FieldNames lists the template's fields in order.
+func (t *ExtractionTemplate) FieldNames() []string {
+	names := make([]string, len(t.Fields))
+	for i, field := range t.Fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// Extract parses the document read from body and applies the template to it.
+// pageURL, if set, resolves URL fields.
+func Extract(ctx context.Context, body io.Reader, t *ExtractionTemplate, pageURL string) ([]Record, error) {
+	ctx, span := tracing.Start(ctx, "analyzer.Extract", trace.WithAttributes(attribute.String("extract.template", t.Name)))
+	defer span.End()
+
+	if !t.compiled {
+		if err := t.Compile(); err != nil {
+			return nil, err
+		}
+	}
+	var base *url.URL
+	if pageURL != "" {
+		base, _ = url.Parse(pageURL)
+	}
+
+	doc, err := html.Parse(body)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logger.WithContext(ctx).WithField("phase", "parse").WithField("error", err).Error("Failed to parse HTML")
+		return nil, err
+	}
+
+	if t.items == nil {
+		return []Record{t.record(doc, base)}, nil
+	}
+	records := []Record{}
+	for n := range doc.Descendants() {
+		if t.items.Match(n) {
+			records = append(records, t.record(n, base))
+		}
+	}
+	span.SetAttributes(attribute.Int("extract.records", len(records)))
+	return records, nil
+}
+
+func (t *ExtractionTemplate) record(scope *html.Node, base *url.URL) Record {
+	record := make(Record, len(t.Fields))
+	for _, field := range t.Fields {
+		record[field.Name] = field.extract(scope, base)
+	}
+	return record
+}
+
+func (f ExtractionField) extract(scope *html.Node, base *url.URL) any {
+	var values []string
+	candidates := func(yield func(*html.Node) bool) {
+		if f.selector == nil {
+			yield(scope)
+			return
+		}
+		for n := range scope.Descendants() {
+			if f.selector.Match(n) && !yield(n) {
+				return
+			}
+		}
+	}
+	for n := range candidates {
+		value, ok := f.value(n, base)
+		if !ok {
+			continue
+		}
+		if !f.All {
+			return value
+		}
+		values = append(values, value)
+	}
+	if f.All {
+		if values == nil {
+			values = []string{}
+		}
+		return values
+	}
+	return nil
+}
+
+func (f ExtractionField) value(n *html.Node, base *url.URL) (string, bool) {
+	var value string
+	if f.Attribute != "" {
+		var ok bool
+		if value, ok = attr(n, f.Attribute); !ok {
+			return "", false
+		}
+		value = strings.TrimSpace(value)
+	} else {
+		value = textContent(n)
+	}
+	if f.URL && base != nil && value != "" {
+		if ref, err := url.Parse(value); err == nil {
+			value = base.ResolveReference(ref).String()
+		}
+	}
+	if f.regex != nil {
+		match := f.regex.FindStringSubmatch(value)
+		switch {
+		case match == nil:
+			return "", false
+		case len(match) > 1:
+			value = match[1]
+		default:
+			value = match[0]
+		}
+	}
+	return value, true
+}
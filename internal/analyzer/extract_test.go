@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const catalogHTML = `<html><head><title>Catalog</title></head><body>
+<h1>Spring Sale</h1>
+<table id="products">
+	<tr class="product"><td class="name"><a href="/p/1">Kettle</a></td><td class="price">Price: $19.99</td><td class="tags"><span>home</span><span>kitchen</span></td></tr>
+	<tr class="product"><td class="name"><a href="https://other.example.com/p/2">Toaster</a></td><td class="price">Call us</td><td class="tags"></td></tr>
+</table>
+</body></html>`
+
+func TestExtract_SingleRecord(t *testing.T) {
+	template := &ExtractionTemplate{Name: "page", Fields: []ExtractionField{
+		{Name: "title", Selector: "title"},
+		{Name: "heading", Selector: "h1"},
+		{Name: "first_product_link", Selector: ".product a", Attribute: "href", URL: true},
+		{Name: "missing", Selector: "footer"},
+	}}
+	records, err := Extract(context.Background(), strings.NewReader(catalogHTML), template, "https://shop.example.com/catalog")
+	if err != nil {
+		t.Fatalf("Extract() returned error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("Expected one record for the page, got %d", len(records))
+	}
+	record := records[0]
+	if record["title"] != "Catalog" || record["heading"] != "Spring Sale" {
+		t.Errorf("Unexpected text fields %+v", record)
+	}
+	if record["first_product_link"] != "https://shop.example.com/p/1" {
+		t.Errorf("Expected the link resolved against the page URL, got %v", record["first_product_link"])
+	}
+	if value, ok := record["missing"]; !ok || value != nil {
+		t.Errorf("Expected a nil value for a field that matched nothing, got %v", value)
+	}
+}
+
+func TestExtract_Items(t *testing.T) {
+	template := &ExtractionTemplate{Name: "products", Items: "tr.product", Fields: []ExtractionField{
+		{Name: "name", Selector: ".name"},
+		{Name: "url", Selector: ".name a", Attribute: "href", URL: true},
+		{Name: "price", Selector: ".price", Regex: `\$([0-9.]+)`},
+		{Name: "tags", Selector: ".tags span", All: true},
+		{Name: "row", Attribute: "class"},
+	}}
+	records, err := Extract(context.Background(), strings.NewReader(catalogHTML), template, "https://shop.example.com/catalog")
+	if err != nil {
+		t.Fatalf("Extract() returned error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected one record per product row, got %d", len(records))
+	}
+	kettle, toaster := records[0], records[1]
+	if kettle["name"] != "Kettle" || kettle["url"] != "https://shop.example.com/p/1" || kettle["price"] != "19.99" || kettle["row"] != "product" {
+		t.Errorf("Unexpected first record %+v", kettle)
+	}
+	if tags, _ := kettle["tags"].([]string); !slices.Equal(tags, []string{"home", "kitchen"}) {
+		t.Errorf("Expected both tags, got %v", kettle["tags"])
+	}
+	if toaster["url"] != "https://other.example.com/p/2" {
+		t.Errorf("Expected an absolute link to be kept, got %v", toaster["url"])
+	}
+	if toaster["price"] != nil {
+		t.Errorf("Expected a price that does not match the regex to be nil, got %v", toaster["price"])
+	}
+	if tags, ok := toaster["tags"].([]string); !ok || len(tags) != 0 {
+		t.Errorf("Expected an empty list of tags, got %#v", toaster["tags"])
+	}
+}
+
+func TestParseExtractionTemplates(t *testing.T) {
+	templates, err := ParseExtractionTemplates(strings.NewReader(`{"templates": [
+		{"name": "products", "items": "tr.product", "fields": [{"name": "name", "selector": ".name"}, {"name": "sku", "regex": "SKU-(\\d+)"}]}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseExtractionTemplates() returned error: %v", err)
+	}
+	if len(templates) != 1 || !slices.Equal(templates[0].FieldNames(), []string{"name", "sku"}) {
+		t.Errorf("Unexpected templates %+v", templates)
+	}
+
+	for name, input := range map[string]string{
+		"unknown key":        `{"templates": [{"name": "a", "fields": [{"name": "x", "selector": "p", "xpath": "//p"}]}]}`,
+		"missing name":       `{"templates": [{"fields": [{"name": "x", "selector": "p"}]}]}`,
+		"no fields":          `{"templates": [{"name": "a"}]}`,
+		"missing selector":   `{"templates": [{"name": "a", "fields": [{"name": "x"}]}]}`,
+		"invalid selector":   `{"templates": [{"name": "a", "fields": [{"name": "x", "selector": "p >"}]}]}`,
+		"invalid items":      `{"templates": [{"name": "a", "items": "[", "fields": [{"name": "x"}]}]}`,
+		"invalid regex":      `{"templates": [{"name": "a", "fields": [{"name": "x", "selector": "p", "regex": "("}]}]}`,
+		"duplicate field":    `{"templates": [{"name": "a", "fields": [{"name": "x", "selector": "p"}, {"name": "x", "selector": "h1"}]}]}`,
+		"duplicate template": `{"templates": [{"name": "a", "fields": [{"name": "x", "selector": "p"}]}, {"name": "a", "fields": [{"name": "y", "selector": "p"}]}]}`,
+	} {
+		if _, err := ParseExtractionTemplates(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package analyzer
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Types of the resources listed by origin.
+const (
+	ResourceScript     = "script"
+	ResourceStylesheet = "stylesheet"
+)
+
+// ResourceOrigin groups the scripts and stylesheets a page loads from one
+// origin.
+type ResourceOrigin struct {
+	// Origin is the scheme and host of the resources, or "" for relative
+	// references of a page whose URL is unknown.
+	Origin string `json:"origin"`
+	// ThirdParty is set for origins other than the page's. When the page URL
+	// is unknown, every absolute reference is third-party.
+	ThirdParty bool               `json:"third_party"`
+	Resources  []ExternalResource `json:"resources"`
+}
+
+// ExternalResource is a script or stylesheet loaded from its own URL.
+type ExternalResource struct {
+	URL  string `json:"url"`
+	Type string `json:"type"`
+	// Integrity is the integrity attribute as written.
+	Integrity string `json:"integrity,omitempty"`
+	// CrossOrigin is the CORS mode set by the crossorigin attribute,
+	// "anonymous" or "use-credentials", or "" without one.
+	CrossOrigin string `json:"crossorigin,omitempty"`
+	Path        string `json:"path"`
+}
+
+// IntegrityHash is one hash of an integrity attribute, with its digest in
+// base64.
+type IntegrityHash struct {
+	Algorithm string
+	Digest    string
+}
+
+// integrityAlgorithms are the hash algorithms browsers support for
+// Subresource Integrity, strongest last.
+var integrityAlgorithms = []string{"sha256", "sha384", "sha512"}
+
+// ParseIntegrity returns the hashes of an integrity attribute that use a
+// supported algorithm. Browsers ignore the others, and load the resource
+// unchecked if none is left.
+func ParseIntegrity(value string) []IntegrityHash {
+	var hashes []IntegrityHash
+	for _, token := range strings.Fields(value) {
+		token, _, _ = strings.Cut(token, "?")
+		algorithm, digest, ok := strings.Cut(token, "-")
+		algorithm = strings.ToLower(algorithm)
+		if ok && digest != "" && slices.Contains(integrityAlgorithms, algorithm) {
+			hashes = append(hashes, IntegrityHash{algorithm, digest})
+		}
+	}
+	return hashes
+}
+
+// StrongestIntegrity returns the hashes of the strongest algorithm among
+// hashes, the only ones browsers check; a resource matching any of them
+// passes.
+func StrongestIntegrity(hashes []IntegrityHash) []IntegrityHash {
+	strongest := -1
+	for _, hash := range hashes {
+		strongest = max(strongest, slices.Index(integrityAlgorithms, hash.Algorithm))
+	}
+	var checked []IntegrityHash
+	for _, hash := range hashes {
+		if slices.Index(integrityAlgorithms, hash.Algorithm) == strongest {
+			checked = append(checked, hash)
+		}
+	}
+	return checked
+}
+
+// resourceType returns whether n loads a script or a stylesheet, the
+// resources integrity applies to, or "" if it loads neither.
+func resourceType(n *html.Node) string {
+	switch n.Data {
+	case "script":
+		return ResourceScript
+	case "link":
+		rel, _ := attr(n, "rel")
+		as, _ := attr(n, "as")
+		for _, r := range strings.Fields(strings.ToLower(rel)) {
+			switch {
+			case r == "stylesheet", r == "preload" && strings.EqualFold(as, "style"):
+				return ResourceStylesheet
+			case r == "modulepreload", r == "preload" && strings.EqualFold(as, "script"):
+				return ResourceScript
+			}
+		}
+	}
+	return ""
+}
+
+// resourceOrigins groups the scripts and stylesheets among refs by origin.
+func resourceOrigins(page *url.URL, refs []subresource, resolve func(*url.URL) *url.URL) []ResourceOrigin {
+	pageOrigin := ""
+	if page != nil {
+		pageOrigin = origin(page)
+	}
+	var origins []ResourceOrigin
+	index := make(map[string]int)
+	for _, ref := range refs {
+		typ := resourceType(ref.node)
+		if typ == "" || (ref.attribute != "src" && ref.attribute != "href") {
+			continue
+		}
+		u, err := url.Parse(ref.url)
+		if err != nil {
+			continue
+		}
+		resolved := resolve(u)
+		if resolved.Scheme != "" && resolved.Scheme != "http" && resolved.Scheme != "https" {
+			continue
+		}
+		o := origin(resolved)
+		i, ok := index[o]
+		if !ok {
+			i = len(origins)
+			index[o] = i
+			origins = append(origins, ResourceOrigin{Origin: o, ThirdParty: o != "" && o != pageOrigin})
+		}
+		resource := ExternalResource{URL: resolved.String(), Type: typ, Path: elementPath(ref.node)}
+		resource.Integrity, _ = attr(ref.node, "integrity")
+		resource.Integrity = strings.TrimSpace(resource.Integrity)
+		if mode, ok := attr(ref.node, "crossorigin"); ok {
+			resource.CrossOrigin = "anonymous"
+			if strings.EqualFold(strings.TrimSpace(mode), "use-credentials") {
+				resource.CrossOrigin = "use-credentials"
+			}
+		}
+		origins[i].Resources = append(origins[i].Resources, resource)
+	}
+	return origins
+}
+
+// origin returns the scheme and host of u, or "" for a relative URL.
+// Protocol-relative URLs of a page whose URL is unknown have no scheme.
+func origin(u *url.URL) string {
+	if u.Host == "" {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "" {
+		scheme += ":"
+	}
+	return scheme + "//" + strings.ToLower(u.Host)
+}
+
+func init() {
+	RegisterModule("integrity", "Subresource Integrity: third-party scripts and stylesheets without integrity or crossorigin, and malformed integrity values", func() Module {
+		return integrityModule{}
+	})
+}
+
+// integrityModule checks the integrity attributes of the scripts and
+// stylesheets listed by origin.
+type integrityModule struct{}
+
+func (integrityModule) Visit(*html.Node) {}
+
+func (integrityModule) Finalize(result *AnalysisResult) {
+	report := func(rule, severity string, resource ExternalResource, format string, args ...any) {
+		result.Findings = append(result.Findings, Finding{Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...), Element: resource.Path})
+	}
+	for _, group := range result.ResourceOrigins {
+		for _, resource := range group.Resources {
+			hashes := ParseIntegrity(resource.Integrity)
+			switch {
+			case resource.Integrity != "" && len(hashes) == 0:
+				report("sri-invalid", SeverityWarning, resource, "integrity=%q has no sha256, sha384 or sha512 hash, so %s is loaded unchecked", resource.Integrity, resource.URL)
+			case resource.Integrity == "" && group.ThirdParty:
+				report("sri-missing", SeverityWarning, resource, "Third-party %s %s has no integrity attribute", resource.Type, resource.URL)
+			case len(hashes) > 0 && group.ThirdParty && resource.CrossOrigin == "":
+				// Without CORS, the response is opaque and fails every check.
+				report("sri-crossorigin-missing", SeverityError, resource, "Third-party %s %s has an integrity attribute but no crossorigin, so browsers refuse it", resource.Type, resource.URL)
+			}
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const thirdPartyHTML = `<!DOCTYPE html>
+<html>
+<head>
+	<link rel="stylesheet" href="/site.css">
+	<link rel="stylesheet" href="https://cdn.example.net/lib.css" integrity="sha384-abc" crossorigin>
+	<link rel="preload" as="script" href="https://cdn.example.net/lib.js" integrity="md5-abc">
+	<link rel="icon" href="https://cdn.example.net/favicon.ico">
+	<script src="https://tracker.example.org/t.js"></script>
+	<script src="https://cdn.example.net/lib.js" integrity="sha256-abc sha512-def" crossorigin="use-credentials"></script>
+	<script src="https://widgets.example.org/w.js" integrity="sha256-abc"></script>
+	<script>inline()</script>
+</head>
+<body></body>
+</html>`
+
+func TestAnalyze_ResourceOrigins(t *testing.T) {
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(thirdPartyHTML), Options{URL: "https://example.com/page"})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	origins := result.ResourceOrigins
+	if len(origins) != 4 {
+		t.Fatalf("Expected 4 origins, got %+v", origins)
+	}
+	if origins[0].Origin != "https://example.com" || origins[0].ThirdParty || origins[0].Resources[0].URL != "https://example.com/site.css" {
+		t.Errorf("Expected the page's own stylesheet first, got %+v", origins[0])
+	}
+	cdn := origins[1]
+	if cdn.Origin != "https://cdn.example.net" || !cdn.ThirdParty || len(cdn.Resources) != 3 {
+		t.Fatalf("Expected the CDN's stylesheet, preload and script, got %+v", cdn)
+	}
+	if cdn.Resources[0].CrossOrigin != "anonymous" || cdn.Resources[2].CrossOrigin != "use-credentials" || cdn.Resources[1].Type != ResourceScript {
+		t.Errorf("Unexpected CDN resources %+v", cdn.Resources)
+	}
+	if origins[2].Origin != "https://tracker.example.org" || origins[3].Origin != "https://widgets.example.org" {
+		t.Errorf("Expected origins in order of first use, got %+v", origins)
+	}
+
+	// Without a page URL, relative references have no origin.
+	result, err = Analyze(strings.NewReader(thirdPartyHTML))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+	if origins := result.ResourceOrigins; len(origins) != 4 || origins[0].Origin != "" || origins[0].ThirdParty || !origins[1].ThirdParty {
+		t.Errorf("Unexpected origins without a page URL: %+v", origins)
+	}
+}
+
+func TestIntegrityModule(t *testing.T) {
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(thirdPartyHTML), Options{URL: "https://example.com/page", Modules: []string{"integrity"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	expected := []struct{ rule, element string }{
+		{"sri-invalid", "html > head > link:nth-of-type(3)"},
+		{"sri-missing", "html > head > script:nth-of-type(1)"},
+		{"sri-crossorigin-missing", "html > head > script:nth-of-type(3)"},
+	}
+	if len(result.Findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %+v", len(expected), result.Findings)
+	}
+	for i, finding := range result.Findings {
+		if finding.Rule != expected[i].rule || finding.Element != expected[i].element || finding.Module != "integrity" {
+			t.Errorf("Finding %d: expected %s at %s, got %+v", i, expected[i].rule, expected[i].element, finding)
+		}
+	}
+}
+
+func TestParseIntegrity(t *testing.T) {
+	hashes := ParseIntegrity("sha256-aaa  SHA512-bbb?opt md5-ccc sha512-ddd sha384-")
+	if len(hashes) != 3 || hashes[1] != (IntegrityHash{"sha512", "bbb"}) {
+		t.Fatalf("Unexpected hashes %+v", hashes)
+	}
+	strongest := StrongestIntegrity(hashes)
+	if len(strongest) != 2 || strongest[0].Digest != "bbb" || strongest[1].Digest != "ddd" {
+		t.Errorf("Expected the two sha512 hashes, got %+v", strongest)
+	}
+}
@@ -0,0 +1,294 @@
+package analyzer
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/net/html"
+)
+
+// languages/*.txt are sample texts the n-gram profiles of the detectable
+// languages are built from, named by ISO 639-1 code.
+//
+//go:embed languages/*.txt
+var languageSamples embed.FS
+
+// languageNames are the languages DetectLanguage can identify.
+var languageNames = map[string]string{
+	"ar": "Arabic", "da": "Danish", "de": "German", "el": "Greek", "en": "English",
+	"es": "Spanish", "fr": "French", "he": "Hebrew", "hi": "Hindi", "it": "Italian",
+	"ja": "Japanese", "ko": "Korean", "nl": "Dutch", "pl": "Polish", "pt": "Portuguese",
+	"ru": "Russian", "sv": "Swedish", "th": "Thai", "uk": "Ukrainian", "zh": "Chinese",
+}
+
+// scriptLanguages are identified by their script alone.
+var scriptLanguages = []struct {
+	script *unicode.RangeTable
+	lang   string
+}{
+	{unicode.Hangul, "ko"}, {unicode.Hiragana, "ja"}, {unicode.Katakana, "ja"}, {unicode.Han, "zh"},
+	{unicode.Greek, "el"}, {unicode.Arabic, "ar"}, {unicode.Hebrew, "he"}, {unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+const (
+	// minDetectLetters is the least text, in letters, a language is detected in.
+	minDetectLetters = 40
+	// minLanguageConfidence is the confidence the language module needs to
+	// report a detected language that differs from the declared one.
+	minLanguageConfidence = 0.9
+	// maxDetectBytes bounds the text a language is detected in.
+	maxDetectBytes = 64 << 10
+)
+
+// languageProfile holds the log probabilities of the letter sequences of a
+// language, estimated from its sample text.
+type languageProfile struct {
+	lang    string
+	logProb map[string]float64
+	// unseen is the log probability of a sequence missing from the sample.
+	unseen float64
+}
+
+var languageProfiles = sync.OnceValue(func() []languageProfile {
+	files, _ := languageSamples.ReadDir("languages")
+	profiles := make([]languageProfile, 0, len(files))
+	for _, file := range files {
+		sample, err := languageSamples.ReadFile(path.Join("languages", file.Name()))
+		if err != nil {
+			panic(err)
+		}
+		counts := ngrams(string(sample))
+		total := 0
+		for _, count := range counts {
+			total += count
+		}
+		// Add-one smoothing over the sequences seen and one for all others.
+		denominator := math.Log(float64(total + len(counts) + 1))
+		profile := languageProfile{
+			lang:    strings.TrimSuffix(file.Name(), ".txt"),
+			logProb: make(map[string]float64, len(counts)),
+			unseen:  -denominator,
+		}
+		for gram, count := range counts {
+			profile.logProb[gram] = math.Log(float64(count+1)) - denominator
+		}
+		profiles = append(profiles, profile)
+	}
+	return profiles
+})
+
+// DetectLanguage identifies the language of text, returning its ISO 639-1
+// code and a confidence between 0 and 1, or "" if the text is too short.
+// Languages written in their own script are identified by the script; the
+// others by how likely their letter sequences are in each bundled profile.
+func DetectLanguage(text string) (string, float64) {
+	if len(text) > maxDetectBytes {
+		text = strings.ToValidUTF8(text[:maxDetectBytes], "")
+	}
+	letters := 0
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scriptLanguages {
+			if unicode.Is(s.script, r) {
+				scripts[s.lang]++
+				break
+			}
+		}
+	}
+	// Ideographs carry a word each, so far fewer of them are needed.
+	if letters < minDetectLetters && scripts["zh"]+scripts["ja"]+scripts["ko"] < minDetectLetters/4 {
+		return "", 0
+	}
+	// Japanese mixes kana with Chinese characters.
+	if scripts["ja"] > 0 && scripts["ja"]*10 >= scripts["zh"] {
+		scripts["ja"] += scripts["zh"]
+		delete(scripts, "zh")
+	}
+	for lang, count := range scripts {
+		if count*2 > letters {
+			return lang, round2(float64(count) / float64(letters))
+		}
+	}
+
+	grams := ngrams(text)
+	total := 0
+	for _, count := range grams {
+		total += count
+	}
+	profiles := languageProfiles()
+	scores := make([]float64, len(profiles))
+	best := 0
+	for i, profile := range profiles {
+		for gram, count := range grams {
+			logProb, ok := profile.logProb[gram]
+			if !ok {
+				logProb = profile.unseen
+			}
+			scores[i] += float64(count) * logProb
+		}
+		// Per sequence, so that the confidence does not grow without bound
+		// with the length of the text.
+		scores[i] /= float64(total)
+		if scores[i] > scores[best] {
+			best = i
+		}
+	}
+	// The confidence is the probability of the best language among the
+	// profiles, scaled as if the text had minDetectLetters sequences.
+	sum := 0.0
+	for _, score := range scores {
+		sum += math.Exp(minDetectLetters * (score - scores[best]))
+	}
+	return profiles[best].lang, round2(1 / sum)
+}
+
+// ngrams counts the sequences of two and three letters of the words of text,
+// with word boundaries marked by spaces.
+func ngrams(text string) map[string]int {
+	counts := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) }) {
+		runes := []rune(" " + word + " ")
+		for size := 2; size <= 3; size++ {
+			for i := 0; i+size <= len(runes); i++ {
+				counts[string(runes[i:i+size])]++
+			}
+		}
+	}
+	return counts
+}
+
+// LanguageSection is an element that declares a language of its own with a
+// lang attribute, and the language detected in its visible text.
+type LanguageSection struct {
+	Element string `json:"element"`
+	Lang    string `json:"lang"`
+	// Language and Confidence are as returned by DetectLanguage.
+	Language   string  `json:"language,omitempty"`
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// primaryLanguage returns the lowercase primary subtag of a language tag.
+func primaryLanguage(tag string) string {
+	primary, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	primary, _, _ = strings.Cut(primary, "_")
+	return strings.ToLower(primary)
+}
+
+// languageName names a language tag for messages.
+func languageName(tag string) string {
+	if name, ok := languageNames[primaryLanguage(tag)]; ok {
+		return fmt.Sprintf("%s (%s)", name, tag)
+	}
+	return tag
+}
+
+// languageTagPattern matches well-formed BCP 47 language tags, loosely.
+var languageTagPattern = regexp.MustCompile(`^(?i:[a-z]{2,3}(-[a-z0-9]{1,8})*|[xi](-[a-z0-9]{1,8})+)$`)
+
+func init() {
+	RegisterModule("language", "Language: invalid lang attributes, and declared languages that differ from the text, the Content-Language header or hreflang", func() Module {
+		return &languageModule{}
+	})
+}
+
+// languageModule compares declared languages with each other and with the
+// languages detected in the text.
+type languageModule struct {
+	findings  []Finding
+	canonical string
+	alternate map[string]*html.Node
+}
+
+func (m *languageModule) Visit(n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	if lang, ok := attr(n, "lang"); ok && lang != "" && !languageTagPattern.MatchString(strings.TrimSpace(lang)) {
+		m.findings = append(m.findings, Finding{
+			Rule: "lang-invalid", Severity: SeverityError,
+			Message: fmt.Sprintf("lang=%q is not a valid language tag", lang),
+			Element: describeElement(n),
+		})
+	}
+	if n.Data != "link" {
+		return
+	}
+	rel, _ := attr(n, "rel")
+	href, _ := attr(n, "href")
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "canonical":
+			m.canonical = strings.TrimSpace(href)
+		case "alternate":
+			if hreflang, ok := attr(n, "hreflang"); ok && !strings.EqualFold(hreflang, "x-default") {
+				if m.alternate == nil {
+					m.alternate = make(map[string]*html.Node)
+				}
+				m.alternate[strings.TrimSpace(href)] = n
+			}
+		}
+	}
+}
+
+func (m *languageModule) Finalize(result *AnalysisResult) {
+	result.Findings = append(result.Findings, m.findings...)
+	report := func(rule, element, format string, args ...any) {
+		result.Findings = append(result.Findings, Finding{Rule: rule, Severity: SeverityWarning, Message: fmt.Sprintf(format, args...), Element: element})
+	}
+	// differs reports whether a detected language contradicts a declared one
+	// the detector knows.
+	differs := func(declared, detected string, confidence float64) bool {
+		_, known := languageNames[primaryLanguage(declared)]
+		return known && detected != "" && confidence >= minLanguageConfidence && primaryLanguage(declared) != detected
+	}
+
+	declared := result.Lang
+	if content := result.Content; content != nil {
+		switch {
+		case declared == "" && content.Language != "" && content.LanguageConfidence >= minLanguageConfidence:
+			report("lang-missing", "<html>", "Page declares no language; its text appears to be %s", languageName(content.Language))
+		case differs(declared, content.Language, content.LanguageConfidence):
+			report("lang-mismatch", "<html>", "Page declares lang=%q but its text appears to be %s", declared, languageName(content.Language))
+		}
+		for _, section := range content.LanguageSections {
+			if differs(section.Lang, section.Language, section.Confidence) {
+				report("section-lang-mismatch", section.Element, "Section declares lang=%q but its text appears to be %s", section.Lang, languageName(section.Language))
+			}
+		}
+	}
+
+	if result.ContentLanguage != "" {
+		page, source := declared, "lang attribute"
+		if page == "" && result.Content != nil && result.Content.LanguageConfidence >= minLanguageConfidence {
+			page, source = result.Content.Language, "text"
+		}
+		var served []string
+		for _, tag := range strings.Split(result.ContentLanguage, ",") {
+			served = append(served, primaryLanguage(tag))
+		}
+		if page != "" && !slices.Contains(served, primaryLanguage(page)) {
+			report("content-language-mismatch", "", "Content-Language is %q but the page's %s is %s", result.ContentLanguage, source, languageName(page))
+		}
+	}
+
+	if declared != "" && m.canonical != "" {
+		if link, ok := m.alternate[m.canonical]; ok {
+			hreflang, _ := attr(link, "hreflang")
+			if primaryLanguage(hreflang) != primaryLanguage(declared) {
+				report("hreflang-mismatch", describeElement(link), "The alternate link to this page declares hreflang=%q but the page declares lang=%q", hreflang, declared)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	for expected, text := range map[string]string{
+		"en": "Please read the terms and conditions carefully before using this service.",
+		"de": "Der schnelle braune Fuchs springt über den faulen Hund und läuft dann weiter in den Wald.",
+		"fr": "Le renard brun rapide saute par-dessus le chien paresseux et court ensuite dans la forêt.",
+		"es": "El rápido zorro marrón salta sobre el perro perezoso y luego corre hacia el bosque.",
+		"it": "La volpe marrone veloce salta sopra il cane pigro e poi corre nel bosco vicino.",
+		"pt": "A rápida raposa marrom pula sobre o cão preguiçoso e depois corre para a floresta.",
+		"nl": "De snelle bruine vos springt over de luie hond en rent daarna het bos in.",
+		"sv": "Den snabba bruna räven hoppar över den lata hunden och springer sedan in i skogen.",
+		"da": "Den hurtige brune ræv hopper over den dovne hund og løber derefter ind i skoven.",
+		"pl": "Szybki brązowy lis przeskakuje nad leniwym psem i biegnie potem do lasu.",
+		"ru": "Быстрая коричневая лиса прыгает через ленивую собаку и убегает в лес.",
+		"uk": "Швидка коричнева лисиця стрибає через ледачого собаку і тікає до лісу.",
+		"ja": "東京は日本の首都です。たくさんの人が住んでいます。",
+		"zh": "北京是中国的首都，有很多人住在那里。",
+		"ko": "서울은 대한민국의 수도이며 많은 사람들이 살고 있습니다.",
+		"el": "Η γρήγορη καφέ αλεπού πηδά πάνω από τον τεμπέλη σκύλο.",
+	} {
+		lang, confidence := DetectLanguage(text)
+		if lang != expected || confidence < minLanguageConfidence {
+			t.Errorf("DetectLanguage(%q) = %s with %v, expected %s", text, lang, confidence, expected)
+		}
+	}
+
+	if lang, _ := DetectLanguage("Hello there"); lang != "" {
+		t.Errorf("Expected no language for a short text, got %s", lang)
+	}
+}
+
+const multilingualHTML = `<!DOCTYPE html>
+<html lang="de">
+<head>
+	<link rel="canonical" href="https://example.com/de/">
+	<link rel="alternate" hreflang="en" href="https://example.com/de/">
+	<link rel="alternate" hreflang="x-default" href="https://example.com/">
+</head>
+<body>
+	<p>Welcome to our online store. We offer a wide range of products for the home and garden.</p>
+	<blockquote lang="fr">Der schnelle braune Fuchs springt über den faulen Hund und läuft dann weiter in den Wald.</blockquote>
+	<p lang="es">El rápido zorro marrón salta sobre el perro perezoso y luego corre hacia el bosque.</p>
+	<span lang="english">Hi</span>
+</body>
+</html>`
+
+func TestAnalyze_Language(t *testing.T) {
+	result, err := Analyze(strings.NewReader(multilingualHTML))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+	if result.Lang != "de" || result.Content.Language != "en" {
+		t.Errorf("Expected lang de with English text, got %q and %q", result.Lang, result.Content.Language)
+	}
+	if result.Content.Readability == nil {
+		t.Error("Expected readability scores for English text on a page declared German")
+	}
+	sections := result.Content.LanguageSections
+	if len(sections) != 2 || sections[0].Lang != "fr" || sections[0].Language != "de" || sections[1].Language != "es" {
+		t.Errorf("Expected the French and Spanish sections, got %+v", sections)
+	}
+}
+
+func TestLanguageModule(t *testing.T) {
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(multilingualHTML), Options{
+		Modules:         []string{"language"},
+		ContentLanguage: "fr, nl",
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	expected := map[string]string{
+		"lang-invalid":              `<span>`,
+		"lang-mismatch":             "<html>",
+		"section-lang-mismatch":     "<blockquote>",
+		"content-language-mismatch": "",
+		"hreflang-mismatch":         `<link href="https://example.com/de/">`,
+	}
+	if len(result.Findings) != len(expected) {
+		t.Errorf("Expected %d findings, got %+v", len(expected), result.Findings)
+	}
+	for _, finding := range result.Findings {
+		element, ok := expected[finding.Rule]
+		if !ok || finding.Element != element || finding.Module != "language" {
+			t.Errorf("Unexpected finding %+v", finding)
+		}
+	}
+
+	// The header may name the page's language among others, and a page
+	// without lang is compared by its text.
+	result, err = AnalyzeWithOptions(context.Background(), strings.NewReader(`<html><head><meta http-equiv="Content-Language" content="en, de"></head><body><p>Welcome to our online store. We offer a wide range of products for the home and garden.</p></body></html>`), Options{Modules: []string{"language"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	if result.ContentLanguage != "en, de" || len(result.Findings) != 1 || result.Findings[0].Rule != "lang-missing" {
+		t.Errorf("Expected only a missing lang finding, got %+v", result.Findings)
+	}
+}
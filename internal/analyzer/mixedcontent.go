@@ -0,0 +1,51 @@
+package analyzer
+
+import "net/url"
+
+// Subresource kinds. Browsers block active mixed content, which can change
+// the whole page, and load, upgrade or warn about passive content such as
+// images and media. Forms that submit over plain HTTP are warned about when
+// they are submitted.
+const (
+	MixedContentActive  = "active"
+	MixedContentPassive = "passive"
+	MixedContentForm    = "form"
+)
+
+// MixedContent is a reference to a plain HTTP URL from a page served over
+// HTTPS.
+type MixedContent struct {
+	// URL is the reference resolved against the page.
+	URL  string `json:"url"`
+	Kind string `json:"kind"`
+	// Element and Attribute are where the reference is written. Attribute is
+	// empty for url() references in <style> elements.
+	Element   string `json:"element"`
+	Attribute string `json:"attribute,omitempty"`
+	// Path locates the element with a selector Query accepts.
+	Path string `json:"path"`
+}
+
+// mixedContent lists the references to plain HTTP URLs among refs, which
+// are resolved with resolve.
+func mixedContent(refs []subresource, resolve func(*url.URL) *url.URL) []MixedContent {
+	var mixed []MixedContent
+	for _, ref := range refs {
+		u, err := url.Parse(ref.url)
+		if err != nil {
+			continue
+		}
+		resolved := resolve(u)
+		if resolved.Scheme != "http" {
+			continue
+		}
+		mixed = append(mixed, MixedContent{
+			URL:       resolved.String(),
+			Kind:      ref.kind,
+			Element:   ref.node.Data,
+			Attribute: ref.attribute,
+			Path:      elementPath(ref.node),
+		})
+	}
+	return mixed
+}
@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const mixedContentHTML = `<!DOCTYPE html>
+<html>
+<head>
+	<link rel="stylesheet" href="http://cdn.example.com/site.css">
+	<link rel="icon" href="http://example.com/favicon.ico">
+	<link rel="canonical" href="http://example.com/">
+	<script src="//cdn.example.com/app.js"></script>
+	<style>@import url("http://fonts.example.com/css"); body { background: url(http://example.com/bg.png) }</style>
+</head>
+<body>
+	<div><img src="/logo.png"></div>
+	<div>
+		<img src="https://example.com/a.png" srcset="http://example.com/a-2x.png 2x, a-3x.png 3x">
+		<iframe src="http://widgets.example.com/"></iframe>
+	</div>
+	<form action="http://example.com/login"><button formaction="/secure">Go</button></form>
+	<a href="http://example.com/">Links are not loaded</a>
+	<template><script src="http://example.com/unused.js"></script></template>
+</body>
+</html>`
+
+func TestAnalyze_MixedContent(t *testing.T) {
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(mixedContentHTML), Options{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	expected := []MixedContent{
+		{URL: "http://cdn.example.com/site.css", Kind: MixedContentActive, Element: "link", Attribute: "href", Path: "html > head > link:nth-of-type(1)"},
+		{URL: "http://example.com/favicon.ico", Kind: MixedContentPassive, Element: "link", Attribute: "href", Path: "html > head > link:nth-of-type(2)"},
+		{URL: "http://fonts.example.com/css", Kind: MixedContentActive, Element: "style", Path: "html > head > style"},
+		{URL: "http://example.com/bg.png", Kind: MixedContentPassive, Element: "style", Path: "html > head > style"},
+		{URL: "http://example.com/a-2x.png", Kind: MixedContentPassive, Element: "img", Attribute: "srcset", Path: "html > body > div:nth-of-type(2) > img"},
+		{URL: "http://widgets.example.com/", Kind: MixedContentActive, Element: "iframe", Attribute: "src", Path: "html > body > div:nth-of-type(2) > iframe"},
+		{URL: "http://example.com/login", Kind: MixedContentForm, Element: "form", Attribute: "action", Path: "html > body > form"},
+	}
+	if len(result.MixedContent) != len(expected) {
+		t.Fatalf("Expected %d mixed content references, got %+v", len(expected), result.MixedContent)
+	}
+	for i, ref := range result.MixedContent {
+		if ref != expected[i] {
+			t.Errorf("Reference %d: expected %+v, got %+v", i, expected[i], ref)
+		}
+	}
+
+	// Paths select the element they describe.
+	results, err := Query(context.Background(), strings.NewReader(mixedContentHTML), []string{expected[5].Path}, 1)
+	if err != nil {
+		t.Fatalf("Query() returned error: %v", err)
+	}
+	if results[0].Count != 1 || results[0].Matches[0].Tag != "iframe" {
+		t.Errorf("Expected the path to select the iframe, got %+v", results[0])
+	}
+}
+
+func TestAnalyze_MixedContentBase(t *testing.T) {
+	page := `<html><head><base href="http://static.example.com/"></head><body><script src="app.js"></script><img src="https://example.com/a.png"></body></html>`
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(page), Options{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	if len(result.MixedContent) != 1 || result.MixedContent[0].URL != "http://static.example.com/app.js" {
+		t.Errorf("Expected relative references to resolve against <base>, got %+v", result.MixedContent)
+	}
+
+	// Pages served over plain HTTP, or from an unknown URL, have no mixed
+	// content.
+	for _, pageURL := range []string{"http://example.com/", ""} {
+		result, err = AnalyzeWithOptions(context.Background(), strings.NewReader(mixedContentHTML), Options{URL: pageURL})
+		if err != nil {
+			t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+		}
+		if len(result.MixedContent) != 0 {
+			t.Errorf("Expected no mixed content for %q, got %+v", pageURL, result.MixedContent)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+// Finding severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// ErrUnknownModule is returned when an analysis names a module that is not
+// registered.
+var ErrUnknownModule = errors.New("unknown analyzer module")
+
+// Finding is a problem or observation reported by a module.
+type Finding struct {
+	// Module is the name of the reporting module. It is filled in by the
+	// analyzer.
+	Module   string `json:"module"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+	// Element describes where the finding applies, such as a tag and its
+	// identifying attribute.
+	Element string `json:"element,omitempty"`
+}
+
+// Module is an independent check run during the analysis. A new module is
+// created for every analysis, so it may keep per-document state.
+type Module interface {
+	// Visit is called for every node of the document, in document order.
+	Visit(n *html.Node)
+	// Finalize is called once the whole document has been visited and adds the
+	// module's results to result, typically as findings.
+	Finalize(result *AnalysisResult)
+}
+
+// ModuleInfo describes a registered module.
+type ModuleInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type registeredModule struct {
+	info    ModuleInfo
+	factory func() Module
+}
+
+type namedModule struct {
+	name   string
+	module Module
+}
+
+var (
+	modulesMu sync.RWMutex
+	modules   = make(map[string]registeredModule)
+)
+
+// RegisterModule makes a module available under name, replacing any module
+// already registered with it. factory is called once per analysis.
+func RegisterModule(name, description string, factory func() Module) {
+	modulesMu.Lock()
+	defer modulesMu.Unlock()
+	modules[name] = registeredModule{ModuleInfo{name, description}, factory}
+}
+
+// Modules lists the registered modules by name.
+func Modules() []ModuleInfo {
+	modulesMu.RLock()
+	defer modulesMu.RUnlock()
+	infos := make([]ModuleInfo, 0, len(modules))
+	for _, m := range modules {
+		infos = append(infos, m.info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// ValidateModules returns an error wrapping ErrUnknownModule if any of names
+// is not registered.
+func ValidateModules(names []string) error {
+	_, err := newModules(names)
+	return err
+}
+
+// newModules creates the named modules, once each, in the order given.
+func newModules(names []string) ([]namedModule, error) {
+	modulesMu.RLock()
+	defer modulesMu.RUnlock()
+	var created []namedModule
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		m, ok := modules[name]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownModule, name)
+		}
+		created = append(created, namedModule{name, m.factory()})
+	}
+	return created, nil
+}
+
+// walk visits n and its descendants in document order with every module.
+func walk(n *html.Node, visitors []namedModule) {
+	for _, v := range visitors {
+		v.module.Visit(n)
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visitors)
+	}
+}
+
+// attr returns the value of the named attribute of n.
+func attr(n *html.Node, name string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// textContent returns the whitespace-normalized text of n and its descendants.
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			b.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collect(c)
+		}
+	}
+	collect(n)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// describeElement names n by its tag and the first identifying attribute.
+func describeElement(n *html.Node) string {
+	for _, key := range []string{"id", "src", "href", "name"} {
+		if value, ok := attr(n, key); ok && value != "" {
+			return fmt.Sprintf("<%s %s=%q>", n.Data, key, value)
+		}
+	}
+	return "<" + n.Data + ">"
+}
+
+// elementPath locates n by the chain of elements from the root, such as
+// "html > body > div:nth-of-type(2) > img", in a form Query accepts.
+// Positions are only given among siblings of the same type.
+func elementPath(n *html.Node) string {
+	var steps []string
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		step := n.Data
+		if position := siblingPosition(n, false, true); position > 1 || siblingPosition(n, true, true) > 1 {
+			step += fmt.Sprintf(":nth-of-type(%d)", position)
+		}
+		steps = append(steps, step)
+	}
+	slices.Reverse(steps)
+	return strings.Join(steps, " > ")
+}
@@ -0,0 +1,202 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// countingModule counts the elements it visits and reports them as one
+// finding.
+type countingModule struct {
+	elements int
+}
+
+func (m *countingModule) Visit(n *html.Node) {
+	if n.Type == html.ElementNode {
+		m.elements++
+	}
+}
+
+func (m *countingModule) Finalize(result *AnalysisResult) {
+	result.Findings = append(result.Findings, Finding{Rule: "elements", Severity: SeverityInfo, Message: strings.Repeat("x", m.elements)})
+}
+
+func TestAnalyzeWithOptions_CustomModule(t *testing.T) {
+	created := 0
+	RegisterModule("test-counter", "Counts elements", func() Module {
+		created++
+		return &countingModule{}
+	})
+	defer func() {
+		modulesMu.Lock()
+		delete(modules, "test-counter")
+		modulesMu.Unlock()
+	}()
+
+	doc := `<html><head><title>T</title></head><body><p>a</p><p>b</p></body></html>`
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(doc), Options{Modules: []string{"test-counter", "test-counter"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	if created != 1 {
+		t.Errorf("Expected the module to be created once, got %d", created)
+	}
+	if len(result.Findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %v", result.Findings)
+	}
+	finding := result.Findings[0]
+	if finding.Module != "test-counter" || len(finding.Message) != 6 {
+		t.Errorf("Expected a test-counter finding for 6 elements, got %+v", finding)
+	}
+	if result.Title != "T" {
+		t.Errorf("Expected the core analysis to run alongside modules, got title %q", result.Title)
+	}
+}
+
+func TestAnalyzeWithOptions_UnknownModule(t *testing.T) {
+	_, err := AnalyzeWithOptions(context.Background(), strings.NewReader("<p>x</p>"), Options{Modules: []string{"seo", "no-such-module"}})
+	if !errors.Is(err, ErrUnknownModule) {
+		t.Errorf("Expected ErrUnknownModule, got %v", err)
+	}
+	if err := ValidateModules([]string{"seo", "accessibility"}); err != nil {
+		t.Errorf("Expected built-in modules to validate, got %v", err)
+	}
+}
+
+func TestAnalyze_NoModulesNoFindings(t *testing.T) {
+	result, err := Analyze(strings.NewReader(`<html><body><img src="a.png"></body></html>`))
+	if err != nil {
+		t.Fatalf("Analyze() returned error: %v", err)
+	}
+	if len(result.Findings) != 0 {
+		t.Errorf("Expected no findings without modules, got %v", result.Findings)
+	}
+}
+
+func findingRules(findings []Finding) map[string]int {
+	rules := make(map[string]int)
+	for _, finding := range findings {
+		rules[finding.Module+"/"+finding.Rule]++
+	}
+	return rules
+}
+
+func TestSEOModule(t *testing.T) {
+	testCases := []struct {
+		name     string
+		html     string
+		expected map[string]int
+	}{
+		{
+			name: "complete page",
+			html: `<html><head><title>Home</title><meta name="description" content="About us">
+				<link rel="canonical" href="https://example.com/"></head><body><h1>Home</h1></body></html>`,
+			expected: map[string]int{},
+		},
+		{
+			name: "bare page",
+			html: `<html><body><h1>A</h1><h1>B</h1><meta name="robots" content="NOINDEX, follow"></body></html>`,
+			expected: map[string]int{
+				"seo/title-missing": 1, "seo/description-missing": 1, "seo/h1-multiple": 1,
+				"seo/canonical-missing": 1, "seo/noindex": 1,
+			},
+		},
+		{
+			name: "long metadata",
+			html: `<title>` + strings.Repeat("t", 61) + `</title><meta name="description" content="` + strings.Repeat("d", 161) + `">
+				<link rel="canonical" href="/">`,
+			expected: map[string]int{"seo/title-too-long": 1, "seo/description-too-long": 1, "seo/h1-missing": 1},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(tc.html), Options{Modules: []string{"seo"}})
+			if err != nil {
+				t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+			}
+			rules := findingRules(result.Findings)
+			if len(rules) != len(tc.expected) {
+				t.Errorf("Expected findings %v, got %v", tc.expected, rules)
+			}
+			for rule, count := range tc.expected {
+				if rules[rule] != count {
+					t.Errorf("Expected %d %s findings, got %d", count, rule, rules[rule])
+				}
+			}
+		})
+	}
+}
+
+func TestAccessibilityModule(t *testing.T) {
+	doc := `<html><body>
+		<img src="logo.png">
+		<img src="spacer.gif" alt="">
+		<a href="/home"><img src="home.png" alt="Home"></a>
+		<a href="/x"></a>
+		<a href="/y" aria-label="Close"></a>
+		<button></button>
+		<button>Save</button>
+		<h1>Title</h1><h3>Skipped</h3>
+		<input id="email" type="email"><label for="email">Email</label>
+		<input id="name" type="text">
+		<label>Phone <input type="tel"></label>
+		<input type="hidden" name="token">
+		<textarea></textarea>
+	</body></html>`
+
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(doc), Options{Modules: []string{"accessibility"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	expected := map[string]int{
+		"accessibility/html-lang":     1,
+		"accessibility/img-alt":       1,
+		"accessibility/link-name":     1,
+		"accessibility/button-name":   1,
+		"accessibility/heading-order": 1,
+		"accessibility/form-label":    2,
+	}
+	rules := findingRules(result.Findings)
+	if len(rules) != len(expected) {
+		t.Errorf("Expected findings %v, got %v", expected, rules)
+	}
+	for rule, count := range expected {
+		if rules[rule] != count {
+			t.Errorf("Expected %d %s findings, got %d", count, rule, rules[rule])
+		}
+	}
+	for _, finding := range result.Findings {
+		if finding.Rule == "img-alt" && finding.Element != `<img src="logo.png">` {
+			t.Errorf("Expected the image to be described by its src, got %q", finding.Element)
+		}
+	}
+}
+
+func TestAccessibilityModule_FormLabelsByID(t *testing.T) {
+	doc := `<html lang="en"><body>
+		<input id="q" type="search">
+		<textarea id="q"></textarea>
+		<select id="country"></select>
+		<label for="country">Country</label>
+	</body></html>`
+
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(doc), Options{Modules: []string{"accessibility"}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	var elements []string
+	for _, finding := range result.Findings {
+		if finding.Rule == "form-label" {
+			elements = append(elements, finding.Element)
+		}
+	}
+	expected := []string{`<input id="q">`, `<textarea id="q">`}
+	if !slices.Equal(elements, expected) {
+		t.Errorf("Expected form-label findings for %v, got %v", expected, elements)
+	}
+}
@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxOuterHTML bounds the markup returned for one matched element.
+	maxOuterHTML = 64 << 10
+	// maxQueryHTML bounds the markup returned for all the matches of a query.
+	maxQueryHTML = 4 << 20
+)
+
+var errOuterHTMLFull = errors.New("outer HTML limit reached")
+
+// QueryMatch is an element matched by a selector query.
+type QueryMatch struct {
+	Tag        string            `json:"tag"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Text       string            `json:"text"`
+	OuterHTML  string            `json:"outer_html"`
+	// Truncated is set when OuterHTML was cut short, or left out because the
+	// matches listed before it used up the markup budget of the query.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// QueryResult lists the elements matched by one selector.
+type QueryResult struct {
+	Selector string `json:"selector"`
+	// Count is the number of matching elements, including any beyond the
+	// limit that are not listed in Matches.
+	Count   int          `json:"count"`
+	Matches []QueryMatch `json:"matches"`
+}
+
+// Query parses the document read from body and runs the selectors against it
+// in one traversal, listing at most limit matches per selector; a limit of
+// zero or less lists none, so that only the counts are returned. Invalid
+// selectors are reported, wrapping ErrInvalidSelector, before body is read.
+// The outer HTML of the matches is capped per element and for the whole query.
+func Query(ctx context.Context, body io.Reader, selectors []string, limit int) ([]QueryResult, error) {
+	ctx, span := tracing.Start(ctx, "analyzer.Query")
+	defer span.End()
+
+	compiled := make([]*Selector, len(selectors))
+	for i, source := range selectors {
+		selector, err := ParseSelector(source)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		compiled[i] = selector
+	}
+
+	doc, err := html.Parse(body)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logger.WithContext(ctx).WithField("phase", "parse").WithField("error", err).Error("Failed to parse HTML")
+		return nil, err
+	}
+
+	results := make([]QueryResult, len(compiled))
+	for i, selector := range compiled {
+		results[i] = QueryResult{Selector: selector.String(), Matches: []QueryMatch{}}
+	}
+	budget := maxQueryHTML
+	for n := range doc.Descendants() {
+		for i, selector := range compiled {
+			if !selector.Match(n) {
+				continue
+			}
+			results[i].Count++
+			if len(results[i].Matches) < limit {
+				match := newQueryMatch(n, min(maxOuterHTML, budget))
+				budget -= len(match.OuterHTML)
+				results[i].Matches = append(results[i].Matches, match)
+			}
+		}
+	}
+	return results, nil
+}
+
+// newQueryMatch describes n, with at most maxHTML bytes of its outer HTML.
+func newQueryMatch(n *html.Node, maxHTML int) QueryMatch {
+	match := QueryMatch{Tag: n.Data, Text: textContent(n)}
+	if len(n.Attr) > 0 {
+		match.Attributes = make(map[string]string, len(n.Attr))
+		for _, a := range n.Attr {
+			match.Attributes[a.Key] = a.Val
+		}
+	}
+	w := &cappedWriter{limit: maxHTML}
+	html.Render(w, n)
+	match.OuterHTML = w.b.String()
+	if w.full {
+		match.OuterHTML = strings.ToValidUTF8(match.OuterHTML, "")
+		match.Truncated = true
+	}
+	return match
+}
+
+// cappedWriter keeps the first limit bytes written to it and then fails, so
+// that html.Render stops early on large elements.
+type cappedWriter struct {
+	b     strings.Builder
+	limit int
+	full  bool
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	if room := w.limit - w.b.Len(); len(p) > room {
+		w.b.Write(p[:room])
+		w.full = true
+		return room, errOuterHTMLFull
+	}
+	return w.b.Write(p)
+}
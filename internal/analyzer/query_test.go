@@ -0,0 +1,91 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	results, err := Query(context.Background(), strings.NewReader(selectorHTML), []string{".product-card", "#main > h1", "table"}, 1)
+	if err != nil {
+		t.Fatalf("Query() returned error: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected one result per selector, got %d", len(results))
+	}
+
+	cards := results[0]
+	if cards.Selector != ".product-card" || cards.Count != 2 || len(cards.Matches) != 1 {
+		t.Errorf("Expected 2 product cards with 1 listed, got %+v", cards)
+	}
+	card := cards.Matches[0]
+	if card.Tag != "li" || card.Text != "A" || card.Attributes["data-sku"] != "A-1" || card.Attributes["class"] != "product-card sale" {
+		t.Errorf("Unexpected match %+v", card)
+	}
+	expectedHTML := `<li class="product-card sale" data-sku="A-1"><a href="https://shop.example.com/a">A</a></li>`
+	if card.OuterHTML != expectedHTML {
+		t.Errorf("Expected outer HTML %q, got %q", expectedHTML, card.OuterHTML)
+	}
+
+	if results[1].Count != 1 || results[1].Matches[0].Text != "Title" {
+		t.Errorf("Expected the heading, got %+v", results[1])
+	}
+	if results[2].Count != 0 || results[2].Matches == nil {
+		t.Errorf("Expected no matches as an empty list, got %+v", results[2])
+	}
+}
+
+func TestQuery_CountsOnly(t *testing.T) {
+	results, err := Query(context.Background(), strings.NewReader(selectorHTML), []string{"a"}, 0)
+	if err != nil {
+		t.Fatalf("Query() returned error: %v", err)
+	}
+	if results[0].Count != 3 || len(results[0].Matches) != 0 {
+		t.Errorf("Expected a count of 3 without matches, got %+v", results[0])
+	}
+}
+
+func TestQuery_TruncatesOuterHTML(t *testing.T) {
+	doc := "<div id=big>" + strings.Repeat("é", maxOuterHTML) + "</div>"
+	results, err := Query(context.Background(), strings.NewReader(doc), []string{"#big"}, 1)
+	if err != nil {
+		t.Fatalf("Query() returned error: %v", err)
+	}
+	match := results[0].Matches[0]
+	if !match.Truncated || len(match.OuterHTML) > maxOuterHTML || !strings.HasPrefix(match.OuterHTML, `<div id="big">é`) {
+		t.Errorf("Expected truncated outer HTML, got %d bytes, truncated=%v", len(match.OuterHTML), match.Truncated)
+	}
+	if len(match.Text) != 2*maxOuterHTML {
+		t.Errorf("Expected the full text, got %d bytes", len(match.Text))
+	}
+}
+
+func TestQuery_CapsTotalOuterHTML(t *testing.T) {
+	element := "<div>" + strings.Repeat("x", maxOuterHTML-20) + "</div>"
+	doc := strings.Repeat(element, 2*maxQueryHTML/maxOuterHTML)
+	results, err := Query(context.Background(), strings.NewReader(doc), []string{"div"}, 1000)
+	if err != nil {
+		t.Fatalf("Query() returned error: %v", err)
+	}
+
+	total := 0
+	for _, match := range results[0].Matches {
+		total += len(match.OuterHTML)
+	}
+	last := results[0].Matches[len(results[0].Matches)-1]
+	if total > maxQueryHTML || last.OuterHTML != "" || !last.Truncated {
+		t.Errorf("Expected at most %d bytes of outer HTML and the last match left out, got %d bytes and %+v", maxQueryHTML, total, last.Truncated)
+	}
+	if first := results[0].Matches[0]; first.Truncated {
+		t.Error("Expected the first match to be complete")
+	}
+}
+
+func TestQuery_InvalidSelector(t *testing.T) {
+	_, err := Query(context.Background(), strings.NewReader(selectorHTML), []string{"li", "li:hover"}, 1)
+	if !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("Expected ErrInvalidSelector, got %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"golang.org/x/net/html"
+)
+
+// RulesModule is the name the rule set of a rules file is registered under.
+const RulesModule = "rules"
+
+// Rule is a declarative check from a rules file. A rule either matches
+// elements with Selector or links with Link:
+//   - Link: every link whose href, as written, matches the regular expression
+//     is reported.
+//   - Selector with Min or Max: the number of matching elements must be
+//     within the bounds.
+//   - Selector with Attribute: every matching element must have the
+//     attribute, and its value must match Pattern when one is given.
+//   - Selector with Text: the text of every matching element must match the
+//     regular expression.
+//   - Selector alone: every matching element is reported.
+//
+// The selector options combine: a rule may bound the count of elements and
+// check each of them.
+type Rule struct {
+	ID       string `json:"id"`
+	Message  string `json:"message,omitempty"`
+	Severity string `json:"severity,omitempty"`
+
+	Selector  string `json:"selector,omitempty"`
+	Min       *int   `json:"min,omitempty"`
+	Max       *int   `json:"max,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	Text      string `json:"text,omitempty"`
+
+	Link string `json:"link,omitempty"`
+}
+
+// RuleSet is a compiled set of rules.
+type RuleSet struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	selector *Selector
+	pattern  *regexp.Regexp
+	text     *regexp.Regexp
+	link     *regexp.Regexp
+}
+
+// ParseRules reads a JSON rules file of the form {"rules": [...]} and
+// compiles its rules, reporting the first invalid one.
+func ParseRules(r io.Reader) (*RuleSet, error) {
+	var file struct {
+		Rules []Rule `json:"rules"`
+	}
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid rules file: %w", err)
+	}
+	return CompileRules(file.Rules)
+}
+
+// CompileRules validates and compiles rules.
+func CompileRules(rules []Rule) (*RuleSet, error) {
+	set := &RuleSet{}
+	seen := make(map[string]bool)
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			if rule.ID == "" {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			return nil, fmt.Errorf("rule %q: %w", rule.ID, err)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("rule %q: duplicate id", rule.ID)
+		}
+		seen[rule.ID] = true
+		set.rules = append(set.rules, compiled)
+	}
+	return set, nil
+}
+
+func compileRule(rule Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule}
+	if rule.ID == "" {
+		return c, errors.New("missing id")
+	}
+	switch rule.Severity {
+	case "":
+		c.Severity = SeverityWarning
+	case SeverityError, SeverityWarning, SeverityInfo:
+	default:
+		return c, fmt.Errorf("unknown severity %q", rule.Severity)
+	}
+
+	var err error
+	compile := func(field, expr string) *regexp.Regexp {
+		if expr == "" || err != nil {
+			return nil
+		}
+		re, compileErr := regexp.Compile(expr)
+		if compileErr != nil {
+			err = fmt.Errorf("invalid %s: %w", field, compileErr)
+		}
+		return re
+	}
+	c.pattern = compile("pattern", rule.Pattern)
+	c.text = compile("text", rule.Text)
+	c.link = compile("link", rule.Link)
+	if err != nil {
+		return c, err
+	}
+
+	switch {
+	case rule.Link != "" && rule.Selector != "":
+		return c, errors.New("a rule takes either a selector or a link pattern, not both")
+	case rule.Link != "":
+		if rule.Min != nil || rule.Max != nil || rule.Attribute != "" || rule.Pattern != "" || rule.Text != "" {
+			return c, errors.New("link rules take no selector options")
+		}
+		return c, nil
+	case rule.Selector == "":
+		return c, errors.New("missing selector or link pattern")
+	}
+	if c.selector, err = ParseSelector(rule.Selector); err != nil {
+		return c, err
+	}
+	if rule.Pattern != "" && rule.Attribute == "" {
+		return c, errors.New("pattern needs an attribute")
+	}
+	if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+		return c, errors.New("min is greater than max")
+	}
+	return c, nil
+}
+
+// Rules returns the rules of the set.
+func (s *RuleSet) Rules() []Rule {
+	rules := make([]Rule, len(s.rules))
+	for i, rule := range s.rules {
+		rules[i] = rule.Rule
+	}
+	return rules
+}
+
+// NewModule returns a module evaluating the rule set against one document.
+func (s *RuleSet) NewModule() Module {
+	return &rulesModule{rules: s.rules, counts: make([]int, len(s.rules))}
+}
+
+type rulesModule struct {
+	rules    []compiledRule
+	counts   []int
+	findings []Finding
+}
+
+func (m *rulesModule) Visit(n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	for i, rule := range m.rules {
+		if rule.selector == nil || !rule.selector.Match(n) {
+			continue
+		}
+		m.counts[i]++
+		if problem := rule.elementProblem(n); problem != "" {
+			m.findings = append(m.findings, rule.finding(problem, describeElement(n)))
+		}
+	}
+}
+
+// elementProblem describes how n breaks the rule, or returns "".
+func (r compiledRule) elementProblem(n *html.Node) string {
+	if r.Attribute != "" {
+		value, ok := attr(n, r.Attribute)
+		if !ok {
+			return fmt.Sprintf("Element has no %s attribute", r.Attribute)
+		}
+		if r.pattern != nil && !r.pattern.MatchString(value) {
+			return fmt.Sprintf("Attribute %s=%q does not match %s", r.Attribute, value, r.Pattern)
+		}
+	}
+	if r.text != nil {
+		if text := textContent(n); !r.text.MatchString(text) {
+			return fmt.Sprintf("Text %q does not match %s", text, r.Text)
+		}
+	}
+	if r.Attribute == "" && r.text == nil && r.Min == nil && r.Max == nil {
+		return fmt.Sprintf("Element matches %s", r.Selector)
+	}
+	return ""
+}
+
+func (r compiledRule) finding(problem, element string) Finding {
+	message := problem
+	if r.Message != "" {
+		message = r.Message
+	}
+	return Finding{Rule: r.ID, Severity: r.Severity, Message: message, Element: element}
+}
+
+func (r compiledRule) countFinding(bound string, count int) Finding {
+	finding := r.finding(fmt.Sprintf("Expected %s elements matching %s, found %d", bound, r.Selector, count), "")
+	if r.Message != "" {
+		finding.Message = fmt.Sprintf("%s (found %d)", r.Message, count)
+	}
+	return finding
+}
+
+func (m *rulesModule) Finalize(result *AnalysisResult) {
+	for i, rule := range m.rules {
+		switch {
+		case rule.link != nil:
+			for _, link := range result.Links {
+				if rule.link.MatchString(link) {
+					m.findings = append(m.findings, rule.finding("Link matches "+rule.Link, link))
+				}
+			}
+		case rule.Min != nil && m.counts[i] < *rule.Min:
+			m.findings = append(m.findings, rule.countFinding(fmt.Sprintf("at least %d", *rule.Min), m.counts[i]))
+		case rule.Max != nil && m.counts[i] > *rule.Max:
+			m.findings = append(m.findings, rule.countFinding(fmt.Sprintf("at most %d", *rule.Max), m.counts[i]))
+		}
+	}
+	result.Findings = append(result.Findings, m.findings...)
+}
@@ -0,0 +1,127 @@
+package analyzer
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+const houseRules = `{"rules": [
+  {"id": "body-analytics-id", "severity": "error", "message": "body needs a data-analytics-id",
+   "selector": "body", "attribute": "data-analytics-id", "pattern": "^[a-z-]+$"},
+  {"id": "no-staging-links", "severity": "error", "link": "^https?://staging\\.example\\.com"},
+  {"id": "title-suffix", "message": "Title must end with ' | Acme'", "selector": "title", "text": " \\| Acme$", "min": 1},
+  {"id": "one-h1", "selector": "h1", "min": 1, "max": 1},
+  {"id": "no-marquee", "severity": "info", "selector": "marquee"}
+]}`
+
+func analyzeWithRules(t *testing.T, rules *RuleSet, doc string) map[string][]Finding {
+	t.Helper()
+	RegisterModule(RulesModule, "test rules", rules.NewModule)
+	defer func() {
+		modulesMu.Lock()
+		delete(modules, RulesModule)
+		modulesMu.Unlock()
+	}()
+	result, err := AnalyzeWithOptions(context.Background(), strings.NewReader(doc), Options{Modules: []string{RulesModule}})
+	if err != nil {
+		t.Fatalf("AnalyzeWithOptions() returned error: %v", err)
+	}
+	byRule := make(map[string][]Finding)
+	for _, finding := range result.Findings {
+		if finding.Module != RulesModule {
+			t.Errorf("Expected findings from the rules module, got %+v", finding)
+		}
+		byRule[finding.Rule] = append(byRule[finding.Rule], finding)
+	}
+	return byRule
+}
+
+func TestRules_Passing(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(houseRules))
+	if err != nil {
+		t.Fatalf("ParseRules() returned error: %v", err)
+	}
+	doc := `<html><head><title>Home | Acme</title></head>
+		<body data-analytics-id="home"><h1>Home</h1><a href="https://www.example.com/">Live</a></body></html>`
+	if findings := analyzeWithRules(t, rules, doc); len(findings) != 0 {
+		t.Errorf("Expected no findings, got %v", findings)
+	}
+}
+
+func TestRules_Violations(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(houseRules))
+	if err != nil {
+		t.Fatalf("ParseRules() returned error: %v", err)
+	}
+	doc := `<html><head><title>Home</title></head>
+		<body data-analytics-id="Home Page"><h1>A</h1><h1>B</h1>
+		<a href="https://staging.example.com/a">Staging</a><a href="http://staging.example.com/b">Staging</a>
+		<marquee>Sale!</marquee></body></html>`
+	findings := analyzeWithRules(t, rules, doc)
+
+	expected := map[string]int{"body-analytics-id": 1, "no-staging-links": 2, "title-suffix": 1, "one-h1": 1, "no-marquee": 1}
+	if len(findings) != len(expected) {
+		t.Errorf("Expected findings for %v, got %v", expected, findings)
+	}
+	for rule, count := range expected {
+		if len(findings[rule]) != count {
+			t.Errorf("Expected %d %s findings, got %v", count, rule, findings[rule])
+		}
+	}
+	if f := findings["body-analytics-id"]; len(f) == 1 && (f[0].Severity != SeverityError || f[0].Message != "body needs a data-analytics-id" || f[0].Element != "<body>") {
+		t.Errorf("Expected the custom severity and message, got %+v", f[0])
+	}
+	if f := findings["one-h1"]; len(f) == 1 && (f[0].Severity != SeverityWarning || f[0].Message != "Expected at most 1 elements matching h1, found 2") {
+		t.Errorf("Expected a default warning describing the count, got %+v", f[0])
+	}
+	if f := findings["title-suffix"]; len(f) == 1 && f[0].Message != "Title must end with ' | Acme'" {
+		t.Errorf("Expected the custom message, got %+v", f[0])
+	}
+	if f := findings["no-staging-links"]; len(f) == 2 && f[1].Element != "http://staging.example.com/b" {
+		t.Errorf("Expected the link as the element, got %+v", f[1])
+	}
+}
+
+func TestRules_MissingElementCounts(t *testing.T) {
+	rules, err := ParseRules(strings.NewReader(houseRules))
+	if err != nil {
+		t.Fatalf("ParseRules() returned error: %v", err)
+	}
+	findings := analyzeWithRules(t, rules, `<p>no title, no h1</p>`)
+	if f := findings["title-suffix"]; len(f) != 1 || f[0].Message != "Title must end with ' | Acme' (found 0)" {
+		t.Errorf("Expected a missing title to break the min bound, got %v", f)
+	}
+	if len(findings["one-h1"]) != 1 {
+		t.Errorf("Expected a missing h1 to break the min bound, got %v", findings["one-h1"])
+	}
+}
+
+func TestParseRules_Invalid(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rules    string
+		expected string
+	}{
+		{"not JSON", `rules: []`, "invalid rules file"},
+		{"unknown field", `{"rules": [{"id": "a", "selector": "p", "regex": "x"}]}`, "unknown field"},
+		{"missing id", `{"rules": [{"selector": "p"}]}`, "rule 1: missing id"},
+		{"duplicate id", `{"rules": [{"id": "a", "selector": "p"}, {"id": "a", "selector": "div"}]}`, `rule "a": duplicate id`},
+		{"no target", `{"rules": [{"id": "a"}]}`, "missing selector or link pattern"},
+		{"both targets", `{"rules": [{"id": "a", "selector": "p", "link": "x"}]}`, "not both"},
+		{"bad selector", `{"rules": [{"id": "a", "selector": "p >"}]}`, "invalid selector"},
+		{"bad regex", `{"rules": [{"id": "a", "selector": "p", "text": "("}]}`, "invalid text"},
+		{"bad severity", `{"rules": [{"id": "a", "selector": "p", "severity": "fatal"}]}`, "unknown severity"},
+		{"pattern without attribute", `{"rules": [{"id": "a", "selector": "p", "pattern": "x"}]}`, "pattern needs an attribute"},
+		{"bounds", `{"rules": [{"id": "a", "selector": "p", "min": 2, "max": 1}]}`, "min is greater than max"},
+		{"link options", `{"rules": [{"id": "a", "link": "x", "min": 1}]}`, "link rules take no selector options"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseRules(strings.NewReader(tc.rules))
+			if err == nil || !strings.Contains(err.Error(), tc.expected) {
+				t.Errorf("Expected an error containing %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
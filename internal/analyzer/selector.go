@@ -0,0 +1,503 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ErrInvalidSelector is returned for selectors that cannot be parsed.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Selector is a compiled CSS selector. It supports type, universal, id, class
+// and attribute selectors (=, ~=, |=, ^=, $= and *=), the structural
+// pseudo-classes (:first-child, :last-child, :only-child, :nth-child(),
+// :nth-last-child(), :first-of-type, :last-of-type, :nth-of-type(), :empty)
+// and :not(), the descendant, child, next-sibling and subsequent-sibling
+// combinators, and selector lists.
+type Selector struct {
+	source string
+	groups []complexSelector
+}
+
+// complexSelector is a chain of compound selectors joined by combinators;
+// combinators[i] joins parts[i] and parts[i+1].
+type complexSelector struct {
+	parts       []compoundSelector
+	combinators []byte
+}
+
+type compoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []attrSelector
+	pseudos []pseudoClass
+}
+
+// pseudoClass is a structural pseudo-class. The nth forms match elements at
+// positions a*n+b for some n >= 0, counting from 1.
+type pseudoClass struct {
+	name string
+	a, b int
+	not  *Selector
+}
+
+type attrSelector struct {
+	name  string
+	op    string
+	value string
+}
+
+// ParseSelector compiles a CSS selector.
+func ParseSelector(source string) (*Selector, error) {
+	p := &selectorParser{source: source}
+	selector := &Selector{source: source}
+	for {
+		group, err := p.parseComplex()
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidSelector, source, err)
+		}
+		selector.groups = append(selector.groups, group)
+		p.skipSpace()
+		if p.done() {
+			return selector, nil
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("%w %q: unexpected %q at offset %d", ErrInvalidSelector, source, p.peek(), p.pos)
+		}
+	}
+}
+
+// String returns the selector as written.
+func (s *Selector) String() string {
+	return s.source
+}
+
+// Match reports whether n is an element matching the selector.
+func (s *Selector) Match(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	for _, group := range s.groups {
+		if group.match(n, len(group.parts)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether n matches parts[i] with the preceding parts matched
+// by its ancestors and siblings as the combinators require.
+func (c complexSelector) match(n *html.Node, i int) bool {
+	if !c.parts[i].match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c.combinators[i-1] {
+	case '>':
+		p := n.Parent
+		return p != nil && p.Type == html.ElementNode && c.match(p, i-1)
+	case '+':
+		p := previousElement(n)
+		return p != nil && c.match(p, i-1)
+	case '~':
+		for p := previousElement(n); p != nil; p = previousElement(p) {
+			if c.match(p, i-1) {
+				return true
+			}
+		}
+	default:
+		for p := n.Parent; p != nil && p.Type == html.ElementNode; p = p.Parent {
+			if c.match(p, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func previousElement(n *html.Node) *html.Node {
+	for p := n.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == html.ElementNode {
+			return p
+		}
+	}
+	return nil
+}
+
+func (c compoundSelector) match(n *html.Node) bool {
+	if c.tag != "" && c.tag != n.Data {
+		return false
+	}
+	if c.id != "" {
+		if id, _ := attr(n, "id"); id != c.id {
+			return false
+		}
+	}
+	if len(c.classes) > 0 {
+		class, _ := attr(n, "class")
+		classes := strings.Fields(class)
+		for _, want := range c.classes {
+			found := false
+			for _, have := range classes {
+				if have == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		if !a.match(n) {
+			return false
+		}
+	}
+	for _, p := range c.pseudos {
+		if !p.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p pseudoClass) match(n *html.Node) bool {
+	switch p.name {
+	case "not":
+		return !p.not.Match(n)
+	case "empty":
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode || (c.Type == html.TextNode && c.Data != "") {
+				return false
+			}
+		}
+		return true
+	case "first-child":
+		return siblingPosition(n, false, false) == 1
+	case "last-child":
+		return siblingPosition(n, true, false) == 1
+	case "only-child":
+		return siblingPosition(n, false, false) == 1 && siblingPosition(n, true, false) == 1
+	case "first-of-type":
+		return siblingPosition(n, false, true) == 1
+	case "last-of-type":
+		return siblingPosition(n, true, true) == 1
+	case "nth-child":
+		return p.nth(siblingPosition(n, false, false))
+	case "nth-last-child":
+		return p.nth(siblingPosition(n, true, false))
+	case "nth-of-type":
+		return p.nth(siblingPosition(n, false, true))
+	}
+	return false
+}
+
+func (p pseudoClass) nth(position int) bool {
+	if p.a == 0 {
+		return position == p.b
+	}
+	steps := position - p.b
+	return steps%p.a == 0 && steps/p.a >= 0
+}
+
+// siblingPosition returns the 1-based position of n among its element
+// siblings, counted from the end if fromEnd is set and among elements of the
+// same type if sameType is set.
+func siblingPosition(n *html.Node, fromEnd, sameType bool) int {
+	position := 1
+	next := func(s *html.Node) *html.Node {
+		if fromEnd {
+			return s.NextSibling
+		}
+		return s.PrevSibling
+	}
+	for s := next(n); s != nil; s = next(s) {
+		if s.Type == html.ElementNode && (!sameType || s.Data == n.Data) {
+			position++
+		}
+	}
+	return position
+}
+
+func (a attrSelector) match(n *html.Node) bool {
+	value, ok := attr(n, a.name)
+	if !ok {
+		return false
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == a.value
+	case "~=":
+		for _, word := range strings.Fields(value) {
+			if word == a.value {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return value == a.value || strings.HasPrefix(value, a.value+"-")
+	case "^=":
+		return a.value != "" && strings.HasPrefix(value, a.value)
+	case "$=":
+		return a.value != "" && strings.HasSuffix(value, a.value)
+	case "*=":
+		return a.value != "" && strings.Contains(value, a.value)
+	}
+	return false
+}
+
+type selectorParser struct {
+	source string
+	pos    int
+}
+
+func (p *selectorParser) done() bool { return p.pos >= len(p.source) }
+
+func (p *selectorParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.source[p.pos]
+}
+
+func (p *selectorParser) consume(c byte) bool {
+	if p.peek() == c && !p.done() {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// skipSpace skips whitespace and reports whether there was any.
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t\n\r\f", p.peek()) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) parseComplex() (complexSelector, error) {
+	var c complexSelector
+	p.skipSpace()
+	for {
+		part, err := p.parseCompound()
+		if err != nil {
+			return c, err
+		}
+		c.parts = append(c.parts, part)
+
+		spaced := p.skipSpace()
+		if p.done() || p.peek() == ',' {
+			return c, nil
+		}
+		switch combinator := p.peek(); combinator {
+		case '>', '+', '~':
+			p.pos++
+			p.skipSpace()
+			c.combinators = append(c.combinators, combinator)
+		default:
+			if !spaced {
+				return c, fmt.Errorf("unexpected %q at offset %d", combinator, p.pos)
+			}
+			c.combinators = append(c.combinators, ' ')
+		}
+	}
+}
+
+func (p *selectorParser) parseCompound() (compoundSelector, error) {
+	var c compoundSelector
+	start := p.pos
+	if p.consume('*') {
+		// Matches any element.
+	} else if name := p.parseIdent(); name != "" {
+		c.tag = strings.ToLower(name)
+	}
+	for !p.done() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			if c.id = p.parseIdent(); c.id == "" {
+				return c, fmt.Errorf("expected an id at offset %d", p.pos)
+			}
+		case '.':
+			p.pos++
+			class := p.parseIdent()
+			if class == "" {
+				return c, fmt.Errorf("expected a class name at offset %d", p.pos)
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			p.pos++
+			a, err := p.parseAttr()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, a)
+		case ':':
+			p.pos++
+			pseudo, err := p.parsePseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, pseudo)
+		default:
+			if p.pos == start {
+				if p.done() {
+					return c, errors.New("empty selector")
+				}
+				return c, fmt.Errorf("unexpected %q at offset %d", p.peek(), p.pos)
+			}
+			return c, nil
+		}
+	}
+	if p.pos == start {
+		return c, errors.New("empty selector")
+	}
+	return c, nil
+}
+
+func (p *selectorParser) parseAttr() (attrSelector, error) {
+	var a attrSelector
+	p.skipSpace()
+	if a.name = strings.ToLower(p.parseIdent()); a.name == "" {
+		return a, fmt.Errorf("expected an attribute name at offset %d", p.pos)
+	}
+	p.skipSpace()
+	if p.consume(']') {
+		return a, nil
+	}
+	for _, op := range []string{"=", "~=", "|=", "^=", "$=", "*="} {
+		if strings.HasPrefix(p.source[p.pos:], op) {
+			a.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if a.op == "" {
+		return a, fmt.Errorf("expected an attribute operator at offset %d", p.pos)
+	}
+	p.skipSpace()
+	if quote := p.peek(); quote == '"' || quote == '\'' {
+		end := strings.IndexByte(p.source[p.pos+1:], quote)
+		if end < 0 {
+			return a, errors.New("unterminated string")
+		}
+		a.value = p.source[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else if a.value = p.parseIdent(); a.value == "" {
+		return a, fmt.Errorf("expected an attribute value at offset %d", p.pos)
+	}
+	p.skipSpace()
+	if !p.consume(']') {
+		return a, fmt.Errorf("expected ] at offset %d", p.pos)
+	}
+	return a, nil
+}
+
+func (p *selectorParser) parsePseudo() (pseudoClass, error) {
+	pseudo := pseudoClass{name: strings.ToLower(p.parseIdent())}
+	switch pseudo.name {
+	case "first-child", "last-child", "only-child", "first-of-type", "last-of-type", "empty":
+		return pseudo, nil
+	case "not", "nth-child", "nth-last-child", "nth-of-type":
+	case "":
+		return pseudo, fmt.Errorf("expected a pseudo-class at offset %d", p.pos)
+	default:
+		return pseudo, fmt.Errorf("unsupported pseudo-class :%s", pseudo.name)
+	}
+
+	if !p.consume('(') {
+		return pseudo, fmt.Errorf("expected ( after :%s", pseudo.name)
+	}
+	depth, start := 1, p.pos
+	for ; !p.done() && depth > 0; p.pos++ {
+		switch p.peek() {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+	}
+	if depth > 0 {
+		return pseudo, fmt.Errorf("unterminated :%s(", pseudo.name)
+	}
+	argument := p.source[start : p.pos-1]
+
+	if pseudo.name == "not" {
+		not, err := ParseSelector(argument)
+		if err != nil {
+			return pseudo, fmt.Errorf("in :not(): %v", err)
+		}
+		pseudo.not = not
+		return pseudo, nil
+	}
+	var err error
+	if pseudo.a, pseudo.b, err = parseNth(argument); err != nil {
+		return pseudo, fmt.Errorf("in :%s(): %v", pseudo.name, err)
+	}
+	return pseudo, nil
+}
+
+// parseNth parses the an+b argument of the nth pseudo-classes, including odd
+// and even.
+func parseNth(argument string) (a, b int, err error) {
+	expr := strings.ToLower(strings.Join(strings.Fields(argument), ""))
+	switch expr {
+	case "odd":
+		return 2, 1, nil
+	case "even":
+		return 2, 0, nil
+	}
+	coefficient, offset, hasN := strings.Cut(expr, "n")
+	if !hasN {
+		b, err = strconv.Atoi(expr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid argument %q", argument)
+		}
+		return 0, b, nil
+	}
+	switch coefficient {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		if a, err = strconv.Atoi(coefficient); err != nil {
+			return 0, 0, fmt.Errorf("invalid argument %q", argument)
+		}
+	}
+	if offset != "" {
+		if offset[0] != '+' && offset[0] != '-' {
+			return 0, 0, fmt.Errorf("invalid argument %q", argument)
+		}
+		if b, err = strconv.Atoi(offset); err != nil {
+			return 0, 0, fmt.Errorf("invalid argument %q", argument)
+		}
+	}
+	return a, b, nil
+}
+
+// parseIdent reads a CSS identifier. Escapes are not supported.
+func (p *selectorParser) parseIdent() string {
+	start := p.pos
+	for !p.done() {
+		c := p.peek()
+		if c == '-' || c == '_' || c >= 0x80 || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.source[start:p.pos]
+}
@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+const selectorHTML = `<html><body>
+<div id="main" class="page wide">
+  <h1 lang="en-GB">Title</h1>
+  <ul class="products">
+    <li class="product-card sale" data-sku="A-1"><a href="https://shop.example.com/a">A</a></li>
+    <li class="product-card" data-sku="B-2"><a href="/b">B</a></li>
+    <li class="ad"><a href="https://ads.example.net/x">Ad</a></li>
+  </ul>
+  <p>Intro</p>
+  <p>More</p>
+</div>
+<footer><p>Footer</p></footer>
+</body></html>`
+
+// matching returns a description of the elements of doc matching selector, in
+// document order.
+func matching(t *testing.T, doc *html.Node, selector string) string {
+	t.Helper()
+	s, err := ParseSelector(selector)
+	if err != nil {
+		t.Fatalf("ParseSelector(%q) returned error: %v", selector, err)
+	}
+	var found []string
+	for n := range doc.Descendants() {
+		if s.Match(n) {
+			found = append(found, n.Data+":"+textContent(n))
+		}
+	}
+	return strings.Join(found, ", ")
+}
+
+func TestSelector_Match(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(selectorHTML))
+	if err != nil {
+		t.Fatalf("html.Parse() returned error: %v", err)
+	}
+
+	testCases := []struct {
+		selector string
+		expected string
+	}{
+		{"h1", "h1:Title"},
+		{"#main > h1", "h1:Title"},
+		{".product-card", "li:A, li:B"},
+		{"li.product-card.sale", "li:A"},
+		{"[data-sku]", "li:A, li:B"},
+		{`[data-sku="B-2"]`, "li:B"},
+		{"[data-sku^=A]", "li:A"},
+		{"a[href$='/x']", "a:Ad"},
+		{`a[href*="example.com"]`, "a:A"},
+		{"[class~=wide]", "div:Title A B Ad Intro More"},
+		{"[lang|=en]", "h1:Title"},
+		{"div p", "p:Intro, p:More"},
+		{"body > p", ""},
+		{"ul + p", "p:Intro"},
+		{"h1 ~ p", "p:Intro, p:More"},
+		{"footer p, h1", "h1:Title, p:Footer"},
+		{"UL > LI.ad A", "a:Ad"},
+		{"* > a", "a:A, a:B, a:Ad"},
+		{"li:first-child", "li:A"},
+		{"li:last-child", "li:Ad"},
+		{"li:nth-child(2)", "li:B"},
+		{"li:nth-child(odd)", "li:A, li:Ad"},
+		{"li:nth-child(2n)", "li:B"},
+		{"li:nth-child(-n+2)", "li:A, li:B"},
+		{"li:nth-last-child(1)", "li:Ad"},
+		{"#main > p:first-of-type", "p:Intro"},
+		{"#main > p:last-of-type", "p:More"},
+		{"p:nth-of-type(2)", "p:More"},
+		{"footer > p:only-child", "p:Footer"},
+		{".products > li:not(.ad)", "li:A, li:B"},
+		{"li:not(.sale, .ad)", "li:B"},
+		{"ul:empty, li:empty", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			if got := matching(t, doc, tc.selector); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseSelector_Invalid(t *testing.T) {
+	for _, selector := range []string{"", " ", "div >", "a,", "#", ".", "[href", "[href=]", `[href="x]`, "div!", "[=x]", "> a", "a:hover", "li:nth-child(x)", "li:nth-child(2", "li:not(>)", "li:first-child()"} {
+		if _, err := ParseSelector(selector); !errors.Is(err, ErrInvalidSelector) {
+			t.Errorf("ParseSelector(%q): expected ErrInvalidSelector, got %v", selector, err)
+		}
+	}
+}
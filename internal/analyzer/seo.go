@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxSEOTitleLength       = 60
+	maxSEODescriptionLength = 160
+)
+
+func init() {
+	RegisterModule("seo", "Search engine basics: title, meta description, a single h1, canonical URL and indexing", func() Module {
+		return &seoModule{}
+	})
+}
+
+// seoModule checks the page metadata search engines rely on.
+type seoModule struct {
+	description *string
+	canonical   bool
+	noindex     bool
+	h1          int
+}
+
+func (m *seoModule) Visit(n *html.Node) {
+	if n.Type != html.ElementNode {
+		return
+	}
+	switch n.Data {
+	case "meta":
+		name, _ := attr(n, "name")
+		content, _ := attr(n, "content")
+		switch strings.ToLower(name) {
+		case "description":
+			content = strings.TrimSpace(content)
+			m.description = &content
+		case "robots":
+			if strings.Contains(strings.ToLower(content), "noindex") {
+				m.noindex = true
+			}
+		}
+	case "link":
+		if rel, _ := attr(n, "rel"); strings.EqualFold(rel, "canonical") {
+			m.canonical = true
+		}
+	case "h1":
+		m.h1++
+	}
+}
+
+func (m *seoModule) Finalize(result *AnalysisResult) {
+	add := func(rule, severity, message string) {
+		result.Findings = append(result.Findings, Finding{Rule: rule, Severity: severity, Message: message})
+	}
+
+	title := strings.TrimSpace(result.Title)
+	switch {
+	case title == "":
+		add("title-missing", SeverityError, "The page has no title")
+	case len([]rune(title)) > maxSEOTitleLength:
+		add("title-too-long", SeverityInfo, fmt.Sprintf("The title is %d characters long; search results show about %d", len([]rune(title)), maxSEOTitleLength))
+	}
+
+	switch {
+	case m.description == nil || *m.description == "":
+		add("description-missing", SeverityWarning, "The page has no meta description")
+	case len([]rune(*m.description)) > maxSEODescriptionLength:
+		add("description-too-long", SeverityInfo, fmt.Sprintf("The meta description is %d characters long; search results show about %d", len([]rune(*m.description)), maxSEODescriptionLength))
+	}
+
+	switch {
+	case m.h1 == 0:
+		add("h1-missing", SeverityWarning, "The page has no h1 heading")
+	case m.h1 > 1:
+		add("h1-multiple", SeverityWarning, fmt.Sprintf("The page has %d h1 headings", m.h1))
+	}
+
+	if !m.canonical {
+		add("canonical-missing", SeverityInfo, `The page declares no <link rel="canonical">`)
+	}
+	if m.noindex {
+		add("noindex", SeverityWarning, "The page asks search engines not to index it")
+	}
+}
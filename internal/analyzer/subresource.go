@@ -0,0 +1,175 @@
+package analyzer
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// subresource is a URL an element loads or submits to, as written.
+type subresource struct {
+	node      *html.Node
+	attribute string
+	url       string
+	kind      string
+}
+
+var (
+	cssURLPattern    = regexp.MustCompile(`url\(\s*['"]?([^'")\s]+)['"]?\s*\)`)
+	cssImportPattern = regexp.MustCompile(`@import\s+(?:url\(\s*)?['"]?([^'")\s;]+)`)
+)
+
+// subresources lists the URLs n loads or submits to. url() references in
+// style attributes and <style> elements are passive, except for @import.
+func subresources(n *html.Node) []subresource {
+	if n.Type != html.ElementNode {
+		return nil
+	}
+	var refs []subresource
+	add := func(attribute, kind string) {
+		if value, ok := attr(n, attribute); ok && strings.TrimSpace(value) != "" {
+			refs = append(refs, subresource{n, attribute, strings.TrimSpace(value), kind})
+		}
+	}
+	addSrcset := func(attribute string) {
+		value, _ := attr(n, attribute)
+		for _, candidate := range strings.Split(value, ",") {
+			if fields := strings.Fields(candidate); len(fields) > 0 {
+				refs = append(refs, subresource{n, attribute, fields[0], MixedContentPassive})
+			}
+		}
+	}
+
+	switch n.Data {
+	case "script", "iframe", "frame", "embed", "track":
+		add("src", MixedContentActive)
+	case "object":
+		add("data", MixedContentActive)
+	case "link":
+		if kind := linkKind(n); kind != "" {
+			add("href", kind)
+		}
+	case "img":
+		add("src", MixedContentPassive)
+		addSrcset("srcset")
+	case "source":
+		add("src", MixedContentPassive)
+		addSrcset("srcset")
+	case "audio":
+		add("src", MixedContentPassive)
+	case "video":
+		add("src", MixedContentPassive)
+		add("poster", MixedContentPassive)
+	case "input":
+		if typ, _ := attr(n, "type"); strings.EqualFold(typ, "image") {
+			add("src", MixedContentPassive)
+		}
+		add("formaction", MixedContentForm)
+	case "button":
+		add("formaction", MixedContentForm)
+	case "form":
+		add("action", MixedContentForm)
+	case "style":
+		refs = append(refs, cssSubresources(n, "", textContent(n))...)
+	}
+	if style, ok := attr(n, "style"); ok {
+		refs = append(refs, cssSubresources(n, "style", style)...)
+	}
+	return refs
+}
+
+// linkKind returns the kind of resource a <link> loads, or "" if its
+// relations load nothing.
+func linkKind(n *html.Node) string {
+	rel, _ := attr(n, "rel")
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet", "modulepreload", "prefetch", "manifest":
+			return MixedContentActive
+		case "preload":
+			switch as, _ := attr(n, "as"); strings.ToLower(as) {
+			case "image", "audio", "video":
+				return MixedContentPassive
+			}
+			return MixedContentActive
+		case "icon", "apple-touch-icon":
+			return MixedContentPassive
+		}
+	}
+	return ""
+}
+
+// cssSubresources lists the url() and @import references of css.
+func cssSubresources(n *html.Node, attribute, css string) []subresource {
+	var refs []subresource
+	imports := make(map[string]bool)
+	for _, match := range cssImportPattern.FindAllStringSubmatch(css, -1) {
+		imports[match[1]] = true
+		refs = append(refs, subresource{n, attribute, match[1], MixedContentActive})
+	}
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		if !imports[match[1]] {
+			refs = append(refs, subresource{n, attribute, match[1], MixedContentPassive})
+		}
+	}
+	return refs
+}
+
+// subresourceCollector gathers the subresources of a page. They are resolved
+// once the whole document has been seen, since <base href> applies to those
+// before it too.
+type subresourceCollector struct {
+	// page is the URL the document was served from, if known.
+	page *url.URL
+	base string
+	refs []subresource
+}
+
+func (c *subresourceCollector) Visit(n *html.Node) {
+	if n.Type != html.ElementNode || inTemplate(n) {
+		return
+	}
+	if n.Data == "base" && c.base == "" {
+		c.base, _ = attr(n, "href")
+	}
+	c.refs = append(c.refs, subresources(n)...)
+}
+
+// Finalize lists the mixed content of pages served over HTTPS, and the
+// scripts and stylesheets of every page.
+func (c *subresourceCollector) Finalize(result *AnalysisResult) {
+	var base *url.URL
+	if href, err := url.Parse(strings.TrimSpace(c.base)); err == nil && c.base != "" {
+		base = href
+	}
+	switch {
+	case c.page != nil && base != nil:
+		base = c.page.ResolveReference(base)
+	case c.page != nil:
+		base = c.page
+	}
+	resolve := func(u *url.URL) *url.URL {
+		if base == nil {
+			return u
+		}
+		return base.ResolveReference(u)
+	}
+
+	if c.page != nil && c.page.Scheme == "https" {
+		result.MixedContent = mixedContent(c.refs, resolve)
+	}
+	result.ResourceOrigins = resourceOrigins(c.page, c.refs, resolve)
+}
+
+// inTemplate reports whether n is part of a <template>, whose content is not
+// rendered or loaded.
+func inTemplate(n *html.Node) bool {
+	for p := n.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.Data == "template" {
+			return true
+		}
+	}
+	return false
+}
@@ -1,48 +1,232 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"html/template"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
 
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
 	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
 	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
 )
 
-var templates = template.Must(template.ParseGlob("template/*.html"))
+const maxDocumentSize = 10 << 20
 
-func HomePageHandler(w http.ResponseWriter, _ *http.Request) {
-	err := templates.ExecuteTemplate(w, "index.html", nil)
+// templates parses the page templates on first use, so that the command
+// line modes, which never render a page, can run outside the repository root.
+var templates = sync.OnceValue(func() *template.Template {
+	return template.Must(template.New("").Funcs(template.FuncMap{
+		"percent": func(ratio float64) float64 { return 100 * ratio },
+	}).ParseGlob("template/*.html"))
+})
+
+// LoadTemplates parses the page templates, panicking if they are missing or
+// invalid. The server calls it at startup to fail fast.
+func LoadTemplates() {
+	templates()
+}
+
+// errorPage is the data rendered by error.html.
+type errorPage struct {
+	Status    int
+	Message   string
+	RequestID string
+}
+
+func HomePageHandler(w http.ResponseWriter, r *http.Request) {
+	err := templates().ExecuteTemplate(w, "index.html", analyzer.Modules())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
-		logger.WithField("error", err).Error("Failed to execute template")
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
 		return
 	}
 }
 
+// AnalysisHandler analyzes the page at the url form value, or the HTML
+// document uploaded as file or pasted as html
 func AnalysisHandler(w http.ResponseWriter, r *http.Request) {
 	analysisService := service.NewAnalysisService()
-	url := r.FormValue(`url`)
-	page, err := analysisService.AnalyzePage(r.Context(), url)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		logger.WithField("error", err).Error("Failed to analyze page")
+	r.Body = http.MaxBytesReader(w, r.Body, maxDocumentSize)
+	if err := parseForm(r, maxDocumentSize); err != nil {
+		renderError(w, r, requestErrorStatus(err), err.Error())
 		return
 	}
-	err = templates.ExecuteTemplate(w, "results.html", page)
+
+	var page *service.AnalysisServiceResultDTO
+	var err error
+	if document, ok := submittedDocument(r); ok {
+		defer document.Close()
+		page, err = analysisService.AnalyzeDocument(r.Context(), document, documentOptions(r))
+		if err != nil {
+			logger.WithContext(r.Context()).WithField("error", err).Warn("Failed to analyze document")
+			renderError(w, r, requestErrorStatus(err), err.Error())
+			return
+		}
+	} else {
+		url := r.FormValue(`url`)
+		options := service.AnalysisOptions{
+			ForceRefresh: r.FormValue(`refresh`) != "",
+			Modules:      requestedModules(r),
+		}
+		page, err = analysisService.AnalyzePageWithOptions(r.Context(), url, options)
+		if errors.Is(err, analyzer.ErrUnknownModule) {
+			renderError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			logger.WithContext(r.Context()).WithField("error", err).Error("Failed to analyze page")
+			renderError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	err = templates().ExecuteTemplate(w, "results.html", page)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
-		logger.WithField("error", err).Error("Failed to execute template")
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
 		return
 	}
 }
 
 // HealthHandler provides a health check endpoint
-func HealthHandler(w http.ResponseWriter, _ *http.Request) {
+func HealthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	_, err := w.Write([]byte(`{"status":"healthy","service":"web-page-analyzer"}`))
 	if err != nil {
-		logger.WithField("error", err).Error("Failed to write response")
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to write response")
 		return
 	}
 }
+
+// ModulesAPIHandler lists the analyzer modules that can be enabled per request
+func ModulesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, analyzer.Modules())
+}
+
+// RulesAPIHandler shows the loaded rules file, if RULES_PATH is set
+func RulesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	rules, ok := service.ActiveRules()
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, "no rules file configured")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, rules)
+}
+
+// CacheStatsHandler reports the size and hit/eviction counters of the result cache
+func CacheStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(service.SharedResultCacheStats())
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to write response")
+		return
+	}
+}
+
+// errMissingUpload is reported for multipart requests without a file field.
+var errMissingUpload = errors.New("missing file upload")
+
+// parseForm parses a urlencoded or multipart form of up to maxSize bytes.
+func parseForm(r *http.Request, maxSize int64) error {
+	// ParseMultipartForm does not report errors reading a urlencoded body,
+	// so that is parsed first.
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+	if err := r.ParseMultipartForm(maxSize); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return err
+	}
+	return nil
+}
+
+// documentUpload returns the HTML document uploaded as the file field of a
+// multipart request, or else the request body, reading at most
+// maxDocumentSize bytes of either.
+func documentUpload(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDocumentSize)
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return r.Body, nil
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		if requestErrorStatus(err) == http.StatusRequestEntityTooLarge {
+			return nil, err
+		}
+		return nil, errMissingUpload
+	}
+	return file, nil
+}
+
+// requestErrorStatus is the status for an error reading or parsing a request:
+// 413 when the body is larger than allowed and 400 otherwise.
+func requestErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// submittedDocument returns the HTML document uploaded as the file form field
+// or pasted as the html form field, if the request carries one.
+func submittedDocument(r *http.Request) (io.ReadCloser, bool) {
+	if file, _, err := r.FormFile("file"); err == nil {
+		return file, true
+	}
+	if html := r.FormValue("html"); html != "" {
+		return io.NopCloser(strings.NewReader(html)), true
+	}
+	return nil, false
+}
+
+// documentOptions reads the base_url, check_links and module form or query
+// values.
+func documentOptions(r *http.Request) service.DocumentOptions {
+	return service.DocumentOptions{
+		BaseURL:    strings.TrimSpace(r.FormValue("base_url")),
+		CheckLinks: r.FormValue("check_links") != "",
+		Modules:    requestedModules(r),
+	}
+}
+
+// requestedModules reads the analyzer modules to enable from repeated module
+// values, as sent by the form's checkboxes, and comma-separated modules
+// values, as is handier in API URLs.
+func requestedModules(r *http.Request) []string {
+	if r.Form == nil {
+		r.ParseMultipartForm(maxDocumentSize)
+	}
+	var names []string
+	for _, name := range r.Form["module"] {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	for _, list := range r.Form["modules"] {
+		for _, name := range strings.Split(list, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// renderError writes error.html with the request's correlation ID so that
+// users can quote it when reporting a problem.
+func renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	err := templates().ExecuteTemplate(w, "error.html", errorPage{
+		Status:    status,
+		Message:   message,
+		RequestID: RequestIDFromContext(r.Context()),
+	})
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
+	}
+}
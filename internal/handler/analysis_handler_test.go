@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// requestCase is a request a handler is expected to reject with status.
+type requestCase struct {
+	name        string
+	method      string
+	target      string
+	contentType string
+	body        string
+	status      int
+}
+
+// testRejectedRequests serves each case with h and checks its status.
+func testRejectedRequests(t *testing.T, h http.HandlerFunc, testCases []requestCase) {
+	t.Helper()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			rec := httptest.NewRecorder()
+			h(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("Expected status %d, got %d: %s", tc.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+// multipartUpload returns a multipart body carrying content in field, and its
+// content type.
+func multipartUpload(t *testing.T, field, content string) (string, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile(field, "page.html")
+	if err != nil {
+		t.Fatalf("CreateFormFile() returned error: %v", err)
+	}
+	io.WriteString(part, content)
+	writer.Close()
+	return body.String(), writer.FormDataContentType()
+}
+
+// oversizedDocument is an HTML document larger than maxDocumentSize.
+func oversizedDocument() string {
+	return "<p>" + strings.Repeat("x", maxDocumentSize) + "</p>"
+}
+
+func TestAnalysisHandler_RejectsBadRequests(t *testing.T) {
+	t.Chdir("../..")
+	t.Setenv("HISTORY_PATH", "none")
+	upload, uploadType := multipartUpload(t, "file", oversizedDocument())
+
+	testRejectedRequests(t, AnalysisHandler, []requestCase{
+		{"oversized upload", http.MethodPost, "/analyze", uploadType, upload, http.StatusRequestEntityTooLarge},
+		{"oversized pasted document", http.MethodPost, "/analyze", "application/x-www-form-urlencoded", "html=" + oversizedDocument(), http.StatusRequestEntityTooLarge},
+		{"malformed multipart form", http.MethodPost, "/analyze", "multipart/form-data; boundary=x", "not a form", http.StatusBadRequest},
+		{"malformed form", http.MethodPost, "/analyze", "application/x-www-form-urlencoded", "html=%zz", http.StatusBadRequest},
+	})
+}
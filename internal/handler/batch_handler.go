@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+const maxBatchUploadSize = 4 << 20
+
+// BatchPageHandler renders the batch submission form
+func BatchPageHandler(w http.ResponseWriter, r *http.Request) {
+	err := templates().ExecuteTemplate(w, "batch.html", service.ExtractionTemplates())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
+		return
+	}
+}
+
+// BatchSubmitHandler starts a batch from the form's uploaded file or pasted
+// list and redirects to its status page
+func BatchSubmitHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchUploadSize)
+	if err := parseForm(r, maxBatchUploadSize); err != nil {
+		renderError(w, r, batchErrorStatus(err), err.Error())
+		return
+	}
+	var list io.Reader = strings.NewReader(r.FormValue("urls"))
+	if file, _, err := r.FormFile("file"); err == nil {
+		defer file.Close()
+		list = file
+	}
+
+	batch, err := startBatch(r, list, r.FormValue("template"))
+	if err != nil {
+		renderError(w, r, batchErrorStatus(err), err.Error())
+		return
+	}
+	http.Redirect(w, r, "/batch/"+batch.ID, http.StatusSeeOther)
+}
+
+// BatchStatusHandler renders the progress and results of a batch
+func BatchStatusHandler(w http.ResponseWriter, r *http.Request) {
+	batch, err := service.SharedBatchRunner().Get(r.PathValue("id"))
+	if err != nil {
+		renderError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	err = templates().ExecuteTemplate(w, "batch_status.html", batch)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
+		return
+	}
+}
+
+// BatchReportHandler downloads the report of a batch as CSV, or as JSON with
+// ?format=json. Extraction batches download their records as CSV, or as JSON
+// Lines with ?format=jsonl
+func BatchReportHandler(w http.ResponseWriter, r *http.Request) {
+	batch, err := service.SharedBatchRunner().Get(r.PathValue("id"))
+	if err != nil {
+		renderError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "json" {
+		w.Header().Set("Content-Disposition", `attachment; filename="batch-`+batch.ID+`.json"`)
+		writeJSON(w, r, http.StatusOK, batch)
+		return
+	}
+	if batch.Template != "" {
+		w.Header().Set("Content-Disposition", `attachment; filename="batch-`+batch.ID+`.`+extractionExtension(format)+`"`)
+		writeExtractions(w, r, format, batch.Fields, batch.Extractions())
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="batch-`+batch.ID+`.csv"`)
+	if err := batch.WriteCSV(w); err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to write response")
+	}
+}
+
+// CreateBatchAPIHandler starts a batch from a JSON, CSV or newline-separated
+// request body, extracting data with the template query parameter if set
+func CreateBatchAPIHandler(w http.ResponseWriter, r *http.Request) {
+	batch, err := startBatch(r, http.MaxBytesReader(w, r.Body, maxBatchUploadSize), r.URL.Query().Get("template"))
+	if err != nil {
+		writeJSONError(w, r, batchErrorStatus(err), err.Error())
+		return
+	}
+	w.Header().Set("Location", "/api/batches/"+batch.ID)
+	writeJSON(w, r, http.StatusAccepted, batch)
+}
+
+// GetBatchAPIHandler returns the status and results of a batch as JSON
+func GetBatchAPIHandler(w http.ResponseWriter, r *http.Request) {
+	batch, err := service.SharedBatchRunner().Get(r.PathValue("id"))
+	if errors.Is(err, service.ErrBatchNotFound) {
+		writeJSONError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, batch)
+}
+
+// startBatch parses list and starts a batch that outlives the request but
+// keeps its logging fields. A template makes it an extraction batch.
+func startBatch(r *http.Request, list io.Reader, template string) (service.Batch, error) {
+	urls, err := service.ParseURLList(list)
+	if err != nil {
+		return service.Batch{}, err
+	}
+	ctx := context.WithoutCancel(r.Context())
+	if template != "" {
+		return service.SharedBatchRunner().StartExtraction(ctx, urls, template)
+	}
+	return service.SharedBatchRunner().Start(ctx, urls)
+}
+
+// batchErrorStatus returns the HTTP status for an error starting a batch.
+func batchErrorStatus(err error) int {
+	if errors.Is(err, service.ErrTooManyBatches) {
+		return http.StatusTooManyRequests
+	}
+	return requestErrorStatus(err)
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateBatchAPIHandler_RejectsBadRequests(t *testing.T) {
+	oversized := strings.Repeat("https://example.com/\n", maxBatchUploadSize/20)
+
+	testRejectedRequests(t, CreateBatchAPIHandler, []requestCase{
+		{"oversized list", http.MethodPost, "/api/batches", "text/plain", oversized, http.StatusRequestEntityTooLarge},
+		{"invalid JSON list", http.MethodPost, "/api/batches", "application/json", `["https://example.com"`, http.StatusBadRequest},
+	})
+}
+
+func TestBatchSubmitHandler_RejectsBadRequests(t *testing.T) {
+	t.Chdir("../..")
+	oversized := strings.Repeat("https://example.com/\n", maxBatchUploadSize/20)
+	upload, uploadType := multipartUpload(t, "file", oversized)
+
+	testRejectedRequests(t, BatchSubmitHandler, []requestCase{
+		{"oversized upload", http.MethodPost, "/batch", uploadType, upload, http.StatusRequestEntityTooLarge},
+		{"oversized pasted list", http.MethodPost, "/batch", "application/x-www-form-urlencoded", "urls=" + oversized, http.StatusRequestEntityTooLarge},
+		{"invalid JSON list", http.MethodPost, "/batch", "application/x-www-form-urlencoded", "urls=%5B", http.StatusBadRequest},
+	})
+}
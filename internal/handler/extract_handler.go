@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+// ExtractAPIHandler fetches the page in the url query parameter and extracts
+// records from it with the template parameter, as JSON, or as JSON Lines or
+// CSV with format=jsonl or format=csv
+func ExtractAPIHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("url") == "" || query.Get("template") == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "missing url or template")
+		return
+	}
+	extraction, err := service.NewAnalysisService().ExtractPage(r.Context(), query.Get("url"), query.Get("template"))
+	if errors.Is(err, service.ErrUnknownTemplate) {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to extract page")
+		writeJSONError(w, r, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeExtraction(w, r, extraction)
+}
+
+// DocumentExtractAPIHandler extracts records with the template query
+// parameter from the HTML document in the request body, or uploaded as the
+// file form field. base_url resolves URL fields
+func DocumentExtractAPIHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("template") == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "missing template")
+		return
+	}
+	document, err := documentUpload(w, r)
+	if err != nil {
+		writeJSONError(w, r, requestErrorStatus(err), err.Error())
+		return
+	}
+	defer document.Close()
+	extraction, err := service.NewAnalysisService().ExtractDocument(r.Context(), document, query.Get("base_url"), query.Get("template"))
+	if err != nil {
+		writeJSONError(w, r, requestErrorStatus(err), err.Error())
+		return
+	}
+	writeExtraction(w, r, extraction)
+}
+
+// ExtractionTemplatesAPIHandler lists the loaded extraction templates
+func ExtractionTemplatesAPIHandler(w http.ResponseWriter, r *http.Request) {
+	templates := service.ExtractionTemplates()
+	if templates == nil {
+		writeJSON(w, r, http.StatusOK, []any{})
+		return
+	}
+	writeJSON(w, r, http.StatusOK, templates)
+}
+
+func writeExtraction(w http.ResponseWriter, r *http.Request, extraction *service.PageExtraction) {
+	format := r.URL.Query().Get("format")
+	if format == "" || format == "json" {
+		writeJSON(w, r, http.StatusOK, extraction)
+		return
+	}
+	template, err := service.ExtractionTemplate(extraction.Template)
+	if err != nil {
+		writeJSONError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeExtractions(w, r, format, template.FieldNames(), []service.PageExtraction{*extraction})
+}
+
+// writeExtractions writes records as JSON Lines for format=jsonl and as CSV
+// otherwise.
+func writeExtractions(w http.ResponseWriter, r *http.Request, format string, fields []string, extractions []service.PageExtraction) {
+	var err error
+	if format == "jsonl" {
+		w.Header().Set("Content-Type", "application/jsonl")
+		err = service.WriteExtractionJSONL(w, extractions)
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		err = service.WriteExtractionCSV(w, fields, extractions)
+	}
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to write response")
+	}
+}
+
+func extractionExtension(format string) string {
+	if format == "jsonl" {
+		return "jsonl"
+	}
+	return "csv"
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestExtractAPIHandler_RejectsBadRequests(t *testing.T) {
+	testRejectedRequests(t, ExtractAPIHandler, []requestCase{
+		{"missing template", http.MethodGet, "/api/extract?url=https://example.com", "", "", http.StatusBadRequest},
+		{"missing url", http.MethodGet, "/api/extract?template=products", "", "", http.StatusBadRequest},
+	})
+}
+
+func TestDocumentExtractAPIHandler_RejectsBadRequests(t *testing.T) {
+	upload, uploadType := multipartUpload(t, "file", oversizedDocument())
+	wrongField, wrongFieldType := multipartUpload(t, "document", "<p>hi</p>")
+
+	testRejectedRequests(t, DocumentExtractAPIHandler, []requestCase{
+		{"missing template", http.MethodPost, "/api/extract", "text/html", "<p>hi</p>", http.StatusBadRequest},
+		{"oversized upload", http.MethodPost, "/api/extract?template=products", uploadType, upload, http.StatusRequestEntityTooLarge},
+		{"missing upload", http.MethodPost, "/api/extract?template=products", wrongFieldType, wrongField, http.StatusBadRequest},
+		{"unknown template", http.MethodPost, "/api/extract?template=missing", "text/html", "<p>hi</p>", http.StatusBadRequest},
+	})
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+const defaultHistoryLimit = 50
+
+// historyPage is the data rendered by history.html.
+type historyPage struct {
+	URL     string
+	Records []service.HistoryRecord
+}
+
+// HistoryHandler lists past analyses, optionally filtered to one URL
+func HistoryHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := historyFilter(r)
+	if err != nil {
+		renderError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = templates().ExecuteTemplate(w, "history.html", historyPage{
+		URL:     filter.URL,
+		Records: service.NewAnalysisService().History(filter),
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
+		return
+	}
+}
+
+// HistoryRecordHandler renders a stored analysis with the results template
+func HistoryRecordHandler(w http.ResponseWriter, r *http.Request) {
+	record, ok := historyRecord(r)
+	if !ok || record.Result == nil {
+		renderError(w, r, http.StatusNotFound, "analysis not found")
+		return
+	}
+	err := templates().ExecuteTemplate(w, "results.html", record.Result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
+		return
+	}
+}
+
+// HistoryAPIHandler returns past analyses as JSON, without their full results
+func HistoryAPIHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := historyFilter(r)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	records := service.NewAnalysisService().History(filter)
+	if records == nil {
+		records = []service.HistoryRecord{}
+	}
+	writeJSON(w, r, http.StatusOK, records)
+}
+
+// HistoryRecordAPIHandler returns one stored analysis as JSON
+func HistoryRecordAPIHandler(w http.ResponseWriter, r *http.Request) {
+	record, ok := historyRecord(r)
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, "analysis not found")
+		return
+	}
+	writeJSON(w, r, http.StatusOK, record)
+}
+
+// HistoryDiffHandler renders the changes between a stored analysis and the
+// one given by the with query parameter, or a fresh run when it is omitted
+// from a POST
+func HistoryDiffHandler(w http.ResponseWriter, r *http.Request) {
+	diff, status, err := compareHistory(r)
+	if err != nil {
+		renderError(w, r, status, err.Error())
+		return
+	}
+	err = templates().ExecuteTemplate(w, "diff.html", diff)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to execute template")
+		return
+	}
+}
+
+// HistoryDiffAPIHandler returns the changes between two analyses as JSON
+func HistoryDiffAPIHandler(w http.ResponseWriter, r *http.Request) {
+	diff, status, err := compareHistory(r)
+	if err != nil {
+		writeJSONError(w, r, status, err.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, diff)
+}
+
+// compareHistory diffs the analysis in the id path segment against the one in
+// the with query parameter and returns the HTTP status to report on failure.
+// Only a POST may omit with to compare against a fresh run, since that
+// fetches the page again.
+func compareHistory(r *http.Request) (*service.AnalysisDiff, int, error) {
+	baseID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return nil, http.StatusNotFound, service.ErrHistoryNotFound
+	}
+	var targetID int64
+	if with := r.URL.Query().Get("with"); with != "" {
+		targetID, err = strconv.ParseInt(with, 10, 64)
+		if err != nil || targetID <= 0 {
+			return nil, http.StatusBadRequest, errors.New("invalid with: expected an analysis ID")
+		}
+	} else if r.Method != http.MethodPost {
+		return nil, http.StatusBadRequest, errors.New("missing with: expected an analysis ID, or POST to compare with a fresh run")
+	}
+
+	diff, err := service.NewAnalysisService().Compare(r.Context(), baseID, targetID)
+	switch {
+	case errors.Is(err, service.ErrHistoryNotFound):
+		return nil, http.StatusNotFound, err
+	case errors.Is(err, service.ErrDifferentURLs):
+		return nil, http.StatusBadRequest, err
+	case err != nil:
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to compare analyses")
+		return nil, http.StatusInternalServerError, err
+	}
+	return diff, http.StatusOK, nil
+}
+
+// historyFilter reads the url, since, until and limit query parameters.
+// Times are RFC 3339.
+func historyFilter(r *http.Request) (service.HistoryFilter, error) {
+	query := r.URL.Query()
+	filter := service.HistoryFilter{URL: query.Get("url"), Limit: defaultHistoryLimit}
+
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: expected an RFC 3339 time", name)
+		}
+		*dst = t
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return filter, errors.New("invalid limit: expected a non-negative integer")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+func historyRecord(r *http.Request) (service.HistoryRecord, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return service.HistoryRecord{}, false
+	}
+	return service.NewAnalysisService().HistoryRecord(id)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to write response")
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	writeJSON(w, r, status, map[string]string{
+		"error":      message,
+		"request_id": RequestIDFromContext(r.Context()),
+	})
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/metrics"
+)
+
+var (
+	httpRequests = metrics.NewCounterVec(
+		"http_requests_total",
+		"HTTP requests served, by route, method and status code.",
+		"route", "method", "code")
+	httpRequestDuration = metrics.NewHistogramVec(
+		"http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by route.",
+		nil, "route")
+)
+
+// Instrument records request counts and latencies for h, labelled by the
+// route pattern the request matched, and wraps the request in a server span
+// that continues any trace started by the caller.
+func Instrument(h http.HandlerFunc) http.HandlerFunc {
+	instrumented := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := routeOf(r)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(recorder, r)
+
+		duration := time.Since(start)
+		httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Inc()
+		httpRequestDuration.WithLabelValues(route).Observe(duration.Seconds())
+		logger.WithContext(r.Context()).WithFields(logrus.Fields{
+			"route":    route,
+			"status":   recorder.status,
+			"duration": duration.String(),
+		}).Info("Request completed")
+	})
+	traced := otelhttp.NewHandler(instrumented, "", otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return routeOf(r)
+	}))
+	return traced.ServeHTTP
+}
+
+// routeOf returns the route pattern r matched, or "unmatched".
+func routeOf(r *http.Request) string {
+	if r.Pattern == "" {
+		return "unmatched"
+	}
+	return r.Pattern
+}
+
+// RequestIDHeader carries the correlation ID of a request.
+const RequestIDHeader = "X-Request-ID"
+
+const maxRequestIDLength = 128
+
+type requestIDKey struct{}
+
+// RequestID assigns every request a correlation ID, reusing a well-formed
+// X-Request-ID sent by the caller. The ID is echoed in the response header and
+// attached to the request's logger.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = logger.ContextWithFields(ctx, logrus.Fields{
+			"request_id": id,
+			"method":     r.Method,
+			"path":       r.URL.Path,
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the correlation ID assigned by RequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID accepts IDs of printable ASCII up to maxRequestIDLength so
+// that caller-supplied values cannot inject content into logs or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+const maxMonitorBodySize = 64 << 10
+
+// ListMonitorsHandler returns every registered monitor and its alert state
+func ListMonitorsHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, service.SharedMonitorRunner().List())
+}
+
+// CreateMonitorHandler registers a monitor from a JSON body with url,
+// schedule and optionally conditions and webhook_url
+func CreateMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	monitor := service.Monitor{Conditions: service.DefaultAlertConditions()}
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxMonitorBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&monitor); err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, "invalid monitor: "+err.Error())
+		return
+	}
+
+	created, err := service.SharedMonitorRunner().Add(monitor)
+	if errors.Is(err, service.ErrInvalidMonitor) {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Warn("Failed to save monitors")
+	}
+	w.Header().Set("Location", "/api/monitors/"+created.ID)
+	writeJSON(w, r, http.StatusCreated, created)
+}
+
+// GetMonitorHandler returns one monitor
+func GetMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	monitor, ok := service.SharedMonitorRunner().Get(r.PathValue("id"))
+	if !ok {
+		writeJSONError(w, r, http.StatusNotFound, service.ErrMonitorNotFound.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, monitor)
+}
+
+// DeleteMonitorHandler removes a monitor
+func DeleteMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	err := service.SharedMonitorRunner().Remove(r.PathValue("id"))
+	if errors.Is(err, service.ErrMonitorNotFound) {
+		writeJSONError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Warn("Failed to save monitors")
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RunMonitorHandler starts a monitor's next run immediately
+func RunMonitorHandler(w http.ResponseWriter, r *http.Request) {
+	if err := service.SharedMonitorRunner().RunNow(r.PathValue("id")); err != nil {
+		writeJSONError(w, r, http.StatusNotFound, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+// QueryAPIHandler fetches the page in the url query parameter and returns the
+// elements matching each selector parameter, up to limit per selector
+func QueryAPIHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	selectors, limit, ok := queryParams(w, r)
+	if !ok {
+		return
+	}
+	if query.Get("url") == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "missing url")
+		return
+	}
+	result, err := service.NewAnalysisService().QueryPage(r.Context(), query.Get("url"), selectors, limit)
+	if errors.Is(err, analyzer.ErrInvalidSelector) {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to query page")
+		writeJSONError(w, r, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// DocumentQueryAPIHandler runs the selector parameters against the HTML
+// document in the request body, or uploaded as the file form field
+func DocumentQueryAPIHandler(w http.ResponseWriter, r *http.Request) {
+	selectors, limit, ok := queryParams(w, r)
+	if !ok {
+		return
+	}
+	document, err := documentUpload(w, r)
+	if err != nil {
+		writeJSONError(w, r, requestErrorStatus(err), err.Error())
+		return
+	}
+	defer document.Close()
+	result, err := service.NewAnalysisService().QueryDocument(r.Context(), document, selectors, limit)
+	if err != nil {
+		writeJSONError(w, r, requestErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, r, http.StatusOK, result)
+}
+
+// queryParams reads the repeated selector and the limit query parameters,
+// reporting missing or invalid values to the client.
+func queryParams(w http.ResponseWriter, r *http.Request) ([]string, int, bool) {
+	query := r.URL.Query()
+	var selectors []string
+	for _, selector := range query["selector"] {
+		if selector = strings.TrimSpace(selector); selector != "" {
+			selectors = append(selectors, selector)
+		}
+	}
+	if len(selectors) == 0 {
+		writeJSONError(w, r, http.StatusBadRequest, "missing selector")
+		return nil, 0, false
+	}
+	if len(selectors) > service.MaxQuerySelectors {
+		writeJSONError(w, r, http.StatusBadRequest, "at most "+strconv.Itoa(service.MaxQuerySelectors)+" selectors are allowed")
+		return nil, 0, false
+	}
+	limit := service.DefaultQueryLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > service.MaxQueryLimit {
+			writeJSONError(w, r, http.StatusBadRequest, "limit must be between 0 and "+strconv.Itoa(service.MaxQueryLimit))
+			return nil, 0, false
+		}
+		limit = n
+	}
+	return selectors, limit, true
+}
@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+func TestQueryAPIHandler_RejectsBadRequests(t *testing.T) {
+	tooMany := "/api/query?url=https://example.com" + strings.Repeat("&selector=p", service.MaxQuerySelectors+1)
+
+	testRejectedRequests(t, QueryAPIHandler, []requestCase{
+		{"missing selector", http.MethodGet, "/api/query?url=https://example.com", "", "", http.StatusBadRequest},
+		{"too many selectors", http.MethodGet, tooMany, "", "", http.StatusBadRequest},
+		{"limit out of range", http.MethodGet, "/api/query?url=https://example.com&selector=p&limit=1001", "", "", http.StatusBadRequest},
+		{"missing url", http.MethodGet, "/api/query?selector=p", "", "", http.StatusBadRequest},
+		{"invalid selector", http.MethodGet, "/api/query?url=https://example.com&selector=p[", "", "", http.StatusBadRequest},
+	})
+}
+
+func TestDocumentQueryAPIHandler_RejectsBadRequests(t *testing.T) {
+	upload, uploadType := multipartUpload(t, "file", oversizedDocument())
+	wrongField, wrongFieldType := multipartUpload(t, "document", "<p>hi</p>")
+
+	testRejectedRequests(t, DocumentQueryAPIHandler, []requestCase{
+		{"oversized body", http.MethodPost, "/api/query?selector=p", "text/html", oversizedDocument(), http.StatusRequestEntityTooLarge},
+		{"oversized upload", http.MethodPost, "/api/query?selector=p", uploadType, upload, http.StatusRequestEntityTooLarge},
+		{"missing upload", http.MethodPost, "/api/query?selector=p", wrongFieldType, wrongField, http.StatusBadRequest},
+		{"invalid selector", http.MethodPost, "/api/query?selector=p[", "text/html", "<p>hi</p>", http.StatusBadRequest},
+	})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/service"
+)
+
+// ReportAPIHandler analyzes the page in the url query parameter, or serves it
+// from the result cache, and writes it in the report format given by format
+// (JSON by default). Set refresh to bypass the cache and modules to enable
+// analyzer modules.
+func ReportAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writer, ok := reportWriter(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	if query.Get("url") == "" {
+		writeJSONError(w, r, http.StatusBadRequest, "missing url")
+		return
+	}
+	options := service.AnalysisOptions{
+		ForceRefresh: query.Get("refresh") != "",
+		Modules:      requestedModules(r),
+	}
+	result, err := service.NewAnalysisService().AnalyzePageWithOptions(r.Context(), query.Get("url"), options)
+	if errors.Is(err, analyzer.ErrUnknownModule) {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to analyze page")
+		writeJSONError(w, r, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeReport(w, r, writer, result)
+}
+
+// DocumentReportAPIHandler analyzes the HTML document in the request body, or
+// uploaded as the file form field, and writes it in the report format given by
+// format. base_url, check_links and modules are read as for the analysis form.
+func DocumentReportAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writer, ok := reportWriter(w, r)
+	if !ok {
+		return
+	}
+	document, err := documentUpload(w, r)
+	if err != nil {
+		writeJSONError(w, r, requestErrorStatus(err), err.Error())
+		return
+	}
+	defer document.Close()
+	if r.MultipartForm == nil {
+		// The body is the document, whatever its declared type: read the
+		// options from the query only so that it isn't parsed as a form.
+		r.Form = r.URL.Query()
+	}
+	result, err := service.NewAnalysisService().AnalyzeDocument(r.Context(), document, documentOptions(r))
+	if err != nil {
+		writeJSONError(w, r, requestErrorStatus(err), err.Error())
+		return
+	}
+	writeReport(w, r, writer, result)
+}
+
+// HistoryReportAPIHandler writes a stored analysis in the report format given
+// by format
+func HistoryReportAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writer, ok := reportWriter(w, r)
+	if !ok {
+		return
+	}
+	record, found := historyRecord(r)
+	if !found || record.Result == nil {
+		writeJSONError(w, r, http.StatusNotFound, "analysis not found")
+		return
+	}
+	// Copy the result so that the stored analysis is left untouched.
+	result := *record.Result
+	if result.URL == "" {
+		result.URL = record.URL
+	}
+	writeReport(w, r, writer, &result)
+}
+
+// reportWriter looks up the writer for the format query parameter and reports
+// unknown formats to the client.
+func reportWriter(w http.ResponseWriter, r *http.Request) (service.ReportWriter, bool) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	writer, err := service.ReportWriterFor(format)
+	if err != nil {
+		writeJSONError(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return writer, true
+}
+
+func writeReport(w http.ResponseWriter, r *http.Request, writer service.ReportWriter, result *service.AnalysisServiceResultDTO) {
+	w.Header().Set("Content-Type", writer.ContentType())
+	if r.URL.Query().Get("download") != "" {
+		w.Header().Set("Content-Disposition", `attachment; filename="analysis-report.`+writer.Extension()+`"`)
+	}
+	if err := writer.Write(w, result); err != nil {
+		logger.WithContext(r.Context()).WithField("error", err).Error("Failed to write response")
+	}
+}
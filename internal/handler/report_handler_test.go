@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReportAPIHandler_RejectsBadRequests(t *testing.T) {
+	testRejectedRequests(t, ReportAPIHandler, []requestCase{
+		{"unknown format", http.MethodGet, "/api/report?url=https://example.com&format=pdf", "", "", http.StatusBadRequest},
+		{"missing url", http.MethodGet, "/api/report", "", "", http.StatusBadRequest},
+	})
+}
+
+func TestDocumentReportAPIHandler_RejectsBadRequests(t *testing.T) {
+	t.Setenv("HISTORY_PATH", "none")
+	upload, uploadType := multipartUpload(t, "file", oversizedDocument())
+	wrongField, wrongFieldType := multipartUpload(t, "document", "<p>hi</p>")
+
+	testRejectedRequests(t, DocumentReportAPIHandler, []requestCase{
+		{"unknown format", http.MethodPost, "/api/report?format=pdf", "text/html", "<p>hi</p>", http.StatusBadRequest},
+		{"oversized body", http.MethodPost, "/api/report", "text/html", oversizedDocument(), http.StatusRequestEntityTooLarge},
+		{"oversized upload", http.MethodPost, "/api/report", uploadType, upload, http.StatusRequestEntityTooLarge},
+		{"missing upload", http.MethodPost, "/api/report", wrongFieldType, wrongField, http.StatusBadRequest},
+	})
+}
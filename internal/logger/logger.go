@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"context"
 	"os"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var Logger *logrus.Logger
@@ -59,3 +61,36 @@ func WithField(key string, value interface{}) *logrus.Entry {
 func Info(args ...interface{}) {
 	GetLogger().Info(args...)
 }
+
+type fieldsKey struct{}
+
+// ContextWithFields returns a copy of ctx whose logger carries fields in
+// addition to any already attached to ctx
+func ContextWithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := make(logrus.Fields, len(fields))
+	if existing, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		for k, v := range existing {
+			merged[k] = v
+		}
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithContext creates a new entry carrying the fields attached to ctx with
+// ContextWithFields and the trace and span IDs of the span in ctx
+func WithContext(ctx context.Context) *logrus.Entry {
+	entry := GetLogger().WithContext(ctx)
+	if fields, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		entry = entry.WithFields(logrus.Fields{
+			"trace_id": sc.TraceID().String(),
+			"span_id":  sc.SpanID().String(),
+		})
+	}
+	return entry
+}
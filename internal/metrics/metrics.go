@@ -0,0 +1,102 @@
+// Package metrics holds the Prometheus registry the service's metrics are
+// registered with, together with small helpers shared by the instrumentation:
+// constructors that register with the registry, a handler that serves it and
+// label helpers that keep series counts bounded.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultBuckets are the histogram buckets, in seconds, used for latencies.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Default is the registry the package-level constructors register with. It
+// includes the Go runtime and process metrics.
+var Default = func() *prometheus.Registry {
+	r := prometheus.NewRegistry()
+	r.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return r
+}()
+
+// Handler serves the default registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Default, promhttp.HandlerOpts{Registry: Default})
+}
+
+// NewCounterVec creates a counter partitioned by labels and registers it with
+// the default registry.
+func NewCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	Default.MustRegister(c)
+	return c
+}
+
+// NewHistogramVec creates a histogram partitioned by labels and registers it
+// with the default registry. Nil buckets selects DefaultBuckets.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	Default.MustRegister(h)
+	return h
+}
+
+// NewGaugeFunc registers a gauge with the default registry whose value is
+// computed by fn on every scrape.
+func NewGaugeFunc(name, help string, fn func() float64) {
+	Default.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, fn))
+}
+
+// NewCounterFunc registers a counter with the default registry whose value
+// is computed by fn on every scrape.
+func NewCounterFunc(name, help string, fn func() float64) {
+	Default.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, fn))
+}
+
+// OtherLabel replaces label values beyond a LabelLimiter's limit.
+const OtherLabel = "other"
+
+// LabelLimiter bounds the number of distinct values a label takes, so that a
+// label fed from untrusted input such as a host name cannot create an
+// unbounded number of series. The first Max values seen keep their own
+// series; later values are reported as OtherLabel.
+type LabelLimiter struct {
+	max  int
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// NewLabelLimiter creates a limiter admitting up to max distinct values.
+func NewLabelLimiter(max int) *LabelLimiter {
+	return &LabelLimiter{max: max, seen: make(map[string]bool)}
+}
+
+// Value returns value if it has its own series, or OtherLabel.
+func (l *LabelLimiter) Value(value string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.seen[value] {
+		return value
+	}
+	if len(l.seen) >= l.max {
+		return OtherLabel
+	}
+	l.seen[value] = true
+	return value
+}
+
+// StatusClass buckets an HTTP status code as "2xx", "4xx" and so on.
+func StatusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandler_ServesRegisteredMetrics(t *testing.T) {
+	requests := NewCounterVec("test_requests_total", "Test requests.", "route", "code")
+	requests.WithLabelValues("/", "200").Inc()
+	requests.WithLabelValues("/", "200").Inc()
+	latency := NewHistogramVec("test_duration_seconds", "Test latency.", []float64{0.1, 1}, "phase")
+	latency.WithLabelValues("fetch").Observe(0.5)
+	NewGaugeFunc("test_queue_depth", "Queue depth.", func() float64 { return 7 })
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text/plain content type, got %q", ct)
+	}
+	for _, line := range []string{
+		`test_requests_total{code="200",route="/"} 2`,
+		`test_duration_seconds_bucket{phase="fetch",le="1"} 1`,
+		`test_queue_depth 7`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(rec.Body.String(), "\n"+line) {
+			t.Errorf("Expected exposition to contain %q, got:\n%s", line, rec.Body.String())
+		}
+	}
+}
+
+func TestLabelLimiter(t *testing.T) {
+	limiter := NewLabelLimiter(3)
+	for i := 0; i < 3; i++ {
+		host := fmt.Sprintf("host%d.example.com", i)
+		if got := limiter.Value(host); got != host {
+			t.Errorf("Expected %s to keep its own label, got %q", host, got)
+		}
+	}
+	if got := limiter.Value("host3.example.com"); got != OtherLabel {
+		t.Errorf("Expected a value beyond the limit to be reported as %q, got %q", OtherLabel, got)
+	}
+	if got := limiter.Value("host1.example.com"); got != "host1.example.com" {
+		t.Errorf("Expected an admitted value to keep its label, got %q", got)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	testCases := map[int]string{200: "2xx", 301: "3xx", 404: "4xx", 503: "5xx", 0: "unknown"}
+	for code, expected := range testCases {
+		if got := StatusClass(code); got != expected {
+			t.Errorf("StatusClass(%d) = %q, expected %q", code, got, expected)
+		}
+	}
+}
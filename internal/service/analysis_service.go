@@ -2,32 +2,66 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
-	"sync"
 	"time"
 
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
 	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
 )
 
 type AnalysisService struct {
 	httpClient interface {
 		Do(req *http.Request) (*http.Response, error)
 	}
+	scheduler   *LinkScheduler
+	linkCache   *LinkCache
+	fetchCache  *FetchCache
+	resultCache *ResultCache
+	breaker     *CircuitBreaker
+	history     *HistoryStore
+	retry       RetryPolicy
+	// tlsExpiryWindow is how close to expiry certificates are reported.
+	tlsExpiryWindow time.Duration
 }
 
 func NewAnalysisService() *AnalysisService {
 	return &AnalysisService{
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: instrumentedTransport{next: otelhttp.NewTransport(http.DefaultTransport)},
 		},
+		scheduler:   sharedLinkScheduler(),
+		linkCache:   sharedLinkCache(),
+		fetchCache:  sharedFetchCache(),
+		resultCache: sharedResultCache(),
+		breaker:     sharedCircuitBreaker(),
+		history:     sharedHistory(),
+		retry:       RetryPolicyFromEnv(),
+
+		tlsExpiryWindow: TLSExpiryWindowFromEnv(),
 	}
 }
 
 type AnalysisServiceResultDTO struct {
+	// URL is the analyzed page, or the declared base URL of a document.
+	URL string
+	// Document is set when the HTML was submitted directly rather than
+	// fetched from URL.
+	Document bool `json:",omitempty"`
 	analyzer.AnalysisResult
 	InternalLinksCount             int
 	ExternalLinksCount             int
@@ -37,112 +71,548 @@ type AnalysisServiceResultDTO struct {
 	ExternalLinks                  []string
 	InaccessibleInternalLinks      []string
 	InaccessibleExternalLinks      []string
+	HostUnavailableLinks           []string
+	// BrokenAnchorLinks are links, as written, whose fragment names no
+	// id or <a name> on the page they point to.
+	BrokenAnchorLinks      []string `json:",omitempty"`
+	BrokenAnchorLinksCount int
+	LinkResults            []LinkCheckResult
+	// Integrity verifies the integrity attributes of the page's scripts and
+	// stylesheets. It is set when links are checked.
+	Integrity []IntegrityCheck `json:",omitempty"`
+	// TLS describes the connection a page was fetched over, and LinkHostsTLS
+	// those of the hosts of its HTTPS links.
+	TLS          *TLSInfo  `json:",omitempty"`
+	LinkHostsTLS []TLSInfo `json:",omitempty"`
+	// Security grades the security headers of a fetched page.
+	Security   *SecurityReport `json:",omitempty"`
+	AnalyzedAt time.Time
+	// FromCache and CacheAge describe results served from the result cache.
+	FromCache bool
+	CacheAge  time.Duration
+}
+
+// AnalysisOptions tunes a single analysis. Every field except ForceRefresh
+// and Batch is part of the result cache key.
+type AnalysisOptions struct {
+	// ForceRefresh bypasses the result cache and re-analyzes the page.
+	ForceRefresh bool `json:"-"`
+	// Batch marks analyses run as part of a batch, which are kept in the
+	// history under a cap of their own.
+	Batch bool `json:"-"`
+	// Modules names the analyzer modules to run in addition to the core
+	// analysis. See analyzer.Modules.
+	Modules []string `json:"modules,omitempty"`
+	// RulesVersion identifies the rules file content when the rules module is
+	// enabled. It is set by the service.
+	RulesVersion string `json:"rules_version,omitempty"`
+}
+
+// DocumentOptions tunes the analysis of an HTML document submitted directly.
+type DocumentOptions struct {
+	// BaseURL is the URL the document is, or will be, served from. Links are
+	// classified against it and relative links resolved with it. When empty,
+	// only relative links count as internal.
+	BaseURL string
+	// CheckLinks checks the document's links as for a fetched page. Relative
+	// links are only checked when BaseURL is set.
+	CheckLinks bool
+	// Modules names the analyzer modules to run, as for AnalysisOptions.
+	Modules []string
+}
+
+// LinkCheckResult is the outcome of checking a single link.
+type LinkCheckResult struct {
+	URL        string
+	Accessible bool
+	StatusCode int
+	FinalURL   string
+	CheckedAt  time.Time
+	// Cache reports whether the result came from the link cache: "hit",
+	// "revalidated" or "miss". It is empty when no cache is configured.
+	Cache string
+	Error string
+	// Attempts is the number of requests made, including retries.
+	Attempts int
+	// HostUnavailable is set when the check was skipped because the host's
+	// circuit breaker is open.
+	HostUnavailable bool
+	// TLSFailure classifies links that failed because of TLS, such as an
+	// expired certificate. See the TLS failure classes.
+	TLSFailure string `json:",omitempty"`
+
+	// tls describes the connection to the link's host, for LinkHostsTLS.
+	tls *TLSInfo
+	// body is the response of a GET made by fetchAll, when it succeeded.
+	body *fetchedBody
 }
 
+const errHostUnavailable = "host unavailable"
+
 func (s *AnalysisService) AnalyzePage(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error) {
+	return s.AnalyzePageWithOptions(ctx, pageURL, AnalysisOptions{})
+}
+
+// AnalyzePageWithOptions analyzes pageURL, serving a cached result when one is
+// available and options.ForceRefresh is not set.
+func (s *AnalysisService) AnalyzePageWithOptions(ctx context.Context, pageURL string, options AnalysisOptions) (*AnalysisServiceResultDTO, error) {
+	if err := analyzer.ValidateModules(options.Modules); err != nil {
+		return nil, err
+	}
+	options.Modules = normalizeModules(options.Modules)
+	options.RulesVersion = ""
+	if slices.Contains(options.Modules, analyzer.RulesModule) {
+		options.RulesVersion = rulesVersion()
+	}
+
+	jobID := newID()
+	ctx = logger.ContextWithFields(ctx, logrus.Fields{"url": pageURL, "job_id": jobID})
+	ctx, span := tracing.Start(ctx, "AnalyzePage", trace.WithAttributes(
+		attribute.String("url.full", pageURL),
+		attribute.String("analysis.job_id", jobID),
+		attribute.Bool("analysis.force_refresh", options.ForceRefresh),
+		attribute.String("analysis.modules", strings.Join(options.Modules, ",")),
+	))
+	defer span.End()
+
+	if s.resultCache != nil && !options.ForceRefresh {
+		if cached, cachedAt, ok := s.resultCache.Get(pageURL, options); ok {
+			cached.FromCache = true
+			cached.CacheAge = time.Since(cachedAt).Truncate(time.Second)
+			span.SetAttributes(attribute.Bool("analysis.cache_hit", true))
+			logger.WithContext(ctx).WithField("url", pageURL).Debug("Serving analysis from result cache")
+			return cached, nil
+		}
+	}
+
+	start := time.Now()
+	dto, err := s.analyzePage(ctx, pageURL, options.Modules)
+	duration := time.Since(start)
+	if err != nil {
+		tracing.RecordError(span, err)
+		analysisDuration.WithLabelValues("error").Observe(duration.Seconds())
+		return nil, err
+	}
+	analysisDuration.WithLabelValues("success").Observe(duration.Seconds())
+	span.SetAttributes(attribute.Int("analysis.links", len(dto.Links)))
+	logger.WithContext(ctx).WithFields(logrus.Fields{
+		"duration": duration.String(),
+		"links":    len(dto.Links),
+	}).Info("Analysis completed")
+
+	if s.resultCache != nil {
+		if err := s.resultCache.Put(pageURL, options, dto); err != nil {
+			logger.WithContext(ctx).WithField("error", err).Warn("Failed to persist analysis result")
+		}
+	}
+	if s.history != nil {
+		if _, err := s.history.Add(pageURL, options, dto); err != nil {
+			logger.WithContext(ctx).WithField("error", err).Warn("Failed to record analysis history")
+		}
+	}
+	return dto, nil
+}
+
+// AnalyzeDocument analyzes HTML read from body instead of fetching a page.
+// The result is neither cached nor recorded in the history, since it need
+// not match what is served at options.BaseURL.
+func (s *AnalysisService) AnalyzeDocument(ctx context.Context, body io.Reader, options DocumentOptions) (*AnalysisServiceResultDTO, error) {
+	if options.BaseURL != "" {
+		base, err := url.Parse(options.BaseURL)
+		if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+			return nil, fmt.Errorf("invalid base URL %q: expected an absolute http or https URL", options.BaseURL)
+		}
+	}
+	if err := analyzer.ValidateModules(options.Modules); err != nil {
+		return nil, err
+	}
+
+	jobID := newID()
+	ctx = logger.ContextWithFields(ctx, logrus.Fields{"base_url": options.BaseURL, "job_id": jobID})
+	ctx, span := tracing.Start(ctx, "AnalyzeDocument", trace.WithAttributes(
+		attribute.String("analysis.base_url", options.BaseURL),
+		attribute.String("analysis.job_id", jobID),
+		attribute.Bool("analysis.check_links", options.CheckLinks),
+	))
+	defer span.End()
+
+	start := time.Now()
+	result, err := analyzer.AnalyzeWithOptions(ctx, body, analyzer.Options{Modules: options.Modules, URL: options.BaseURL})
+	observePhase(ctx, phaseParse, start)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	dto := s.buildResult(ctx, options.BaseURL, result, options.CheckLinks, true)
+	dto.Document = true
+	logger.WithContext(ctx).WithFields(logrus.Fields{
+		"duration": time.Since(start).String(),
+		"links":    len(dto.Links),
+	}).Info("Document analysis completed")
+	return dto, nil
+}
+
+// History lists past analyses matching filter, newest first. Results are
+// omitted; use HistoryRecord to load one in full.
+func (s *AnalysisService) History(filter HistoryFilter) []HistoryRecord {
+	if s.history == nil {
+		return nil
+	}
+	return s.history.List(filter)
+}
+
+// HistoryRecord returns a past analysis, including its result.
+func (s *AnalysisService) HistoryRecord(id int64) (HistoryRecord, bool) {
+	if s.history == nil {
+		return HistoryRecord{}, false
+	}
+	return s.history.Get(id)
+}
+
+// normalizeModules sorts and dedupes module names so that the same selection
+// always yields the same result cache key.
+func normalizeModules(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	names = slices.Clone(names)
+	slices.Sort(names)
+	return slices.Compact(names)
+}
+
+// newID returns a short random identifier, used for analysis jobs, monitors
+// and webhook deliveries.
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func (s *AnalysisService) analyzePage(ctx context.Context, pageURL string, modules []string) (*AnalysisServiceResultDTO, error) {
+	response, err := s.fetchPage(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	// Redirects may have moved the page to another scheme.
+	finalURL := pageURL
+	if response.Request != nil && response.Request.URL != nil {
+		finalURL = response.Request.URL.String()
+	}
+
+	parseStart := time.Now()
+	result, err := analyzer.AnalyzeWithOptions(ctx, response.Body, analyzer.Options{
+		Modules:         modules,
+		ContentLanguage: response.Header.Get("Content-Language"),
+		URL:             finalURL,
+	})
+	observePhase(ctx, phaseParse, parseStart)
+	if err != nil {
+		logger.WithContext(ctx).WithField("error", err).Error("Failed to analyze page")
+		return nil, err
+	}
+
+	dto := s.buildResult(ctx, pageURL, result, true, false)
+	if dto.TLS = tlsInfoFromState(hostOf(finalURL), response.TLS); dto.TLS != nil {
+		dto.TLS.assess(time.Now(), s.tlsExpiryWindow)
+	}
+	dto.Security = AuditSecurityHeaders(response.Header, finalURL)
+	return dto, nil
+}
+
+// fetchPage requests pageURL and returns the response if it is successful.
+// The caller closes its body.
+func (s *AnalysisService) fetchPage(ctx context.Context, pageURL string) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pageURL, nil)
 	if err != nil {
-		logger.WithField("error", err).Error("Failed to create request")
+		logger.WithContext(ctx).WithField("error", err).Error("Failed to create request")
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
+	fetchStart := time.Now()
 	response, err := s.httpClient.Do(req)
+	observePhase(ctx, phaseFetch, fetchStart)
 	if err != nil {
-		logger.WithField("error", err).Error("Failed to execute request")
+		logger.WithContext(ctx).WithField("error", err).Error("Failed to execute request")
+		if failure := classifyTLSError(err); failure != "" {
+			return nil, fmt.Errorf("TLS %s: %w", failure, err)
+		}
 		return nil, err
 	}
-	defer response.Body.Close()
 
 	// Check for non-successful status codes after getting the response.
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		response.Body.Close()
 		return nil, fmt.Errorf("request failed with status code: %d", response.StatusCode)
 	}
-	result, err := analyzer.Analyze(response.Body)
-	if err != nil {
-		logger.WithField("error", err).Error("Failed to analyze page")
-		return nil, err
-	}
+	return response, nil
+}
 
-	URL, _ := url.Parse(pageURL)
-	baseScheme := URL.Scheme
-	baseHost := URL.Host
-	baseURL := baseScheme + "://" + baseHost
+// buildResult classifies the links of result against pageURL, checks them and
+// the integrity of its scripts and stylesheets if checkLinks is set and
+// assembles the DTO. Links starting with "/" count as internal; documents
+// also count every other relative link, such as "about.html" or "#intro", and
+// resolve it against pageURL to check it. Without a pageURL, internal links
+// cannot be checked.
+func (s *AnalysisService) buildResult(ctx context.Context, pageURL string, result *analyzer.AnalysisResult, checkLinks, document bool) *AnalysisServiceResultDTO {
+	var baseURL string
+	var page *url.URL
+	if pageURL != "" {
+		page, _ = url.Parse(pageURL)
+		baseURL = page.Scheme + "://" + page.Host
+	}
 
 	var internalLinks, externalLinks, internalLinksFormatted []string
 	for _, link := range result.Links {
-		if strings.HasPrefix(link, "/") {
+		if ref, err := url.Parse(link); document && err == nil && ref.Scheme == "" {
+			if ref.Host != "" && (page == nil || ref.Host != page.Host) {
+				// A protocol-relative link to another host.
+				externalLinks = append(externalLinks, link)
+				continue
+			}
+			internalLinks = append(internalLinks, link)
+			// Fragment-only links point into the document itself and are
+			// covered by the anchor checks.
+			if page != nil && !strings.HasPrefix(link, "#") {
+				internalLinksFormatted = append(internalLinksFormatted, page.ResolveReference(ref).String())
+			}
+		} else if strings.HasPrefix(link, "/") {
 			internalLinks = append(internalLinks, link)
-			internalLinksFormatted = append(internalLinksFormatted, baseURL+link)
-		} else if strings.Contains(link, baseURL) {
+			if baseURL != "" {
+				internalLinksFormatted = append(internalLinksFormatted, baseURL+link)
+			}
+		} else if baseURL != "" && strings.Contains(link, baseURL) {
 			internalLinks = append(internalLinks, link)
 		} else {
 			externalLinks = append(externalLinks, link)
 		}
 	}
-	inaccessibleExternalLinksCount := s.countInaccessibleLinks(ctx, externalLinks)
-	inaccessibleInternalLinksCount := s.countInaccessibleLinks(ctx, internalLinksFormatted)
 
-	dto := &AnalysisServiceResultDTO{
-		AnalysisResult:                 *result,
-		InternalLinksCount:             len(internalLinks),
-		ExternalLinksCount:             len(externalLinks),
-		InaccessibleExternalLinksCount: inaccessibleExternalLinksCount,
-		InaccessibleInternalLinksCount: inaccessibleInternalLinksCount,
-		InternalLinks:                  internalLinks,
-		ExternalLinks:                  externalLinks,
+	var internalResults, externalResults []LinkCheckResult
+	if checkLinks {
+		linkChecksStart := time.Now()
+		linkCtx, linkSpan := tracing.Start(ctx, "link_checks")
+		externalResults = s.checkLinks(linkCtx, externalLinks)
+		internalResults = s.checkLinks(linkCtx, internalLinksFormatted)
+		linkSpan.End()
+		observePhase(ctx, phaseLinkChecks, linkChecksStart)
 	}
 
-	return dto, nil
+	anchorChecksStart := time.Now()
+	brokenAnchors := s.checkAnchors(ctx, pageURL, result, checkLinks)
+	observePhase(ctx, phaseAnchorChecks, anchorChecksStart)
+
+	var integrity []IntegrityCheck
+	if checkLinks {
+		integrityChecksStart := time.Now()
+		integrity = s.checkIntegrity(ctx, result)
+		observePhase(ctx, phaseIntegrityChecks, integrityChecksStart)
+	}
+
+	linkResults := append(internalResults, externalResults...)
+	dto := &AnalysisServiceResultDTO{
+		URL:                       pageURL,
+		AnalysisResult:            *result,
+		InternalLinksCount:        len(internalLinks),
+		ExternalLinksCount:        len(externalLinks),
+		InternalLinks:             internalLinks,
+		ExternalLinks:             externalLinks,
+		InaccessibleInternalLinks: inaccessibleLinks(internalResults),
+		InaccessibleExternalLinks: inaccessibleLinks(externalResults),
+		HostUnavailableLinks:      hostUnavailableLinks(linkResults),
+		BrokenAnchorLinks:         brokenAnchors,
+		BrokenAnchorLinksCount:    len(brokenAnchors),
+		LinkResults:               linkResults,
+		LinkHostsTLS:              tlsHosts(linkResults, time.Now(), s.tlsExpiryWindow),
+		Integrity:                 integrity,
+		AnalyzedAt:                time.Now(),
+	}
+	dto.InaccessibleInternalLinksCount = len(dto.InaccessibleInternalLinks)
+	dto.InaccessibleExternalLinksCount = len(dto.InaccessibleExternalLinks)
+	return dto
 }
 
 func (s *AnalysisService) countInaccessibleLinks(ctx context.Context, links []string) int {
-	jobs := make(chan string, len(links))
-	results := make(chan bool, len(links))
-	var wg sync.WaitGroup
-	inaccessibleCount := 0
+	return len(inaccessibleLinks(s.checkLinks(ctx, links)))
+}
 
-	// Start workers
-	for w := 0; w < 10; w++ { // Cap at 10 concurrent checks
-		wg.Add(1)
-		go s.linkCheckerWorker(ctx, &wg, jobs, results)
+func (s *AnalysisService) checkLinks(ctx context.Context, links []string) []LinkCheckResult {
+	scheduler := s.scheduler
+	if scheduler == nil {
+		scheduler = sharedLinkScheduler()
 	}
-
-	// Send jobs
-	for _, link := range links {
-		jobs <- link
+	results := scheduler.CheckAll(ctx, links, s.checkLink)
+	for _, result := range results {
+		if result.Cache != "" {
+			linkCacheLookups.WithLabelValues(result.Cache).Inc()
+		}
 	}
-	close(jobs)
+	return results
+}
 
-	// Wait for all workers to finish
-	wg.Wait()
-	close(results)
+// inaccessibleLinks lists links that were checked and found broken. Links
+// skipped because their host is unavailable are reported separately.
+func inaccessibleLinks(results []LinkCheckResult) []string {
+	var links []string
+	for _, result := range results {
+		if !result.Accessible && !result.HostUnavailable {
+			links = append(links, result.URL)
+		}
+	}
+	return links
+}
 
-	// Collect results
-	for isAccessible := range results {
-		if !isAccessible {
-			inaccessibleCount++
+func hostUnavailableLinks(results []LinkCheckResult) []string {
+	var links []string
+	for _, result := range results {
+		if result.HostUnavailable {
+			links = append(links, result.URL)
 		}
 	}
+	return links
+}
 
-	return inaccessibleCount
+// checkLink checks a single link inside a "link.check" span.
+func (s *AnalysisService) checkLink(ctx context.Context, link string) LinkCheckResult {
+	ctx, span := tracing.Start(ctx, "link.check", trace.WithAttributes(attribute.String("url.full", link)))
+	defer span.End()
+
+	result := s.doCheckLink(ctx, link)
+	span.SetAttributes(attribute.Bool("link.accessible", result.Accessible))
+	span.SetAttributes(attribute.Int("link.attempts", result.Attempts))
+	if result.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+	}
+	if result.Cache != "" {
+		span.SetAttributes(attribute.String("link.cache", result.Cache))
+	}
+	if result.Error != "" {
+		span.SetStatus(codes.Error, result.Error)
+	}
+	return result
 }
 
-func (s *AnalysisService) linkCheckerWorker(ctx context.Context, wg *sync.WaitGroup, jobs <-chan string, results chan<- bool) {
-	defer wg.Done()
-	for link := range jobs {
+// doCheckLink checks a single link, serving fresh results from the link cache
+// and revalidating stale ones with their ETag or Last-Modified validators.
+func (s *AnalysisService) doCheckLink(ctx context.Context, link string) LinkCheckResult {
+	var cached LinkCacheEntry
+	var found bool
+	if s.linkCache != nil {
+		var fresh bool
+		cached, found, fresh = s.linkCache.Get(link)
+		if fresh {
+			return cachedLinkResult(link, cached, CacheHit)
+		}
+	}
+
+	result := LinkCheckResult{URL: link, FinalURL: link, CheckedAt: time.Now()}
+	if s.linkCache != nil {
+		result.Cache = CacheMiss
+	}
+
+	host := hostOf(link)
+	if s.breaker != nil && !s.breaker.Allow(host) {
+		result.HostUnavailable = true
+		result.Error = errHostUnavailable
+		return result
+	}
+
+	resp, attempts, err := s.retry.do(ctx, s.httpClient, func() (*http.Request, error) {
 		req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
 		if err != nil {
-			results <- false
-			continue
+			return nil, err
 		}
-		resp, err := s.httpClient.Do(req)
-		if err != nil || (resp.StatusCode < 200 || resp.StatusCode >= 400) {
-			results <- false
-		} else {
-			results <- true
+		if found {
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
 		}
-		if resp != nil {
-			resp.Body.Close()
+		return req, nil
+	})
+	result.Attempts = attempts
+	if attempts == 0 {
+		if s.breaker != nil {
+			s.breaker.Release(host)
+		}
+		result.Error = err.Error()
+		return result
+	}
+	s.recordHost(host, resp, err)
+	if err != nil {
+		result.Error = err.Error()
+		result.TLSFailure = classifyTLSError(err)
+		result.tls = tlsInfoFromError(tlsHostOf(link, nil, err), err)
+		s.storeLinkResult(link, result, nil)
+		return result
+	}
+	defer resp.Body.Close()
+
+	if found && resp.StatusCode == http.StatusNotModified {
+		cached.CheckedAt = result.CheckedAt
+		if info := tlsInfoFromState(tlsHostOf(link, resp, nil), resp.TLS); info != nil {
+			cached.TLS = info
 		}
+		s.linkCache.Put(link, cached)
+		return cachedLinkResult(link, cached, CacheRevalidated)
+	}
+
+	result.tls = tlsInfoFromState(tlsHostOf(link, resp, nil), resp.TLS)
+	result.StatusCode = resp.StatusCode
+	result.Accessible = resp.StatusCode >= 200 && resp.StatusCode < 400
+	if resp.Request != nil && resp.Request.URL != nil {
+		result.FinalURL = resp.Request.URL.String()
+	}
+	s.storeLinkResult(link, result, resp.Header)
+	return result
+}
+
+// recordHost reports the outcome of a request to host to the circuit breaker.
+// TLS failures are not counted: a bad certificate fails every request the
+// same way, and counting it would hide the failure behind "host unavailable".
+func (s *AnalysisService) recordHost(host string, resp *http.Response, err error) {
+	switch {
+	case s.breaker == nil:
+	case classifyTLSError(err) != "":
+		s.breaker.Release(host)
+	default:
+		s.breaker.Record(host, err != nil || resp.StatusCode >= 500)
+	}
+}
+
+func (s *AnalysisService) storeLinkResult(link string, result LinkCheckResult, header http.Header) {
+	if s.linkCache == nil {
+		return
+	}
+	entry := LinkCacheEntry{
+		Accessible: result.Accessible,
+		StatusCode: result.StatusCode,
+		FinalURL:   result.FinalURL,
+		CheckedAt:  result.CheckedAt,
+		Error:      result.Error,
+		TLSFailure: result.TLSFailure,
+		TLS:        result.tls,
+	}
+	if header != nil {
+		entry.ETag = header.Get("ETag")
+		entry.LastModified = header.Get("Last-Modified")
+	}
+	s.linkCache.Put(link, entry)
+}
+
+func cachedLinkResult(link string, entry LinkCacheEntry, cache string) LinkCheckResult {
+	return LinkCheckResult{
+		URL:        link,
+		Accessible: entry.Accessible,
+		StatusCode: entry.StatusCode,
+		FinalURL:   entry.FinalURL,
+		CheckedAt:  entry.CheckedAt,
+		Cache:      cache,
+		Error:      entry.Error,
+		TLSFailure: entry.TLSFailure,
+		tls:        entry.TLS,
 	}
 }
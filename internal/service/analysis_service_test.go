@@ -1,15 +1,23 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
+	"slices"
 	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
 )
 
 // MockHTTPClient is a mock implementation of the HTTP client interface
@@ -48,8 +56,16 @@ const sampleHTML = `<!DOCTYPE html>
 </body>
 </html>`
 
+// newSharedTestService returns NewAnalysisService with the shared history
+// kept in memory, so that tests never write to the user's data directory.
+func newSharedTestService(t *testing.T) *AnalysisService {
+	t.Helper()
+	t.Setenv("HISTORY_PATH", "none")
+	return NewAnalysisService()
+}
+
 func TestNewAnalysisService(t *testing.T) {
-	service := NewAnalysisService()
+	service := newSharedTestService(t)
 
 	if service == nil {
 		t.Fatal("NewAnalysisService() returned nil")
@@ -163,6 +179,36 @@ func TestAnalyzePage_HTTPClientError(t *testing.T) {
 	}
 }
 
+func TestAnalyzePage_LogsCarryRequestAndJobFields(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.GetLogger()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stdout) })
+
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			return nil, errors.New("network error")
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+	ctx := logger.ContextWithFields(context.Background(), logrus.Fields{"request_id": "req-1"})
+
+	if _, err := service.AnalyzePage(ctx, "https://example.com/test"); err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(strings.SplitN(buf.String(), "\n", 2)[0]), &entry); err != nil {
+		t.Fatalf("Expected a JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry["request_id"] != "req-1" || entry["url"] != "https://example.com/test" {
+		t.Errorf("Expected request_id and url fields, got %v", entry)
+	}
+	if id, _ := entry["job_id"].(string); id == "" {
+		t.Errorf("Expected a job_id field, got %v", entry)
+	}
+}
+
 func TestAnalyzePage_NonSuccessStatusCode(t *testing.T) {
 	testCases := []struct {
 		name       string
@@ -204,7 +250,7 @@ func TestAnalyzePage_NonSuccessStatusCode(t *testing.T) {
 }
 
 func TestAnalyzePage_InvalidURL(t *testing.T) {
-	service := NewAnalysisService()
+	service := newSharedTestService(t)
 	ctx := context.Background()
 
 	result, err := service.AnalyzePage(ctx, "://invalid-url")
@@ -357,7 +403,7 @@ func TestCountInaccessibleLinks_NetworkErrors(t *testing.T) {
 }
 
 func TestCountInaccessibleLinks_EmptyList(t *testing.T) {
-	service := NewAnalysisService()
+	service := newSharedTestService(t)
 	ctx := context.Background()
 
 	count := service.countInaccessibleLinks(ctx, []string{})
@@ -525,6 +571,120 @@ func TestAnalyzePage_AnalyzerError(t *testing.T) {
 	}
 }
 
+func TestAnalyzeDocument_WithBaseURLChecksLinks(t *testing.T) {
+	var checked []string
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			checked = append(checked, req.URL.String())
+			if req.URL.Host == "external.com" {
+				return createMockResponse(404, ""), nil
+			}
+			return createMockResponse(200, ""), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient, scheduler: NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 1})}
+
+	result, err := service.AnalyzeDocument(context.Background(), strings.NewReader(sampleHTML), DocumentOptions{
+		BaseURL:    "https://example.com/staging/page",
+		CheckLinks: true,
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeDocument() returned error: %v", err)
+	}
+	if !result.Document || result.URL != "https://example.com/staging/page" || result.Title != "Test Page" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if result.InternalLinksCount != 2 || result.ExternalLinksCount != 1 {
+		t.Errorf("Expected links classified against the base URL, got internal %v, external %v", result.InternalLinks, result.ExternalLinks)
+	}
+	if len(checked) != 2 || result.InaccessibleExternalLinksCount != 1 {
+		t.Errorf("Expected the relative and external links to be checked, got %v", checked)
+	}
+}
+
+func TestAnalyzeDocument_WithoutBaseURL(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			t.Errorf("Unexpected request to %s", req.URL)
+			return createMockResponse(200, ""), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+
+	result, err := service.AnalyzeDocument(context.Background(), strings.NewReader(sampleHTML), DocumentOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeDocument() returned error: %v", err)
+	}
+	if result.InternalLinksCount != 1 || result.ExternalLinksCount != 2 || len(result.LinkResults) != 0 {
+		t.Errorf("Expected only the relative link to be internal and nothing checked, got %+v", result)
+	}
+
+	_, err = service.AnalyzeDocument(context.Background(), strings.NewReader(sampleHTML), DocumentOptions{BaseURL: "example.com"})
+	if err == nil {
+		t.Error("Expected a relative base URL to be rejected")
+	}
+}
+
+func TestAnalyzeDocument_RelativeLinks(t *testing.T) {
+	html := `<html><body>
+    <a href="about.html">About</a>
+    <a href="./docs/">Docs</a>
+    <a href="../up.html">Up</a>
+    <a href="#intro">Intro</a>
+    <a href="//cdn.example.net/file">CDN</a>
+    <h2 id="intro">Intro</h2>
+</body></html>`
+	var checked []string
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			checked = append(checked, req.URL.String())
+			return createMockResponse(200, ""), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient, scheduler: NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 1})}
+
+	result, err := service.AnalyzeDocument(context.Background(), strings.NewReader(html), DocumentOptions{
+		BaseURL:    "https://example.com/staging/page.html",
+		CheckLinks: true,
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeDocument() returned error: %v", err)
+	}
+	if result.InternalLinksCount != 4 || !slices.Equal(result.ExternalLinks, []string{"//cdn.example.net/file"}) {
+		t.Errorf("Expected relative links to be internal, got internal %v, external %v", result.InternalLinks, result.ExternalLinks)
+	}
+	expected := []string{
+		"https://example.com/staging/about.html",
+		"https://example.com/staging/docs/",
+		"https://example.com/up.html",
+	}
+	var checkedInternal []string
+	for _, link := range checked {
+		if strings.HasPrefix(link, "https://example.com/") {
+			checkedInternal = append(checkedInternal, link)
+		}
+	}
+	slices.Sort(checkedInternal)
+	slices.Sort(expected)
+	if !slices.Equal(checkedInternal, expected) {
+		t.Errorf("Expected relative links resolved against the base URL, checked %v", checked)
+	}
+
+	checked = nil
+	result, err = service.AnalyzeDocument(context.Background(), strings.NewReader(html), DocumentOptions{CheckLinks: true})
+	if err != nil {
+		t.Fatalf("AnalyzeDocument() returned error: %v", err)
+	}
+	if result.InternalLinksCount != 4 || len(result.InaccessibleInternalLinks) != 0 {
+		t.Errorf("Expected relative links to be internal and unchecked without a base URL, got %+v", result.InternalLinks)
+	}
+	for _, link := range checked {
+		if !strings.Contains(link, "cdn.example.net") {
+			t.Errorf("Expected no relative link to be checked without a base URL, checked %s", link)
+		}
+	}
+}
+
 func TestLinkCheckerWorker_InvalidURL(t *testing.T) {
 	mockClient := &MockHTTPClient{
 		DoFunc: func(_ *http.Request) (*http.Response, error) {
@@ -667,3 +827,57 @@ func BenchmarkCountInaccessibleLinks(b *testing.B) {
 		service.countInaccessibleLinks(ctx, links)
 	}
 }
+
+func TestAnalyzePage_ContentLanguage(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			response := createMockResponse(200, `<html lang="en"><body><p>Welcome to our shop. We offer a wide range of products for the home and garden.</p></body></html>`)
+			response.Header.Set("Content-Language", "de")
+			return response, nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+
+	result, err := service.AnalyzePageWithOptions(context.Background(), "https://example.com/", AnalysisOptions{Modules: []string{"language"}})
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+	if result.Lang != "en" || result.ContentLanguage != "de" || result.Content.Language != "en" {
+		t.Errorf("Expected declared, served and detected languages, got %q, %q and %q", result.Lang, result.ContentLanguage, result.Content.Language)
+	}
+	if len(result.Findings) != 1 || result.Findings[0].Rule != "content-language-mismatch" {
+		t.Errorf("Expected a Content-Language mismatch, got %+v", result.Findings)
+	}
+}
+
+func TestAnalyzePage_MixedContent(t *testing.T) {
+	page := `<html><body><script src="http://cdn.example.com/app.js"></script></body></html>`
+	service := &AnalysisService{httpClient: &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(200, page), nil
+		},
+	}}
+	result, err := service.AnalyzePage(context.Background(), "https://example.com/")
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+	if len(result.MixedContent) != 1 || result.MixedContent[0].Kind != "active" {
+		t.Errorf("Expected the script as active mixed content, got %+v", result.MixedContent)
+	}
+
+	// A page redirected to plain HTTP has no mixed content.
+	service.httpClient = &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			response := createMockResponse(200, page)
+			response.Request, _ = http.NewRequest(http.MethodGet, "http://example.com/", nil)
+			return response, nil
+		},
+	}
+	result, err = service.AnalyzePage(context.Background(), "https://example.com/other")
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+	if len(result.MixedContent) != 0 {
+		t.Errorf("Expected no mixed content on a plain HTTP page, got %+v", result.MixedContent)
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
+)
+
+// fragmentExists reports whether fragment names one of anchors. The empty
+// fragment and "top" scroll to the top of any page, and text fragments
+// (":~:text=...") do not name an element.
+func fragmentExists(anchors map[string]bool, fragment string) bool {
+	return fragment == "" || strings.EqualFold(fragment, "top") || strings.HasPrefix(fragment, ":~:") || anchors[fragment]
+}
+
+func anchorSet(anchors []string) map[string]bool {
+	set := make(map[string]bool, len(anchors))
+	for _, anchor := range anchors {
+		set[anchor] = true
+	}
+	return set
+}
+
+// fragmentLink is a link with a fragment pointing at another page.
+type fragmentLink struct {
+	href     string
+	fragment string
+}
+
+// checkAnchors returns the links of result, as written, whose fragment names
+// no anchor of the page it points to. Same-page fragments are checked against
+// result itself; when fetch is set, other pages are fetched with GET, since a
+// HEAD check cannot see their anchors. Links whose target cannot be fetched
+// or is not HTML are left to the link checks.
+func (s *AnalysisService) checkAnchors(ctx context.Context, pageURL string, result *analyzer.AnalysisResult, fetch bool) []string {
+	var base *url.URL
+	if pageURL != "" {
+		base, _ = url.Parse(pageURL)
+	}
+	own := anchorSet(result.Anchors)
+
+	broken := make(map[string]bool)
+	seen := make(map[string]bool)
+	targets := make(map[string][]fragmentLink)
+	var targetOrder []string
+	for _, href := range result.Links {
+		if seen[href] || !strings.Contains(href, "#") {
+			continue
+		}
+		seen[href] = true
+		link, err := url.Parse(href)
+		if err != nil {
+			continue
+		}
+		if base != nil {
+			link = base.ResolveReference(link)
+		}
+		if strings.HasPrefix(href, "#") || (base != nil && samePage(link, base)) {
+			if !fragmentExists(own, link.Fragment) {
+				broken[href] = true
+			}
+			continue
+		}
+		if !fetch || (link.Scheme != "http" && link.Scheme != "https") || fragmentExists(nil, link.Fragment) {
+			continue
+		}
+		fragment := link.Fragment
+		link.Fragment, link.RawFragment = "", ""
+		target := link.String()
+		if _, ok := targets[target]; !ok {
+			targetOrder = append(targetOrder, target)
+		}
+		targets[target] = append(targets[target], fragmentLink{href, fragment})
+	}
+
+	if len(targetOrder) > 0 {
+		ctx, span := tracing.Start(ctx, "anchor_checks", trace.WithAttributes(attribute.Int("anchor.pages", len(targetOrder))))
+		for target, anchors := range s.fetchAnchors(ctx, targetOrder) {
+			for _, link := range targets[target] {
+				if !fragmentExists(anchors, link.fragment) {
+					broken[link.href] = true
+				}
+			}
+		}
+		span.End()
+	}
+
+	var links []string
+	for _, href := range result.Links {
+		if broken[href] {
+			links = append(links, href)
+			delete(broken, href)
+		}
+	}
+	return links
+}
+
+func samePage(link, page *url.URL) bool {
+	return link.Scheme == page.Scheme && strings.EqualFold(link.Host, page.Host) &&
+		link.Path == page.Path && link.RawQuery == page.RawQuery
+}
+
+// fetchAnchors fetches pages through the link scheduler and returns the
+// anchors of each one that is reachable HTML.
+func (s *AnalysisService) fetchAnchors(ctx context.Context, pages []string) map[string]map[string]bool {
+	found := make(map[string]map[string]bool, len(pages))
+	for _, fetched := range s.fetchAll(ctx, pages) {
+		anchors, err := pageAnchors(ctx, fetched)
+		if err != nil {
+			logger.WithContext(ctx).WithField("link", fetched.URL).WithField("error", err).Debug("Skipping fragment check")
+			continue
+		}
+		found[fetched.URL] = anchors
+	}
+	return found
+}
+
+func pageAnchors(ctx context.Context, fetched LinkCheckResult) (map[string]bool, error) {
+	if fetched.body == nil {
+		return nil, errors.New(fetched.Error)
+	}
+	if contentType := fetched.body.contentType; contentType != "" {
+		if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+			return nil, fmt.Errorf("not HTML: %s", mediaType)
+		}
+	}
+	result, err := analyzer.AnalyzeContext(ctx, bytes.NewReader(fetched.body.body))
+	if err != nil {
+		return nil, err
+	}
+	return anchorSet(result.Anchors), nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAnalyzePage_BrokenAnchors(t *testing.T) {
+	page := `<html><head><title>Anchors</title></head><body>
+    <h2 id="intro">Intro</h2>
+    <a href="#intro">Same page</a>
+    <a href="#missing">Same page, missing</a>
+    <a href="#">Top</a>
+    <a href="#:~:text=Intro">Text fragment</a>
+    <a href="https://example.com/page?x=1#intro">Same page, absolute</a>
+    <a href="/docs#install">Other page</a>
+    <a href="/docs#nope">Other page, missing</a>
+    <a href="/docs#install">Other page again</a>
+    <a href="https://files.example.org/guide.pdf#page=2">PDF</a>
+    <a href="https://gone.example.org/#x">Unreachable</a>
+</body></html>`
+
+	var mu sync.Mutex
+	gets := make(map[string]int)
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				mu.Lock()
+				gets[req.URL.String()]++
+				mu.Unlock()
+			}
+			switch req.URL.Host + req.URL.Path {
+			case "example.com/page":
+				return createMockResponse(200, page), nil
+			case "example.com/docs":
+				resp := createMockResponse(200, `<h2 id="install">Install</h2>`)
+				resp.Header.Set("Content-Type", "text/html; charset=utf-8")
+				return resp, nil
+			case "files.example.org/guide.pdf":
+				resp := createMockResponse(200, "%PDF")
+				resp.Header.Set("Content-Type", "application/pdf")
+				return resp, nil
+			case "gone.example.org/":
+				return createMockResponse(404, ""), nil
+			}
+			return createMockResponse(200, ""), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+
+	result, err := service.AnalyzePage(context.Background(), "https://example.com/page?x=1")
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	expected := []string{"#missing", "/docs#nope"}
+	if !slices.Equal(result.BrokenAnchorLinks, expected) || result.BrokenAnchorLinksCount != 2 {
+		t.Errorf("Expected broken anchors %v, got %v", expected, result.BrokenAnchorLinks)
+	}
+	if gets["https://example.com/docs"] != 1 {
+		t.Errorf("Expected the target page to be fetched once with GET, got %v", gets)
+	}
+	if !slices.Contains(result.InaccessibleExternalLinks, "https://gone.example.org/#x") {
+		t.Errorf("Expected an unreachable target to be reported by the link checks, got %v", result.InaccessibleExternalLinks)
+	}
+}
+
+func TestAnalyzeDocument_SamePageAnchorsWithoutFetching(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			t.Errorf("Unexpected request to %s", req.URL)
+			return createMockResponse(200, ""), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+	html := `<a name="top-of-list"></a><a href="#top-of-list">ok</a><a href="#gone">broken</a><a href="/other#x">other</a>`
+
+	result, err := service.AnalyzeDocument(context.Background(), strings.NewReader(html), DocumentOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzeDocument() returned error: %v", err)
+	}
+	if !slices.Equal(result.BrokenAnchorLinks, []string{"#gone"}) {
+		t.Errorf("Expected only the same-page link to be checked, got %v", result.BrokenAnchorLinks)
+	}
+}
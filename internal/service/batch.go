@@ -0,0 +1,464 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+const (
+	defaultBatchConcurrency = 4
+	defaultBatchMaxURLs     = 1000
+	defaultBatchRetained    = 50
+	defaultBatchMaxRunning  = 4
+	batchWorstOffenders     = 10
+)
+
+// Batch and item states.
+const (
+	BatchPending = "pending"
+	BatchRunning = "running"
+	BatchDone    = "done"
+	BatchFailed  = "failed"
+)
+
+var (
+	// ErrBatchNotFound is returned for an unknown or evicted batch ID.
+	ErrBatchNotFound = errors.New("batch not found")
+	// ErrTooManyBatches is returned when MaxRunning batches are already running.
+	ErrTooManyBatches = errors.New("too many batches running, try again later")
+)
+
+// BatchConfig controls batch analysis.
+type BatchConfig struct {
+	// Concurrency is the number of pages of one batch analyzed at a time.
+	Concurrency int
+	// MaxURLs caps the number of URLs accepted in one batch.
+	MaxURLs int
+	// Retained is the number of batches kept in memory, oldest evicted first.
+	Retained int
+	// MaxRunning caps the number of batches running at once across all
+	// clients.
+	MaxRunning int
+}
+
+// DefaultBatchConfig returns the configuration used when none is provided.
+func DefaultBatchConfig() BatchConfig {
+	return BatchConfig{
+		Concurrency: defaultBatchConcurrency,
+		MaxURLs:     defaultBatchMaxURLs,
+		Retained:    defaultBatchRetained,
+		MaxRunning:  defaultBatchMaxRunning,
+	}
+}
+
+// BatchItem is the status and outcome of one URL in a batch.
+type BatchItem struct {
+	URL     string          `json:"url"`
+	Status  string          `json:"status"`
+	Error   string          `json:"error,omitempty"`
+	Summary *HistorySummary `json:"summary,omitempty"`
+	// Records are the data extracted from the page in extraction batches.
+	Records  []analyzer.Record `json:"records,omitempty"`
+	Duration time.Duration     `json:"duration_ns,omitempty"`
+}
+
+// BatchSummary aggregates the items of a batch.
+type BatchSummary struct {
+	Total             int `json:"total"`
+	Pending           int `json:"pending"`
+	Succeeded         int `json:"succeeded"`
+	Failed            int `json:"failed"`
+	InaccessibleLinks int `json:"inaccessible_links"`
+	// Records counts the records extracted in extraction batches.
+	Records int `json:"records,omitempty"`
+	// WorstOffenders are the pages with the most inaccessible links.
+	WorstOffenders []BatchItem `json:"worst_offenders,omitempty"`
+}
+
+// Batch is a set of URLs analyzed together, or, when Template is set, a set
+// of pages data is extracted from with that extraction template.
+type Batch struct {
+	ID       string `json:"id"`
+	Template string `json:"template,omitempty"`
+	// Fields are the template's fields, in order, for extraction batches.
+	Fields     []string     `json:"fields,omitempty"`
+	Status     string       `json:"status"`
+	CreatedAt  time.Time    `json:"created_at"`
+	FinishedAt time.Time    `json:"finished_at,omitzero"`
+	Summary    BatchSummary `json:"summary"`
+	Items      []BatchItem  `json:"items"`
+}
+
+// BatchRunner analyzes lists of URLs in the background with a bounded
+// number of workers per batch and keeps recent batches for reporting.
+type BatchRunner struct {
+	config  BatchConfig
+	analyze func(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error)
+	extract func(ctx context.Context, pageURL, template string) (*PageExtraction, error)
+	now     func() time.Time
+
+	mu      sync.Mutex
+	batches map[string]*Batch
+	order   []string
+	running int
+}
+
+// NewBatchRunner creates a runner. analyze runs one analysis.
+func NewBatchRunner(config BatchConfig, analyze func(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error)) *BatchRunner {
+	if config.Concurrency <= 0 {
+		config.Concurrency = defaultBatchConcurrency
+	}
+	if config.MaxURLs <= 0 {
+		config.MaxURLs = defaultBatchMaxURLs
+	}
+	if config.Retained <= 0 {
+		config.Retained = defaultBatchRetained
+	}
+	if config.MaxRunning <= 0 {
+		config.MaxRunning = defaultBatchMaxRunning
+	}
+	return &BatchRunner{
+		config:  config,
+		analyze: analyze,
+		now:     time.Now,
+		batches: make(map[string]*Batch),
+	}
+}
+
+// WithExtractor sets the function extraction batches extract one page with
+// and returns b.
+func (b *BatchRunner) WithExtractor(extract func(ctx context.Context, pageURL, template string) (*PageExtraction, error)) *BatchRunner {
+	b.extract = extract
+	return b
+}
+
+// Start validates urls and analyzes them in the background. ctx carries
+// logging fields and cancels the batch when done.
+func (b *BatchRunner) Start(ctx context.Context, urls []string) (Batch, error) {
+	return b.start(ctx, urls, nil)
+}
+
+// StartExtraction validates urls and extracts data from them with the named
+// template in the background.
+func (b *BatchRunner) StartExtraction(ctx context.Context, urls []string, templateName string) (Batch, error) {
+	if b.extract == nil {
+		return Batch{}, errors.New("extraction is not supported by this batch runner")
+	}
+	template, err := ExtractionTemplate(templateName)
+	if err != nil {
+		return Batch{}, err
+	}
+	return b.start(ctx, urls, template)
+}
+
+func (b *BatchRunner) start(ctx context.Context, urls []string, template *analyzer.ExtractionTemplate) (Batch, error) {
+	if len(urls) == 0 {
+		return Batch{}, errors.New("no URLs given")
+	}
+	if len(urls) > b.config.MaxURLs {
+		return Batch{}, fmt.Errorf("too many URLs: %d given, at most %d allowed", len(urls), b.config.MaxURLs)
+	}
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return Batch{}, fmt.Errorf("invalid URL %q: expected an absolute http or https URL", u)
+		}
+	}
+
+	batch := &Batch{ID: newID(), Status: BatchRunning, CreatedAt: b.now(), Items: make([]BatchItem, len(urls))}
+	if template != nil {
+		batch.Template = template.Name
+		batch.Fields = template.FieldNames()
+	}
+	for i, u := range urls {
+		batch.Items[i] = BatchItem{URL: u, Status: BatchPending}
+	}
+
+	b.mu.Lock()
+	if b.running >= b.config.MaxRunning {
+		b.mu.Unlock()
+		return Batch{}, ErrTooManyBatches
+	}
+	b.running++
+	b.batches[batch.ID] = batch
+	b.order = append(b.order, batch.ID)
+	for len(b.order) > b.config.Retained {
+		delete(b.batches, b.order[0])
+		b.order = b.order[1:]
+	}
+	snapshot := batch.snapshot()
+	b.mu.Unlock()
+
+	ctx = logger.ContextWithFields(ctx, logrus.Fields{"batch_id": batch.ID})
+	go b.run(ctx, batch)
+	return snapshot, nil
+}
+
+// Get returns a snapshot of a batch with an up-to-date summary.
+func (b *BatchRunner) Get(id string) (Batch, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	batch, ok := b.batches[id]
+	if !ok {
+		return Batch{}, ErrBatchNotFound
+	}
+	return batch.snapshot(), nil
+}
+
+func (b *BatchRunner) run(ctx context.Context, batch *Batch) {
+	start := b.now()
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(b.config.Concurrency, len(batch.Items)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				b.runItem(ctx, batch, i)
+			}
+		}()
+	}
+	for i := range batch.Items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	b.mu.Lock()
+	batch.Status = BatchDone
+	batch.FinishedAt = b.now()
+	b.running--
+	b.mu.Unlock()
+	logger.WithContext(ctx).WithFields(logrus.Fields{
+		"urls":     len(batch.Items),
+		"duration": b.now().Sub(start).String(),
+	}).Info("Batch completed")
+}
+
+func (b *BatchRunner) runItem(ctx context.Context, batch *Batch, i int) {
+	b.mu.Lock()
+	batch.Items[i].Status = BatchRunning
+	pageURL := batch.Items[i].URL
+	b.mu.Unlock()
+
+	start := b.now()
+	var dto *AnalysisServiceResultDTO
+	var extraction *PageExtraction
+	var err error
+	if batch.Template != "" {
+		extraction, err = b.extract(ctx, pageURL, batch.Template)
+	} else {
+		dto, err = b.analyze(ctx, pageURL)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	item := &batch.Items[i]
+	item.Duration = b.now().Sub(start)
+	if err != nil {
+		item.Status = BatchFailed
+		item.Error = err.Error()
+		return
+	}
+	item.Status = BatchDone
+	if extraction != nil {
+		item.Records = extraction.Records
+		return
+	}
+	summary := summarize(dto)
+	item.Summary = &summary
+}
+
+// snapshot copies the batch and computes its summary. Callers must hold the runner's lock.
+func (batch *Batch) snapshot() Batch {
+	out := *batch
+	out.Items = make([]BatchItem, len(batch.Items))
+	copy(out.Items, batch.Items)
+
+	summary := BatchSummary{Total: len(out.Items)}
+	var offenders []BatchItem
+	for _, item := range out.Items {
+		switch item.Status {
+		case BatchDone:
+			summary.Succeeded++
+			summary.Records += len(item.Records)
+			if item.Summary == nil {
+				continue
+			}
+			summary.InaccessibleLinks += item.Summary.InaccessibleLinks
+			if item.Summary.InaccessibleLinks > 0 {
+				offenders = append(offenders, item)
+			}
+		case BatchFailed:
+			summary.Failed++
+		default:
+			summary.Pending++
+		}
+	}
+	sort.SliceStable(offenders, func(i, j int) bool {
+		return offenders[i].Summary.InaccessibleLinks > offenders[j].Summary.InaccessibleLinks
+	})
+	if len(offenders) > batchWorstOffenders {
+		offenders = offenders[:batchWorstOffenders]
+	}
+	summary.WorstOffenders = offenders
+	out.Summary = summary
+	return out
+}
+
+// WriteCSV writes one row per URL. Cells that a spreadsheet would treat as a
+// formula are prefixed with a single quote, since titles and URLs come from
+// untrusted pages.
+func (batch Batch) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{
+		"url", "status", "error", "title", "html_version", "has_login_form",
+		"internal_links", "external_links", "inaccessible_links", "host_unavailable_links", "duration_ms",
+	})
+	for _, item := range batch.Items {
+		row := []string{item.URL, item.Status, item.Error, "", "", "", "", "", "", "", strconv.FormatInt(item.Duration.Milliseconds(), 10)}
+		if s := item.Summary; s != nil {
+			row[3], row[4], row[5] = s.Title, s.HTMLVersion, strconv.FormatBool(s.HasLoginForm)
+			row[6], row[7] = strconv.Itoa(s.InternalLinks), strconv.Itoa(s.ExternalLinks)
+			row[8], row[9] = strconv.Itoa(s.InaccessibleLinks), strconv.Itoa(s.HostUnavailableLinks)
+		}
+		for i := range row {
+			row[i] = csvSafe(row[i])
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// Extractions returns the records of the pages of an extraction batch that
+// are done, for WriteExtractionJSONL and WriteExtractionCSV.
+func (batch Batch) Extractions() []PageExtraction {
+	var extractions []PageExtraction
+	for _, item := range batch.Items {
+		if item.Status == BatchDone {
+			extractions = append(extractions, PageExtraction{URL: item.URL, Template: batch.Template, Records: item.Records})
+		}
+	}
+	return extractions
+}
+
+func csvSafe(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// ParseURLList reads URLs from a JSON array (of strings or of objects with a
+// "url" field), a CSV file (the "url" column if there is a header naming
+// one, otherwise the first column) or plain text with one URL per line.
+// Blank lines, lines starting with "#" and duplicates are skipped.
+func ParseURLList(r io.Reader) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\xef\xbb\xbf"))
+
+	var candidates []string
+	switch {
+	case bytes.HasPrefix(data, []byte("[")):
+		candidates, err = parseJSONURLList(data)
+	case bytes.ContainsRune(firstLine(data), ','):
+		candidates, err = parseCSVURLList(data)
+	default:
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			candidates = append(candidates, scanner.Text())
+		}
+		err = scanner.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" || strings.HasPrefix(candidate, "#") || seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		urls = append(urls, candidate)
+	}
+	return urls, nil
+}
+
+func parseJSONURLList(data []byte) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid JSON URL list: %w", err)
+	}
+	urls := make([]string, 0, len(items))
+	for _, item := range items {
+		var s string
+		if json.Unmarshal(item, &s) == nil {
+			urls = append(urls, s)
+			continue
+		}
+		var obj struct {
+			URL string `json:"url"`
+		}
+		if err := json.Unmarshal(item, &obj); err != nil {
+			return nil, errors.New("invalid JSON URL list: expected strings or objects with a url field")
+		}
+		urls = append(urls, obj.URL)
+	}
+	return urls, nil
+}
+
+func parseCSVURLList(data []byte) ([]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV URL list: %w", err)
+	}
+
+	column := 0
+	if len(records) > 0 {
+		for i, name := range records[0] {
+			if strings.EqualFold(strings.TrimSpace(name), "url") {
+				column = i
+				records = records[1:]
+				break
+			}
+		}
+	}
+	urls := make([]string, 0, len(records))
+	for _, record := range records {
+		if column < len(record) {
+			urls = append(urls, record[column])
+		}
+	}
+	return urls, nil
+}
+
+func firstLine(data []byte) []byte {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	return line
+}
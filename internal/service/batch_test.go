@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseURLList(t *testing.T) {
+	expected := []string{"https://a.example.com", "https://b.example.com"}
+	testCases := map[string]string{
+		"lines":         "https://a.example.com\n\n# comment\nhttps://b.example.com\r\nhttps://a.example.com\n",
+		"csv header":    "name,url\nA,https://a.example.com\nB, https://b.example.com\n",
+		"csv no header": "https://a.example.com,A\nhttps://b.example.com,B\n",
+		"json strings":  `["https://a.example.com", "https://b.example.com"]`,
+		"json objects":  "\xef\xbb\xbf" + `[{"url": "https://a.example.com"}, {"url": "https://b.example.com", "note": "x"}]`,
+	}
+	for name, input := range testCases {
+		t.Run(name, func(t *testing.T) {
+			urls, err := ParseURLList(strings.NewReader(input))
+			if err != nil {
+				t.Fatalf("ParseURLList() returned error: %v", err)
+			}
+			if !slices.Equal(urls, expected) {
+				t.Errorf("Expected %v, got %v", expected, urls)
+			}
+		})
+	}
+
+	if _, err := ParseURLList(strings.NewReader(`[1, 2]`)); err == nil {
+		t.Error("Expected an error for a JSON array of numbers")
+	}
+}
+
+func waitForBatch(t *testing.T, runner *BatchRunner, id string) Batch {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		batch, err := runner.Get(id)
+		if err != nil {
+			t.Fatalf("Get() returned error: %v", err)
+		}
+		if batch.Status == BatchDone {
+			return batch
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Batch did not finish, last state: %+v", batch)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatchRunner_BoundedPoolAndSummary(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	runner := NewBatchRunner(BatchConfig{Concurrency: 2}, func(_ context.Context, pageURL string) (*AnalysisServiceResultDTO, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if strings.Contains(pageURL, "down") {
+			return nil, errors.New("connection refused")
+		}
+		var broken int
+		fmt.Sscanf(pageURL, "https://example.com/%d", &broken)
+		return &AnalysisServiceResultDTO{InaccessibleExternalLinksCount: broken}, nil
+	})
+
+	urls := []string{"https://example.com/0", "https://example.com/3", "https://down.example.com", "https://example.com/7", "https://example.com/1"}
+	started, err := runner.Start(context.Background(), urls)
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	if started.Summary.Pending != len(urls) {
+		t.Errorf("Expected all items pending at start, got %+v", started.Summary)
+	}
+
+	batch := waitForBatch(t, runner, started.ID)
+	if peak.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent analyses, got %d", peak.Load())
+	}
+	summary := batch.Summary
+	if summary.Succeeded != 4 || summary.Failed != 1 || summary.InaccessibleLinks != 11 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	var worst []string
+	for _, item := range summary.WorstOffenders {
+		worst = append(worst, item.URL)
+	}
+	if !slices.Equal(worst, []string{"https://example.com/7", "https://example.com/3", "https://example.com/1"}) {
+		t.Errorf("Expected worst offenders ordered by inaccessible links, got %v", worst)
+	}
+	if batch.Items[2].Status != BatchFailed || batch.Items[2].Error != "connection refused" {
+		t.Errorf("Expected failed item to carry its error, got %+v", batch.Items[2])
+	}
+}
+
+func TestBatchRunner_Validation(t *testing.T) {
+	runner := NewBatchRunner(BatchConfig{MaxURLs: 2}, nil)
+	for _, urls := range [][]string{
+		nil,
+		{"https://a.example.com", "https://b.example.com", "https://c.example.com"},
+		{"example.com"},
+	} {
+		if _, err := runner.Start(context.Background(), urls); err == nil {
+			t.Errorf("Expected %v to be rejected", urls)
+		}
+	}
+	if _, err := runner.Get("missing"); !errors.Is(err, ErrBatchNotFound) {
+		t.Errorf("Expected ErrBatchNotFound, got %v", err)
+	}
+}
+
+func TestBatchRunner_MaxRunning(t *testing.T) {
+	release := make(chan struct{})
+	runner := NewBatchRunner(BatchConfig{MaxRunning: 1}, func(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error) {
+		<-release
+		return &AnalysisServiceResultDTO{}, nil
+	})
+	ctx := context.Background()
+
+	first, err := runner.Start(ctx, []string{"https://a.example.com"})
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	if _, err := runner.Start(ctx, []string{"https://b.example.com"}); !errors.Is(err, ErrTooManyBatches) {
+		t.Fatalf("Expected ErrTooManyBatches while a batch is running, got %v", err)
+	}
+
+	close(release)
+	waitForBatch(t, runner, first.ID)
+	if _, err := runner.Start(ctx, []string{"https://b.example.com"}); err != nil {
+		t.Errorf("Expected a batch to start once the running one finished, got %v", err)
+	}
+}
+
+func TestBatch_WriteCSV(t *testing.T) {
+	batch := Batch{Items: []BatchItem{
+		{URL: "https://example.com", Status: BatchDone, Summary: &HistorySummary{Title: "=HYPERLINK(\"x\")", InaccessibleLinks: 2}},
+		{URL: "https://down.example.com", Status: BatchFailed, Error: "timeout"},
+	}}
+	var out strings.Builder
+	if err := batch.WriteCSV(&out); err != nil {
+		t.Fatalf("WriteCSV() returned error: %v", err)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(out.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got error: %v", err)
+	}
+	if len(records) != 3 || records[0][0] != "url" {
+		t.Fatalf("Expected a header and one row per URL, got %v", records)
+	}
+	if records[1][3] != `'=HYPERLINK("x")` {
+		t.Errorf("Expected formula-like title to be escaped, got %q", records[1][3])
+	}
+	if records[1][8] != "2" || records[2][2] != "timeout" {
+		t.Errorf("Unexpected rows: %v", records[1:])
+	}
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// CircuitBreakerConfig controls when a host is considered down.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive host failures that opens the circuit.
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before a single probe is let through.
+	Cooldown time.Duration
+}
+
+// DefaultCircuitBreakerConfig returns the configuration used when none is provided.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: defaultBreakerThreshold,
+		Cooldown:         defaultBreakerCooldown,
+	}
+}
+
+// CircuitBreaker tracks consecutive failures per host and short-circuits
+// checks against hosts that appear to be down.
+type CircuitBreaker struct {
+	config CircuitBreakerConfig
+	now    func() time.Time
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+type hostCircuit struct {
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker creates a circuit breaker with every host closed.
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = defaultBreakerThreshold
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = defaultBreakerCooldown
+	}
+	return &CircuitBreaker{
+		config: config,
+		now:    time.Now,
+		hosts:  make(map[string]*hostCircuit),
+	}
+}
+
+// Allow reports whether a request to host may proceed. Once the cooldown of
+// an open circuit has elapsed, exactly one probe is allowed until its outcome
+// is recorded.
+func (b *CircuitBreaker) Allow(host string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	circuit, ok := b.hosts[host]
+	if !ok || circuit.failures < b.config.FailureThreshold {
+		return true
+	}
+	if b.now().Before(circuit.openUntil) || circuit.probing {
+		return false
+	}
+	circuit.probing = true
+	return true
+}
+
+// Record reports the outcome of a request to host.
+func (b *CircuitBreaker) Record(host string, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		delete(b.hosts, host)
+		return
+	}
+
+	circuit, ok := b.hosts[host]
+	if !ok {
+		circuit = &hostCircuit{}
+		b.hosts[host] = circuit
+	}
+	circuit.failures++
+	circuit.probing = false
+	if circuit.failures >= b.config.FailureThreshold {
+		circuit.openUntil = b.now().Add(b.config.Cooldown)
+	}
+}
+
+// Release ends a probe allowed for host without recording an outcome, for
+// requests that were never sent or whose outcome says nothing about whether
+// the host is up.
+func (b *CircuitBreaker) Release(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if circuit, ok := b.hosts[host]; ok {
+		circuit.probing = false
+	}
+}
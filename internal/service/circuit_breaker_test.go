@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 3, Cooldown: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		breaker.Record("down.example.com", true)
+	}
+	if !breaker.Allow("down.example.com") {
+		t.Fatal("Expected circuit to stay closed below the threshold")
+	}
+
+	breaker.Record("down.example.com", true)
+	if breaker.Allow("down.example.com") {
+		t.Error("Expected circuit to open at the threshold")
+	}
+	if !breaker.Allow("up.example.com") {
+		t.Error("Expected other hosts to be unaffected")
+	}
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, Cooldown: time.Minute})
+
+	breaker.Record("example.com", true)
+	breaker.Record("example.com", false)
+	breaker.Record("example.com", true)
+
+	if !breaker.Allow("example.com") {
+		t.Error("Expected a success to reset the consecutive failure count")
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+
+	breaker.Record("example.com", true)
+	now = now.Add(2 * time.Minute)
+
+	if !breaker.Allow("example.com") {
+		t.Fatal("Expected a probe to be allowed after the cooldown")
+	}
+	if breaker.Allow("example.com") {
+		t.Error("Expected only one probe while the first is in flight")
+	}
+
+	breaker.Record("example.com", true)
+	if breaker.Allow("example.com") {
+		t.Error("Expected a failed probe to re-open the circuit")
+	}
+
+	now = now.Add(2 * time.Minute)
+	breaker.Allow("example.com")
+	breaker.Record("example.com", false)
+	if !breaker.Allow("example.com") {
+		t.Error("Expected a successful probe to close the circuit")
+	}
+}
+
+func TestCheckLink_UnsentProbeReleasesCircuit(t *testing.T) {
+	breaker := NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 1, Cooldown: time.Minute})
+	now := time.Now()
+	breaker.now = func() time.Time { return now }
+	service := &AnalysisService{
+		httpClient: &MockHTTPClient{DoFunc: func(_ *http.Request) (*http.Response, error) {
+			t.Error("Expected no request for an invalid URL")
+			return nil, errors.New("unexpected request")
+		}},
+		breaker: breaker,
+	}
+
+	// The request for an invalid URL cannot be built, so the probe is never sent.
+	link := "https://example.com/%zz"
+	breaker.Record(hostOf(link), true)
+	now = now.Add(2 * time.Minute)
+	if result := service.checkLink(context.Background(), link); result.Attempts != 0 || result.HostUnavailable {
+		t.Fatalf("Expected the probe to fail before sending, got %+v", result)
+	}
+
+	if !breaker.Allow(hostOf(link)) {
+		t.Error("Expected a probe that was never sent to leave the circuit half-open")
+	}
+}
+
+func TestAnalyzePage_HostUnavailable(t *testing.T) {
+	testHTML := `<!DOCTYPE html>
+<html>
+<head><title>Dead Host</title></head>
+<body>
+    <a href="https://down.example.org/1">1</a>
+    <a href="https://down.example.org/2">2</a>
+    <a href="https://down.example.org/3">3</a>
+    <a href="https://down.example.org/4">4</a>
+</body>
+</html>`
+
+	var linkRequests int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				return createMockResponse(200, testHTML), nil
+			}
+			atomic.AddInt64(&linkRequests, 1)
+			return nil, errors.New("connection refused")
+		},
+	}
+
+	service := &AnalysisService{
+		httpClient: mockClient,
+		scheduler:  NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 1, MaxPerHost: 1}),
+		breaker:    NewCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, Cooldown: time.Minute}),
+	}
+
+	result, err := service.AnalyzePage(context.Background(), "https://example.com/page")
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	if linkRequests != 2 {
+		t.Errorf("Expected checks to stop after 2 failures, got %d requests", linkRequests)
+	}
+	if result.InaccessibleExternalLinksCount != 2 {
+		t.Errorf("Expected 2 inaccessible links, got %d", result.InaccessibleExternalLinksCount)
+	}
+	if len(result.HostUnavailableLinks) != 2 {
+		t.Errorf("Expected 2 links reported as host unavailable, got %v", result.HostUnavailableLinks)
+	}
+}
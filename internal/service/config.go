@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+// LinkSchedulerConfigFromEnv reads the scheduler configuration from
+// LINK_CHECK_CONCURRENCY, LINK_CHECK_PER_HOST and LINK_CHECK_HOST_DELAY,
+// falling back to the defaults for anything unset.
+func LinkSchedulerConfigFromEnv() LinkSchedulerConfig {
+	config := DefaultLinkSchedulerConfig()
+	config.MaxConcurrent = envInt("LINK_CHECK_CONCURRENCY", config.MaxConcurrent)
+	config.MaxPerHost = envInt("LINK_CHECK_PER_HOST", config.MaxPerHost)
+	config.HostDelay = envDuration("LINK_CHECK_HOST_DELAY", config.HostDelay)
+	return config
+}
+
+// LinkCacheConfigFromEnv reads the link cache configuration from
+// LINK_CACHE_SUCCESS_TTL, LINK_CACHE_FAILURE_TTL, LINK_CACHE_ENTRIES,
+// LINK_CACHE_PATH and LINK_CACHE_FLUSH_INTERVAL, falling back to the defaults
+// for anything unset.
+func LinkCacheConfigFromEnv() LinkCacheConfig {
+	config := DefaultLinkCacheConfig()
+	config.SuccessTTL = envDuration("LINK_CACHE_SUCCESS_TTL", config.SuccessTTL)
+	config.FailureTTL = envDuration("LINK_CACHE_FAILURE_TTL", config.FailureTTL)
+	config.MaxEntries = envInt("LINK_CACHE_ENTRIES", config.MaxEntries)
+	config.Path = os.Getenv("LINK_CACHE_PATH")
+	config.FlushInterval = envDuration("LINK_CACHE_FLUSH_INTERVAL", config.FlushInterval)
+	return config
+}
+
+// FetchCacheConfigFromEnv reads the configuration of the cache of bodies
+// fetched for fragment and integrity checks from FETCH_CACHE_TTL and
+// FETCH_CACHE_MAX_BYTES, falling back to the defaults for anything unset.
+func FetchCacheConfigFromEnv() FetchCacheConfig {
+	config := DefaultFetchCacheConfig()
+	config.TTL = envDuration("FETCH_CACHE_TTL", config.TTL)
+	config.MaxBytes = int64(envInt("FETCH_CACHE_MAX_BYTES", int(config.MaxBytes)))
+	return config
+}
+
+// ResultCacheConfigFromEnv reads the result cache configuration from
+// RESULT_CACHE_ENTRIES, RESULT_CACHE_TTL, RESULT_CACHE_DIR and
+// RESULT_CACHE_DISK_MAX_BYTES, falling back to the defaults for anything unset.
+func ResultCacheConfigFromEnv() ResultCacheConfig {
+	config := DefaultResultCacheConfig()
+	config.MaxEntries = envInt("RESULT_CACHE_ENTRIES", config.MaxEntries)
+	config.TTL = envDuration("RESULT_CACHE_TTL", config.TTL)
+	config.Dir = os.Getenv("RESULT_CACHE_DIR")
+	config.MaxDiskBytes = int64(envInt("RESULT_CACHE_DISK_MAX_BYTES", int(config.MaxDiskBytes)))
+	return config
+}
+
+// RetryPolicyFromEnv reads the link check retry policy from
+// LINK_CHECK_RETRIES, LINK_CHECK_RETRY_BASE_DELAY and LINK_CHECK_RETRY_MAX_DELAY,
+// falling back to the defaults for anything unset. The delays must be
+// positive.
+func RetryPolicyFromEnv() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.MaxRetries = envInt("LINK_CHECK_RETRIES", policy.MaxRetries)
+	policy.BaseDelay = envPositiveDuration("LINK_CHECK_RETRY_BASE_DELAY", policy.BaseDelay)
+	policy.MaxDelay = envPositiveDuration("LINK_CHECK_RETRY_MAX_DELAY", policy.MaxDelay)
+	return policy
+}
+
+// TLSExpiryWindowFromEnv reads from TLS_EXPIRY_WARNING how close to expiry
+// certificates are reported, 30 days by default.
+func TLSExpiryWindowFromEnv() time.Duration {
+	return envDuration("TLS_EXPIRY_WARNING", defaultTLSExpiryWindow)
+}
+
+// CircuitBreakerConfigFromEnv reads the per-host circuit breaker configuration
+// from HOST_BREAKER_THRESHOLD and HOST_BREAKER_COOLDOWN, falling back to the
+// defaults for anything unset.
+func CircuitBreakerConfigFromEnv() CircuitBreakerConfig {
+	config := DefaultCircuitBreakerConfig()
+	config.FailureThreshold = envInt("HOST_BREAKER_THRESHOLD", config.FailureThreshold)
+	config.Cooldown = envDuration("HOST_BREAKER_COOLDOWN", config.Cooldown)
+	return config
+}
+
+// HistoryConfigFromEnv reads the history configuration from HISTORY_PATH,
+// HISTORY_MAX_AGE, HISTORY_MAX_ENTRIES and HISTORY_MAX_BATCH_ENTRIES, falling
+// back to the defaults for anything unset. Setting HISTORY_PATH to "none"
+// keeps history for the life of the process only; a relative path is
+// resolved against the working directory at startup.
+func HistoryConfigFromEnv() HistoryConfig {
+	config := DefaultHistoryConfig()
+	if path, ok := os.LookupEnv("HISTORY_PATH"); ok {
+		config.Path = path
+	}
+	if config.Path == "none" {
+		config.Path = ""
+	}
+	if config.Path != "" {
+		if path, err := filepath.Abs(config.Path); err == nil {
+			config.Path = path
+		}
+	}
+	config.MaxAge = envDuration("HISTORY_MAX_AGE", config.MaxAge)
+	config.MaxEntries = envInt("HISTORY_MAX_ENTRIES", config.MaxEntries)
+	config.MaxBatchEntries = envInt("HISTORY_MAX_BATCH_ENTRIES", config.MaxBatchEntries)
+	return config
+}
+
+// MonitorConfigFromEnv reads the monitor configuration from MONITORS_PATH,
+// MONITOR_WEBHOOK_URL and MONITOR_WEBHOOK_SECRET. Setting MONITORS_PATH to
+// "none" keeps monitors in memory only; a relative path is resolved against
+// the working directory at startup.
+func MonitorConfigFromEnv() MonitorConfig {
+	config := MonitorConfig{
+		Path:          dataPath(monitorsFileName),
+		WebhookURL:    os.Getenv("MONITOR_WEBHOOK_URL"),
+		WebhookSecret: os.Getenv("MONITOR_WEBHOOK_SECRET"),
+	}
+	if path, ok := os.LookupEnv("MONITORS_PATH"); ok {
+		config.Path = path
+	}
+	if config.Path == "none" {
+		config.Path = ""
+	}
+	if config.Path != "" {
+		if path, err := filepath.Abs(config.Path); err == nil {
+			config.Path = path
+		}
+	}
+	return config
+}
+
+// dataPath is name in $XDG_DATA_HOME/web-page-analyzer, falling back to
+// ~/.local/share and then to the temporary directory.
+func dataPath(name string) string {
+	dir := os.Getenv("XDG_DATA_HOME")
+	if dir == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, ".local", "share")
+		} else {
+			dir = os.TempDir()
+		}
+	}
+	return filepath.Join(dir, "web-page-analyzer", name)
+}
+
+// BatchConfigFromEnv reads the batch configuration from BATCH_CONCURRENCY,
+// BATCH_MAX_URLS, BATCH_RETAINED and BATCH_MAX_RUNNING, falling back to the
+// defaults for anything unset.
+func BatchConfigFromEnv() BatchConfig {
+	config := DefaultBatchConfig()
+	config.Concurrency = envInt("BATCH_CONCURRENCY", config.Concurrency)
+	config.MaxURLs = envInt("BATCH_MAX_URLS", config.MaxURLs)
+	config.Retained = envInt("BATCH_RETAINED", config.Retained)
+	config.MaxRunning = envInt("BATCH_MAX_RUNNING", config.MaxRunning)
+	return config
+}
+
+var sharedLinkScheduler = sync.OnceValue(func() *LinkScheduler {
+	return NewLinkScheduler(LinkSchedulerConfigFromEnv())
+})
+
+var sharedLinkCache = sync.OnceValue(func() *LinkCache {
+	cache, err := NewLinkCache(LinkCacheConfigFromEnv())
+	if err != nil {
+		logger.WithField("error", err).Error("Failed to load link cache, starting empty")
+		config := LinkCacheConfigFromEnv()
+		config.Path = ""
+		cache, _ = NewLinkCache(config)
+	}
+	return cache
+})
+
+var sharedFetchCache = sync.OnceValue(func() *FetchCache {
+	return NewFetchCache(FetchCacheConfigFromEnv())
+})
+
+var sharedCircuitBreaker = sync.OnceValue(func() *CircuitBreaker {
+	return NewCircuitBreaker(CircuitBreakerConfigFromEnv())
+})
+
+var sharedResultCache = sync.OnceValue(func() *ResultCache {
+	cache, err := NewResultCache(ResultCacheConfigFromEnv())
+	if err != nil {
+		logger.WithField("error", err).Error("Failed to open result cache directory, caching in memory only")
+		config := ResultCacheConfigFromEnv()
+		config.Dir = ""
+		cache, _ = NewResultCache(config)
+	}
+	return cache
+})
+
+var sharedHistory = sync.OnceValue(func() *HistoryStore {
+	store, err := NewHistoryStore(HistoryConfigFromEnv())
+	if err != nil {
+		logger.WithField("error", err).Error("Failed to open history database, keeping history for this process only")
+		config := HistoryConfigFromEnv()
+		config.Path = ""
+		store, _ = NewHistoryStore(config)
+	}
+	return store
+})
+
+var sharedMonitorRunner = sync.OnceValue(func() *MonitorRunner {
+	analyze := func(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error) {
+		return NewAnalysisService().AnalyzePageWithOptions(ctx, pageURL, AnalysisOptions{ForceRefresh: true})
+	}
+	config := MonitorConfigFromEnv()
+	if _, set := os.LookupEnv("MONITORS_PATH"); !set {
+		if _, err := os.Stat(config.Path); errors.Is(err, fs.ErrNotExist) {
+			if _, err := os.Stat(legacyMonitorsPath); err == nil {
+				logger.WithField("old_path", legacyMonitorsPath).WithField("path", config.Path).
+					Warn("Monitors at the old default path are not loaded; move them to the new path or set MONITORS_PATH")
+			}
+		}
+	}
+	runner, err := NewMonitorRunner(config, analyze)
+	if err != nil {
+		logger.WithField("error", err).Error("Failed to load monitors, starting with none")
+		config.Path = ""
+		runner, _ = NewMonitorRunner(config, analyze)
+	}
+	return runner
+})
+
+// SharedMonitorRunner returns the process-wide monitor runner.
+func SharedMonitorRunner() *MonitorRunner {
+	return sharedMonitorRunner()
+}
+
+var sharedBatchRunner = sync.OnceValue(func() *BatchRunner {
+	return NewBatchRunner(BatchConfigFromEnv(), func(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error) {
+		return NewAnalysisService().AnalyzePageWithOptions(ctx, pageURL, AnalysisOptions{Batch: true})
+	}).WithExtractor(func(ctx context.Context, pageURL, template string) (*PageExtraction, error) {
+		return NewAnalysisService().ExtractPage(ctx, pageURL, template)
+	})
+})
+
+// SharedBatchRunner returns the process-wide batch runner.
+func SharedBatchRunner() *BatchRunner {
+	return sharedBatchRunner()
+}
+
+// FlushLinkCache writes pending changes of the process-wide link cache to
+// LINK_CACHE_PATH, for use before the process exits.
+func FlushLinkCache() error {
+	return sharedLinkCache().Flush()
+}
+
+// SharedResultCacheStats reports the counters of the process-wide result cache.
+func SharedResultCacheStats() ResultCacheStats {
+	return sharedResultCache().Stats()
+}
+
+func envInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		logger.WithField("key", key).WithField("value", value).Warn("Ignoring invalid integer setting")
+		return fallback
+	}
+	return n
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		logger.WithField("key", key).WithField("value", value).Warn("Ignoring invalid duration setting")
+		return fallback
+	}
+	return d
+}
+
+// envPositiveDuration is envDuration for settings that must be above zero.
+func envPositiveDuration(key string, fallback time.Duration) time.Duration {
+	d := envDuration(key, fallback)
+	if d <= 0 {
+		logger.WithField("key", key).WithField("value", os.Getenv(key)).Warn("Ignoring non-positive duration setting")
+		return fallback
+	}
+	return d
+}
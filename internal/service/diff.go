@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+var (
+	// ErrHistoryNotFound is returned when a stored analysis does not exist or has expired.
+	ErrHistoryNotFound = errors.New("analysis not found")
+	// ErrDifferentURLs is returned when comparing analyses of two different pages.
+	ErrDifferentURLs = errors.New("analyses are of different URLs")
+)
+
+// DiffSide identifies one of the analyses being compared. ID is zero for a
+// fresh run that was not loaded from history.
+type DiffSide struct {
+	ID         int64     `json:"id,omitempty"`
+	AnalyzedAt time.Time `json:"analyzed_at"`
+}
+
+// Change is a value that differs between the two analyses.
+type Change[T comparable] struct {
+	From T `json:"from"`
+	To   T `json:"to"`
+}
+
+// HeadingChange is a change in the number of headings of one level.
+type HeadingChange struct {
+	Level string `json:"level"`
+	From  int    `json:"from"`
+	To    int    `json:"to"`
+}
+
+// MetaChange is a <meta> element added, removed or changed. From is empty
+// for added elements and To for removed ones.
+type MetaChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// AnalysisDiff describes what changed between two analyses of the same page.
+// Nil changes and empty lists mean nothing changed.
+type AnalysisDiff struct {
+	URL          string          `json:"url"`
+	Base         DiffSide        `json:"base"`
+	Target       DiffSide        `json:"target"`
+	Title        *Change[string] `json:"title,omitempty"`
+	HTMLVersion  *Change[string] `json:"html_version,omitempty"`
+	HasLoginForm *Change[bool]   `json:"has_login_form,omitempty"`
+	Headings     []HeadingChange `json:"headings,omitempty"`
+	Meta         []MetaChange    `json:"meta,omitempty"`
+	AddedLinks   []string        `json:"added_links,omitempty"`
+	RemovedLinks []string        `json:"removed_links,omitempty"`
+	NewlyBroken  []string        `json:"newly_broken,omitempty"`
+	NewlyFixed   []string        `json:"newly_fixed,omitempty"`
+	BrokenCount  Change[int]     `json:"broken_count"`
+	LinkCount    Change[int]     `json:"link_count"`
+	// AddedStructuredData and RemovedStructuredData are the JSON-LD blocks
+	// found in only one of the analyses. A changed block is both removed and
+	// added.
+	AddedStructuredData   []analyzer.StructuredData `json:"added_structured_data,omitempty"`
+	RemovedStructuredData []analyzer.StructuredData `json:"removed_structured_data,omitempty"`
+	// NewFindings and ResolvedFindings are the module findings reported by
+	// only one of the analyses.
+	NewFindings      []analyzer.Finding `json:"new_findings,omitempty"`
+	ResolvedFindings []analyzer.Finding `json:"resolved_findings,omitempty"`
+}
+
+// Empty reports whether the two analyses are equivalent.
+func (d *AnalysisDiff) Empty() bool {
+	return d.Title == nil && d.HTMLVersion == nil && d.HasLoginForm == nil &&
+		len(d.Headings) == 0 && len(d.Meta) == 0 && len(d.AddedLinks) == 0 && len(d.RemovedLinks) == 0 &&
+		len(d.NewlyBroken) == 0 && len(d.NewlyFixed) == 0 &&
+		len(d.AddedStructuredData) == 0 && len(d.RemovedStructuredData) == 0 &&
+		len(d.NewFindings) == 0 && len(d.ResolvedFindings) == 0
+}
+
+// DiffAnalyses compares base with target. A link is newly broken when it is
+// inaccessible in target but was not in base, and newly fixed when it was
+// inaccessible in base and was checked and found accessible in target. Links
+// whose host was unavailable in target are neither.
+func DiffAnalyses(base, target *AnalysisServiceResultDTO) *AnalysisDiff {
+	diff := &AnalysisDiff{
+		Title:        changed(base.Title, target.Title),
+		HTMLVersion:  changed(base.HTMLVersion, target.HTMLVersion),
+		HasLoginForm: changed(base.HasLoginForm, target.HasLoginForm),
+	}
+
+	levels := make(map[string]bool)
+	for level := range base.Headings {
+		levels[level] = true
+	}
+	for level := range target.Headings {
+		levels[level] = true
+	}
+	for level := range levels {
+		if from, to := base.Headings[level], target.Headings[level]; from != to {
+			diff.Headings = append(diff.Headings, HeadingChange{Level: level, From: from, To: to})
+		}
+	}
+	slices.SortFunc(diff.Headings, func(a, b HeadingChange) int { return strings.Compare(a.Level, b.Level) })
+
+	for name, from := range base.Meta {
+		if to, ok := target.Meta[name]; !ok || to != from {
+			diff.Meta = append(diff.Meta, MetaChange{Name: name, From: from, To: to})
+		}
+	}
+	for name, to := range target.Meta {
+		if _, ok := base.Meta[name]; !ok {
+			diff.Meta = append(diff.Meta, MetaChange{Name: name, To: to})
+		}
+	}
+	slices.SortFunc(diff.Meta, func(a, b MetaChange) int { return strings.Compare(a.Name, b.Name) })
+
+	diff.AddedStructuredData = unmatched(target.StructuredData, base.StructuredData)
+	diff.RemovedStructuredData = unmatched(base.StructuredData, target.StructuredData)
+	diff.NewFindings = unmatched(target.Findings, base.Findings)
+	diff.ResolvedFindings = unmatched(base.Findings, target.Findings)
+
+	baseLinks, targetLinks := linkSet(base), linkSet(target)
+	diff.AddedLinks = missingFrom(targetLinks, baseLinks)
+	diff.RemovedLinks = missingFrom(baseLinks, targetLinks)
+	diff.LinkCount = Change[int]{From: len(baseLinks), To: len(targetLinks)}
+
+	baseBroken, targetBroken := brokenSet(base), brokenSet(target)
+	diff.NewlyBroken = missingFrom(targetBroken, baseBroken)
+	unavailable := toSet(target.HostUnavailableLinks)
+	for _, link := range missingFrom(baseBroken, targetBroken) {
+		if !unavailable[link] && isCheckedLink(target, link) {
+			diff.NewlyFixed = append(diff.NewlyFixed, link)
+		}
+	}
+	diff.BrokenCount = Change[int]{From: len(baseBroken), To: len(targetBroken)}
+	return diff
+}
+
+// Compare diffs the stored analysis baseID against targetID, or against a
+// fresh analysis of the same page when targetID is zero.
+func (s *AnalysisService) Compare(ctx context.Context, baseID, targetID int64) (*AnalysisDiff, error) {
+	base, ok := s.HistoryRecord(baseID)
+	if !ok || base.Result == nil {
+		return nil, ErrHistoryNotFound
+	}
+
+	var target *AnalysisServiceResultDTO
+	targetSide := DiffSide{ID: targetID}
+	if targetID == 0 {
+		options := base.Options
+		options.ForceRefresh = true
+		fresh, err := s.AnalyzePageWithOptions(ctx, base.URL, options)
+		if err != nil {
+			return nil, err
+		}
+		target = fresh
+		targetSide.AnalyzedAt = fresh.AnalyzedAt
+	} else {
+		record, ok := s.HistoryRecord(targetID)
+		if !ok || record.Result == nil {
+			return nil, ErrHistoryNotFound
+		}
+		if normalizeLinkURL(record.URL) != normalizeLinkURL(base.URL) {
+			return nil, ErrDifferentURLs
+		}
+		target = record.Result
+		targetSide.AnalyzedAt = record.AnalyzedAt
+	}
+
+	diff := DiffAnalyses(base.Result, target)
+	diff.URL = base.URL
+	diff.Base = DiffSide{ID: base.ID, AnalyzedAt: base.AnalyzedAt}
+	diff.Target = targetSide
+	return diff, nil
+}
+
+func changed[T comparable](from, to T) *Change[T] {
+	if from == to {
+		return nil
+	}
+	return &Change[T]{From: from, To: to}
+}
+
+func linkSet(result *AnalysisServiceResultDTO) map[string]bool {
+	set := toSet(result.InternalLinks)
+	for _, link := range result.ExternalLinks {
+		set[link] = true
+	}
+	return set
+}
+
+func brokenSet(result *AnalysisServiceResultDTO) map[string]bool {
+	set := toSet(result.InaccessibleInternalLinks)
+	for _, link := range result.InaccessibleExternalLinks {
+		set[link] = true
+	}
+	return set
+}
+
+// isCheckedLink reports whether link was checked in result. Inaccessible
+// internal links are reported under the absolute URL that was checked, which
+// is not in InternalLinks.
+func isCheckedLink(result *AnalysisServiceResultDTO, link string) bool {
+	return slices.ContainsFunc(result.LinkResults, func(r LinkCheckResult) bool { return r.URL == link })
+}
+
+func toSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// unmatched returns the members of a, in order, that are not in b. Repeated
+// members are matched one for one.
+func unmatched[T comparable](a, b []T) []T {
+	remaining := make(map[T]int, len(b))
+	for _, item := range b {
+		remaining[item]++
+	}
+	var out []T
+	for _, item := range a {
+		if remaining[item] > 0 {
+			remaining[item]--
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// missingFrom returns the sorted members of a that are not in b.
+func missingFrom(a, b map[string]bool) []string {
+	var out []string
+	for item := range a {
+		if !b[item] {
+			out = append(out, item)
+		}
+	}
+	slices.Sort(out)
+	return out
+}
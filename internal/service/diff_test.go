@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+func TestDiffAnalyses(t *testing.T) {
+	base := &AnalysisServiceResultDTO{
+		AnalysisResult: analyzer.AnalysisResult{
+			Title:       "Old",
+			HTMLVersion: "HTML5",
+			Headings:    map[string]int{"h1": 1, "h2": 3},
+			Meta:        map[string]string{"description": "Old page", "robots": "noindex"},
+			StructuredData: []analyzer.StructuredData{
+				{Type: "Organization", JSON: `{"@type":"Organization","name":"Old"}`},
+				{Type: "WebSite", JSON: `{"@type":"WebSite"}`},
+			},
+			Findings: []analyzer.Finding{
+				{Module: "seo", Rule: "noindex", Severity: "warning", Message: "The page asks search engines not to index it"},
+				{Module: "seo", Rule: "h1-missing", Severity: "warning", Message: "The page has no h1 heading"},
+			},
+		},
+		InternalLinks:             []string{"/a", "/b"},
+		ExternalLinks:             []string{"https://x.example.com"},
+		InaccessibleInternalLinks: []string{"https://example.com/b"},
+		InaccessibleExternalLinks: []string{"https://x.example.com"},
+	}
+	target := &AnalysisServiceResultDTO{
+		AnalysisResult: analyzer.AnalysisResult{
+			Title:        "New",
+			HTMLVersion:  "HTML5",
+			HasLoginForm: true,
+			Headings:     map[string]int{"h1": 1, "h3": 2},
+			Meta:         map[string]string{"description": "New page", "og:title": "New"},
+			StructuredData: []analyzer.StructuredData{
+				{Type: "WebSite", JSON: `{"@type":"WebSite"}`},
+				{Type: "Organization", JSON: `{"@type":"Organization","name":"New"}`},
+			},
+			Findings: []analyzer.Finding{
+				{Module: "seo", Rule: "h1-missing", Severity: "warning", Message: "The page has no h1 heading"},
+				{Module: "seo", Rule: "canonical-missing", Severity: "info", Message: `The page declares no <link rel="canonical">`},
+			},
+		},
+		InternalLinks:             []string{"/a", "/b", "/c"},
+		ExternalLinks:             []string{"https://x.example.com"},
+		InaccessibleInternalLinks: []string{"https://example.com/a"},
+		HostUnavailableLinks:      []string{"https://x.example.com"},
+		LinkResults: []LinkCheckResult{
+			{URL: "https://example.com/a"},
+			{URL: "https://example.com/b", Accessible: true},
+			{URL: "https://example.com/c", Accessible: true},
+			{URL: "https://x.example.com", HostUnavailable: true},
+		},
+	}
+
+	diff := DiffAnalyses(base, target)
+
+	if diff.Title == nil || diff.Title.From != "Old" || diff.Title.To != "New" {
+		t.Errorf("Expected title change, got %+v", diff.Title)
+	}
+	if diff.HTMLVersion != nil {
+		t.Errorf("Expected no HTML version change, got %+v", diff.HTMLVersion)
+	}
+	if diff.HasLoginForm == nil || !diff.HasLoginForm.To {
+		t.Errorf("Expected login form change, got %+v", diff.HasLoginForm)
+	}
+	expectedHeadings := []HeadingChange{{"h2", 3, 0}, {"h3", 0, 2}}
+	if !slices.Equal(diff.Headings, expectedHeadings) {
+		t.Errorf("Expected heading changes %v, got %v", expectedHeadings, diff.Headings)
+	}
+	expectedMeta := []MetaChange{{"description", "Old page", "New page"}, {"og:title", "", "New"}, {"robots", "noindex", ""}}
+	if !slices.Equal(diff.Meta, expectedMeta) {
+		t.Errorf("Expected meta changes %v, got %v", expectedMeta, diff.Meta)
+	}
+	if len(diff.AddedStructuredData) != 1 || diff.AddedStructuredData[0].JSON != `{"@type":"Organization","name":"New"}` ||
+		len(diff.RemovedStructuredData) != 1 || diff.RemovedStructuredData[0].JSON != `{"@type":"Organization","name":"Old"}` {
+		t.Errorf("Expected the Organization block to change, got +%v -%v", diff.AddedStructuredData, diff.RemovedStructuredData)
+	}
+	if len(diff.NewFindings) != 1 || diff.NewFindings[0].Rule != "canonical-missing" ||
+		len(diff.ResolvedFindings) != 1 || diff.ResolvedFindings[0].Rule != "noindex" {
+		t.Errorf("Expected canonical-missing new and noindex resolved, got +%v -%v", diff.NewFindings, diff.ResolvedFindings)
+	}
+	if !slices.Equal(diff.AddedLinks, []string{"/c"}) || len(diff.RemovedLinks) != 0 {
+		t.Errorf("Expected /c added and nothing removed, got +%v -%v", diff.AddedLinks, diff.RemovedLinks)
+	}
+	if !slices.Equal(diff.NewlyBroken, []string{"https://example.com/a"}) {
+		t.Errorf("Expected /a newly broken, got %v", diff.NewlyBroken)
+	}
+	// x.example.com was not checked, so it is not reported as fixed.
+	if !slices.Equal(diff.NewlyFixed, []string{"https://example.com/b"}) {
+		t.Errorf("Expected only /b newly fixed, got %v", diff.NewlyFixed)
+	}
+	if diff.BrokenCount != (Change[int]{From: 2, To: 1}) || diff.LinkCount != (Change[int]{From: 3, To: 4}) {
+		t.Errorf("Unexpected counts: broken %+v, links %+v", diff.BrokenCount, diff.LinkCount)
+	}
+	if diff.Empty() {
+		t.Error("Expected diff not to be empty")
+	}
+	if !DiffAnalyses(base, base).Empty() {
+		t.Error("Expected diff of an analysis with itself to be empty")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	title := "First"
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			return createMockResponse(200, "<html><head><title>"+title+"</title></head></html>"), nil
+		},
+	}
+	service := &AnalysisService{
+		httpClient: mockClient,
+		history:    newTestHistoryStore(t, HistoryConfig{}),
+	}
+	ctx := context.Background()
+
+	service.AnalyzePage(ctx, "https://example.com")
+	title = "Second"
+	service.AnalyzePage(ctx, "https://EXAMPLE.com/")
+	service.AnalyzePage(ctx, "https://other.example.com")
+
+	diff, err := service.Compare(ctx, 1, 2)
+	if err != nil {
+		t.Fatalf("Compare() returned error: %v", err)
+	}
+	if diff.Base.ID != 1 || diff.Target.ID != 2 || diff.Title == nil || diff.Title.To != "Second" {
+		t.Errorf("Unexpected diff: %+v", diff)
+	}
+
+	title = "Third"
+	fresh, err := service.Compare(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Compare() against a fresh run returned error: %v", err)
+	}
+	if fresh.Target.ID != 0 || fresh.Title == nil || fresh.Title.To != "Third" {
+		t.Errorf("Expected comparison with a fresh run, got %+v", fresh)
+	}
+
+	if _, err := service.Compare(ctx, 1, 3); !errors.Is(err, ErrDifferentURLs) {
+		t.Errorf("Expected ErrDifferentURLs, got %v", err)
+	}
+	if _, err := service.Compare(ctx, 1, 99); !errors.Is(err, ErrHistoryNotFound) {
+		t.Errorf("Expected ErrHistoryNotFound, got %v", err)
+	}
+}
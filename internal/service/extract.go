@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
+)
+
+// ErrUnknownTemplate is returned for an extraction template that is not
+// loaded.
+var ErrUnknownTemplate = errors.New("unknown extraction template")
+
+// PageExtraction is the data extracted from one page with a template.
+type PageExtraction struct {
+	URL         string            `json:"url,omitempty"`
+	Template    string            `json:"template"`
+	Records     []analyzer.Record `json:"records"`
+	ExtractedAt time.Time         `json:"extracted_at"`
+}
+
+var extractionTemplates atomic.Pointer[map[string]*analyzer.ExtractionTemplate]
+
+// RegisterExtractionTemplates makes templates available by name, replacing
+// the templates registered before.
+func RegisterExtractionTemplates(templates []*analyzer.ExtractionTemplate) {
+	byName := make(map[string]*analyzer.ExtractionTemplate, len(templates))
+	for _, t := range templates {
+		byName[t.Name] = t
+	}
+	extractionTemplates.Store(&byName)
+}
+
+// LoadExtractionTemplatesFile reads and registers the templates file at path.
+func LoadExtractionTemplatesFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	templates, err := analyzer.ParseExtractionTemplates(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	RegisterExtractionTemplates(templates)
+	return nil
+}
+
+// LoadExtractionTemplates registers the templates file named by
+// EXTRACT_TEMPLATES_PATH, if any. A file that fails to load is logged and no
+// templates are registered.
+func LoadExtractionTemplates() {
+	path := os.Getenv("EXTRACT_TEMPLATES_PATH")
+	if path == "" {
+		return
+	}
+	if err := LoadExtractionTemplatesFile(path); err != nil {
+		logger.WithField("path", path).WithField("error", err).Error("Failed to load extraction templates, continuing without them")
+	}
+}
+
+// ExtractionTemplates lists the registered templates by name.
+func ExtractionTemplates() []*analyzer.ExtractionTemplate {
+	byName := extractionTemplates.Load()
+	if byName == nil {
+		return nil
+	}
+	templates := make([]*analyzer.ExtractionTemplate, 0, len(*byName))
+	for _, t := range *byName {
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates
+}
+
+// ExtractionTemplate returns the registered template called name.
+func ExtractionTemplate(name string) (*analyzer.ExtractionTemplate, error) {
+	if byName := extractionTemplates.Load(); byName != nil {
+		if t, ok := (*byName)[name]; ok {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownTemplate, name)
+}
+
+// ExtractPage fetches pageURL and extracts records from it with the named
+// template. Extractions are not cached, since partner pages change often.
+func (s *AnalysisService) ExtractPage(ctx context.Context, pageURL, templateName string) (*PageExtraction, error) {
+	template, err := ExtractionTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logger.ContextWithFields(ctx, logrus.Fields{"url": pageURL, "job_id": newID(), "template": templateName})
+	ctx, span := tracing.Start(ctx, "ExtractPage", trace.WithAttributes(
+		attribute.String("url.full", pageURL),
+		attribute.String("extract.template", templateName),
+	))
+	defer span.End()
+
+	response, err := s.fetchPage(ctx, pageURL)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	extraction, err := s.extract(ctx, response.Body, template, pageURL)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	extraction.URL = pageURL
+	return extraction, nil
+}
+
+// ExtractDocument extracts records from HTML read from body with the named
+// template. baseURL, if set, resolves URL fields.
+func (s *AnalysisService) ExtractDocument(ctx context.Context, body io.Reader, baseURL, templateName string) (*PageExtraction, error) {
+	template, err := ExtractionTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+	ctx, span := tracing.Start(ctx, "ExtractDocument", trace.WithAttributes(attribute.String("extract.template", templateName)))
+	defer span.End()
+	return s.extract(ctx, body, template, baseURL)
+}
+
+func (s *AnalysisService) extract(ctx context.Context, body io.Reader, template *analyzer.ExtractionTemplate, pageURL string) (*PageExtraction, error) {
+	start := time.Now()
+	records, err := analyzer.Extract(ctx, body, template, pageURL)
+	observePhase(ctx, phaseParse, start)
+	if err != nil {
+		return nil, err
+	}
+	logger.WithContext(ctx).WithField("records", len(records)).Info("Extraction completed")
+	return &PageExtraction{Template: template.Name, Records: records, ExtractedAt: time.Now().UTC()}, nil
+}
+
+// WriteExtractionJSONL writes every record of extractions as one JSON object
+// per line, with the page URL, if known, under "page_url" unless a field has
+// that name.
+func WriteExtractionJSONL(w io.Writer, extractions []PageExtraction) error {
+	encoder := json.NewEncoder(w)
+	for _, extraction := range extractions {
+		for _, record := range extraction.Records {
+			line := make(analyzer.Record, len(record)+1)
+			if extraction.URL != "" {
+				line["page_url"] = extraction.URL
+			}
+			for name, value := range record {
+				line[name] = value
+			}
+			if err := encoder.Encode(line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteExtractionCSV writes every record of extractions as a row with a
+// page_url column followed by fields. Lists are joined with "; ".
+func WriteExtractionCSV(w io.Writer, fields []string, extractions []PageExtraction) error {
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{"page_url"}, fields...))
+	for _, extraction := range extractions {
+		for _, record := range extraction.Records {
+			row := []string{csvSafe(extraction.URL)}
+			for _, field := range fields {
+				row = append(row, csvSafe(recordCell(record[field])))
+			}
+			writer.Write(row)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func recordCell(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, "; ")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+func registerTestTemplates(t *testing.T) *analyzer.ExtractionTemplate {
+	t.Helper()
+	templates, err := analyzer.ParseExtractionTemplates(strings.NewReader(`{"templates": [
+		{"name": "products", "items": "li.product", "fields": [
+			{"name": "name", "selector": "a"},
+			{"name": "url", "selector": "a", "attribute": "href", "url": true},
+			{"name": "tags", "selector": "em", "all": true}
+		]}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseExtractionTemplates() returned error: %v", err)
+	}
+	RegisterExtractionTemplates(templates)
+	t.Cleanup(func() { RegisterExtractionTemplates(nil) })
+	return templates[0]
+}
+
+const productsHTML = `<ul><li class="product"><a href="/a">A</a><em>new</em><em>sale</em></li><li class="product"><a href="/b">=B</a></li></ul>`
+
+func TestExtractPage(t *testing.T) {
+	registerTestTemplates(t)
+	service := &AnalysisService{httpClient: &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(200, productsHTML), nil
+		},
+	}}
+
+	extraction, err := service.ExtractPage(context.Background(), "https://shop.example.com/list", "products")
+	if err != nil {
+		t.Fatalf("ExtractPage() returned error: %v", err)
+	}
+	if extraction.URL != "https://shop.example.com/list" || extraction.Template != "products" || len(extraction.Records) != 2 {
+		t.Fatalf("Unexpected extraction %+v", extraction)
+	}
+	if extraction.Records[0]["url"] != "https://shop.example.com/a" {
+		t.Errorf("Expected the link resolved against the page URL, got %v", extraction.Records[0]["url"])
+	}
+
+	if _, err := service.ExtractPage(context.Background(), "https://shop.example.com/list", "missing"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Expected ErrUnknownTemplate, got %v", err)
+	}
+}
+
+func TestWriteExtractions(t *testing.T) {
+	template := registerTestTemplates(t)
+	extraction, err := (&AnalysisService{}).ExtractDocument(context.Background(), strings.NewReader(productsHTML), "https://shop.example.com/list", "products")
+	if err != nil {
+		t.Fatalf("ExtractDocument() returned error: %v", err)
+	}
+	extraction.URL = "https://shop.example.com/list"
+	extractions := []PageExtraction{*extraction}
+
+	var jsonl bytes.Buffer
+	if err := WriteExtractionJSONL(&jsonl, extractions); err != nil {
+		t.Fatalf("WriteExtractionJSONL() returned error: %v", err)
+	}
+	expectedJSONL := `{"name":"A","page_url":"https://shop.example.com/list","tags":["new","sale"],"url":"https://shop.example.com/a"}
+{"name":"=B","page_url":"https://shop.example.com/list","tags":[],"url":"https://shop.example.com/b"}
+`
+	if jsonl.String() != expectedJSONL {
+		t.Errorf("Expected JSON Lines\n%s\ngot\n%s", expectedJSONL, jsonl.String())
+	}
+
+	var csv bytes.Buffer
+	if err := WriteExtractionCSV(&csv, template.FieldNames(), extractions); err != nil {
+		t.Fatalf("WriteExtractionCSV() returned error: %v", err)
+	}
+	expectedCSV := `page_url,name,url,tags
+https://shop.example.com/list,A,https://shop.example.com/a,new; sale
+https://shop.example.com/list,'=B,https://shop.example.com/b,
+`
+	if csv.String() != expectedCSV {
+		t.Errorf("Expected CSV\n%s\ngot\n%s", expectedCSV, csv.String())
+	}
+}
+
+func TestBatchRunner_Extraction(t *testing.T) {
+	registerTestTemplates(t)
+	runner := NewBatchRunner(BatchConfig{Concurrency: 2}, nil).WithExtractor(func(_ context.Context, pageURL, template string) (*PageExtraction, error) {
+		if strings.Contains(pageURL, "down") {
+			return nil, errors.New("connection refused")
+		}
+		return &PageExtraction{URL: pageURL, Template: template, Records: []analyzer.Record{{"name": "A"}, {"name": "B"}}}, nil
+	})
+
+	if _, err := runner.StartExtraction(context.Background(), []string{"https://example.com/1"}, "missing"); !errors.Is(err, ErrUnknownTemplate) {
+		t.Errorf("Expected ErrUnknownTemplate, got %v", err)
+	}
+	started, err := runner.StartExtraction(context.Background(), []string{"https://example.com/1", "https://down.example.com", "https://example.com/2"}, "products")
+	if err != nil {
+		t.Fatalf("StartExtraction() returned error: %v", err)
+	}
+	batch := waitForBatch(t, runner, started.ID)
+	if batch.Template != "products" || len(batch.Fields) != 3 {
+		t.Errorf("Expected the template and its fields on the batch, got %q %v", batch.Template, batch.Fields)
+	}
+	if batch.Summary.Succeeded != 2 || batch.Summary.Failed != 1 || batch.Summary.Records != 4 {
+		t.Errorf("Unexpected summary %+v", batch.Summary)
+	}
+	extractions := batch.Extractions()
+	if len(extractions) != 2 || extractions[1].URL != "https://example.com/2" {
+		t.Errorf("Expected the extractions of the succeeded pages, got %+v", extractions)
+	}
+}
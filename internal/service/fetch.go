@@ -0,0 +1,265 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
+)
+
+const (
+	// maxFetchSize bounds the bodies fetched for fragment and integrity checks.
+	maxFetchSize = 10 << 20
+
+	defaultFetchCacheTTL      = 10 * time.Minute
+	defaultFetchCacheMaxBytes = 32 << 20
+)
+
+// fetchKind keeps GETs apart from link checks in the link scheduler.
+const fetchKind = http.MethodGet
+
+// FetchCacheConfig controls how long the bodies fetched for fragment and
+// integrity checks are reused.
+type FetchCacheConfig struct {
+	// TTL is how long a body is served without revalidation.
+	TTL time.Duration
+	// MaxBytes caps the size of the cached bodies; the least recently used
+	// are evicted first.
+	MaxBytes int64
+}
+
+// DefaultFetchCacheConfig returns the configuration used when none is provided.
+func DefaultFetchCacheConfig() FetchCacheConfig {
+	return FetchCacheConfig{
+		TTL:      defaultFetchCacheTTL,
+		MaxBytes: defaultFetchCacheMaxBytes,
+	}
+}
+
+// fetchedBody is the body of a successful GET together with the validators
+// needed to revalidate it once it goes stale.
+type fetchedBody struct {
+	body         []byte
+	contentType  string
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+}
+
+// FetchCache keeps the bodies of recent GETs in memory, keyed by normalized
+// URL.
+type FetchCache struct {
+	config FetchCacheConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // most recently used first
+	size    int64
+}
+
+type fetchCacheItem struct {
+	key     string
+	fetched fetchedBody
+}
+
+// NewFetchCache returns an empty cache.
+func NewFetchCache(config FetchCacheConfig) *FetchCache {
+	return &FetchCache{
+		config:  config,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the cached body of link, whether one was found and whether it
+// is still fresh.
+func (c *FetchCache) Get(link string) (fetchedBody, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[normalizeLinkURL(link)]
+	if !ok {
+		return fetchedBody{}, false, false
+	}
+	c.order.MoveToFront(element)
+	body := element.Value.(*fetchCacheItem).fetched
+	return body, true, c.now().Sub(body.fetchedAt) < c.config.TTL
+}
+
+// Put stores the body of link, evicting the least recently used bodies to
+// stay within MaxBytes. Bodies larger than MaxBytes are not cached.
+func (c *FetchCache) Put(link string, body fetchedBody) {
+	key := normalizeLinkURL(link)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	if int64(len(body.body)) > c.config.MaxBytes {
+		return
+	}
+	c.entries[key] = c.order.PushFront(&fetchCacheItem{key: key, fetched: body})
+	c.size += int64(len(body.body))
+	for c.size > c.config.MaxBytes {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *FetchCache) remove(element *list.Element) {
+	item := c.order.Remove(element).(*fetchCacheItem)
+	delete(c.entries, item.key)
+	c.size -= int64(len(item.fetched.body))
+}
+
+// fetchAll GETs links through the link scheduler, so that they share its
+// concurrency and per-host limits with link checks, and returns the outcome
+// of each in order.
+func (s *AnalysisService) fetchAll(ctx context.Context, links []string) []LinkCheckResult {
+	scheduler := s.scheduler
+	if scheduler == nil {
+		scheduler = sharedLinkScheduler()
+	}
+	results := scheduler.schedule(ctx, fetchKind, links, s.fetch)
+	for _, result := range results {
+		if result.Cache != "" {
+			fetchCacheLookups.WithLabelValues(result.Cache).Inc()
+		}
+	}
+	return results
+}
+
+// fetch GETs a single link inside a "link.fetch" span.
+func (s *AnalysisService) fetch(ctx context.Context, link string) LinkCheckResult {
+	ctx, span := tracing.Start(ctx, "link.fetch", trace.WithAttributes(attribute.String("url.full", link)))
+	defer span.End()
+
+	result := s.doFetch(ctx, link)
+	span.SetAttributes(attribute.Int("link.attempts", result.Attempts))
+	if result.StatusCode != 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+	}
+	if result.Cache != "" {
+		span.SetAttributes(attribute.String("link.cache", result.Cache))
+	}
+	if result.Error != "" {
+		span.SetStatus(codes.Error, result.Error)
+	}
+	return result
+}
+
+// doFetch GETs link, serving fresh bodies from the fetch cache and
+// revalidating stale ones with their ETag or Last-Modified validators. The
+// result is accessible, with its body set, only for a 2xx response of at most
+// maxFetchSize bytes.
+func (s *AnalysisService) doFetch(ctx context.Context, link string) LinkCheckResult {
+	var cached fetchedBody
+	var found bool
+	if s.fetchCache != nil {
+		var fresh bool
+		cached, found, fresh = s.fetchCache.Get(link)
+		if fresh {
+			return fetchedResult(link, cached, CacheHit)
+		}
+	}
+
+	result := LinkCheckResult{URL: link, FinalURL: link, CheckedAt: time.Now()}
+	if s.fetchCache != nil {
+		result.Cache = CacheMiss
+	}
+
+	host := hostOf(link)
+	if s.breaker != nil && !s.breaker.Allow(host) {
+		result.HostUnavailable = true
+		result.Error = errHostUnavailable
+		return result
+	}
+
+	resp, attempts, err := s.retry.do(ctx, s.httpClient, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			if cached.etag != "" {
+				req.Header.Set("If-None-Match", cached.etag)
+			}
+			if cached.lastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.lastModified)
+			}
+		}
+		return req, nil
+	})
+	result.Attempts = attempts
+	if attempts == 0 {
+		if s.breaker != nil {
+			s.breaker.Release(host)
+		}
+		result.Error = err.Error()
+		return result
+	}
+	s.recordHost(host, resp, err)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+
+	if found && resp.StatusCode == http.StatusNotModified {
+		cached.fetchedAt = result.CheckedAt
+		s.fetchCache.Put(link, cached)
+		return fetchedResult(link, cached, CacheRevalidated)
+	}
+
+	result.StatusCode = resp.StatusCode
+	if resp.Request != nil && resp.Request.URL != nil {
+		result.FinalURL = resp.Request.URL.String()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.Error = fmt.Sprintf("status %d", resp.StatusCode)
+		return result
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchSize+1))
+	if err == nil && len(body) > maxFetchSize {
+		err = fmt.Errorf("larger than %d bytes", maxFetchSize)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	fetched := fetchedBody{
+		body:         body,
+		contentType:  resp.Header.Get("Content-Type"),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		fetchedAt:    result.CheckedAt,
+	}
+	if s.fetchCache != nil {
+		s.fetchCache.Put(link, fetched)
+	}
+	result.Accessible = true
+	result.body = &fetched
+	return result
+}
+
+func fetchedResult(link string, body fetchedBody, cache string) LinkCheckResult {
+	return LinkCheckResult{
+		URL:        link,
+		Accessible: true,
+		StatusCode: http.StatusOK,
+		FinalURL:   link,
+		CheckedAt:  body.fetchedAt,
+		Cache:      cache,
+		body:       &body,
+	}
+}
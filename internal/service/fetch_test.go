@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFetchAll_CachesAndRevalidatesBodies(t *testing.T) {
+	var gets, revalidations int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt64(&gets, 1)
+			if req.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt64(&revalidations, 1)
+				return createMockResponse(http.StatusNotModified, ""), nil
+			}
+			resp := createMockResponse(200, "console.log(1)")
+			resp.Header.Set("ETag", `"v1"`)
+			return resp, nil
+		},
+	}
+	cache := NewFetchCache(DefaultFetchCacheConfig())
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	service := &AnalysisService{httpClient: mockClient, fetchCache: cache}
+	link := "https://cdn.example.com/app.js"
+
+	for _, expected := range []string{CacheMiss, CacheHit} {
+		result := service.fetchAll(context.Background(), []string{link})[0]
+		if result.Cache != expected || result.body == nil || string(result.body.body) != "console.log(1)" {
+			t.Fatalf("Expected a %s with the body, got %+v", expected, result)
+		}
+	}
+	if gets != 1 {
+		t.Errorf("Expected a fresh body to be served from the cache, got %d GETs", gets)
+	}
+
+	now = now.Add(DefaultFetchCacheConfig().TTL + time.Second)
+	result := service.fetchAll(context.Background(), []string{link})[0]
+	if result.Cache != CacheRevalidated || result.body == nil || string(result.body.body) != "console.log(1)" {
+		t.Errorf("Expected a stale body to be revalidated, got %+v", result)
+	}
+	if revalidations != 1 {
+		t.Errorf("Expected 1 conditional GET, got %d", revalidations)
+	}
+}
+
+func TestFetchAll_UsesPerHostLimit(t *testing.T) {
+	var mu sync.Mutex
+	var active, peak int
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			mu.Lock()
+			active++
+			peak = max(peak, active)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			return createMockResponse(200, "body"), nil
+		},
+	}
+	service := &AnalysisService{
+		httpClient: mockClient,
+		scheduler:  NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 8, MaxPerHost: 1}),
+	}
+
+	links := []string{"https://example.com/a.js", "https://example.com/b.js", "https://example.com/c.js"}
+	for i, result := range service.fetchAll(context.Background(), links) {
+		if result.URL != links[i] || result.body == nil {
+			t.Errorf("Expected result %d to be a body for %s, got %+v", i, links[i], result)
+		}
+	}
+	if peak != 1 {
+		t.Errorf("Expected at most 1 GET to the host at a time, got %d", peak)
+	}
+}
+
+func TestFetchAll_ReportsFailures(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(404, "not found"), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient, fetchCache: NewFetchCache(DefaultFetchCacheConfig())}
+
+	result := service.fetchAll(context.Background(), []string{"https://example.com/gone.js"})[0]
+	if result.body != nil || result.Accessible || result.Error != "status 404" {
+		t.Errorf("Expected a 404 without a body, got %+v", result)
+	}
+	if _, found, _ := service.fetchCache.Get("https://example.com/gone.js"); found {
+		t.Error("Expected failed fetches not to be cached")
+	}
+}
+
+func TestFetchCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewFetchCache(FetchCacheConfig{TTL: time.Minute, MaxBytes: 10})
+	now := time.Now()
+	cache.Put("https://example.com/a", fetchedBody{body: []byte("aaaa"), fetchedAt: now})
+	cache.Put("https://example.com/b", fetchedBody{body: []byte("bbbb"), fetchedAt: now})
+	cache.Get("https://example.com/a")
+	cache.Put("https://example.com/c", fetchedBody{body: []byte("cccc"), fetchedAt: now})
+	cache.Put("https://example.com/big", fetchedBody{body: []byte("too large body"), fetchedAt: now})
+
+	for link, expected := range map[string]bool{
+		"https://example.com/a":   true,
+		"https://example.com/b":   false,
+		"https://example.com/c":   true,
+		"https://example.com/big": false,
+	} {
+		if _, found, _ := cache.Get(link); found != expected {
+			t.Errorf("Expected %s cached to be %v, got %v", link, expected, found)
+		}
+	}
+}
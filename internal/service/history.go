@@ -0,0 +1,383 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	defaultHistoryMaxAge          = 30 * 24 * time.Hour
+	defaultHistoryMaxEntries      = 1000
+	defaultHistoryMaxBatchEntries = 1000
+	historyFileName               = "history.db"
+	historyOpenTimeout            = 5 * time.Second
+)
+
+// Buckets of the history database. Records holds every record without its
+// result, keyed by ID; results holds the results under the same keys. The
+// byURL and bySource buckets index record IDs by normalized URL and by
+// source, with keys made of the indexed value, a NUL byte and the ID.
+var (
+	historyRecordsBucket  = []byte("records")
+	historyResultsBucket  = []byte("results")
+	historyByURLBucket    = []byte("by_url")
+	historyBySourceBucket = []byte("by_source")
+)
+
+// History record sources. Batch analyses are capped separately so that a
+// large batch does not push other analyses out of the history.
+const (
+	HistorySourceAnalysis = "analysis"
+	HistorySourceBatch    = "batch"
+)
+
+// HistoryConfig controls where past analyses are stored and for how long.
+type HistoryConfig struct {
+	// Path is the database file records are stored in. Empty keeps history
+	// for the life of the process only.
+	Path string
+	// MaxAge drops records older than this. Zero keeps records of any age.
+	MaxAge time.Duration
+	// MaxEntries caps the number of records of analyses run outside
+	// batches, oldest first. Zero means no cap.
+	MaxEntries int
+	// MaxBatchEntries caps the number of records of batch analyses, oldest
+	// first. Zero means no cap.
+	MaxBatchEntries int
+}
+
+// DefaultHistoryConfig returns the configuration used when none is provided.
+func DefaultHistoryConfig() HistoryConfig {
+	return HistoryConfig{
+		Path:            dataPath(historyFileName),
+		MaxAge:          defaultHistoryMaxAge,
+		MaxEntries:      defaultHistoryMaxEntries,
+		MaxBatchEntries: defaultHistoryMaxBatchEntries,
+	}
+}
+
+// HistorySummary holds the headline numbers of a stored analysis.
+type HistorySummary struct {
+	Title                string `json:"title"`
+	HTMLVersion          string `json:"html_version"`
+	HasLoginForm         bool   `json:"has_login_form"`
+	InternalLinks        int    `json:"internal_links"`
+	ExternalLinks        int    `json:"external_links"`
+	InaccessibleLinks    int    `json:"inaccessible_links"`
+	HostUnavailableLinks int    `json:"host_unavailable_links"`
+}
+
+// HistoryRecord is one stored analysis.
+type HistoryRecord struct {
+	ID         int64                     `json:"id"`
+	URL        string                    `json:"url"`
+	Source     string                    `json:"source"`
+	AnalyzedAt time.Time                 `json:"analyzed_at"`
+	Options    AnalysisOptions           `json:"options"`
+	Summary    HistorySummary            `json:"summary"`
+	Result     *AnalysisServiceResultDTO `json:"result,omitempty"`
+}
+
+// HistoryFilter selects records returned by HistoryStore.List.
+type HistoryFilter struct {
+	// URL restricts results to analyses of this page. It is compared after
+	// normalization, so case and default ports do not matter.
+	URL string
+	// Since and Until bound AnalyzedAt when non-zero.
+	Since time.Time
+	Until time.Time
+	// Limit caps the number of records returned when positive.
+	Limit int
+}
+
+// HistoryStore keeps past analyses in a bbolt database. Records are keyed by
+// increasing IDs, so walking them newest first also walks them by time, and
+// are indexed by URL so that the history of one page is read without
+// scanning the others.
+type HistoryStore struct {
+	config HistoryConfig
+	now    func() time.Time
+	db     *bolt.DB
+}
+
+// NewHistoryStore opens the history database, creating it if needed, and
+// applies the retention policy to the records it contains.
+func NewHistoryStore(config HistoryConfig) (*HistoryStore, error) {
+	path := config.Path
+	temporary := path == ""
+	if temporary {
+		file, err := os.CreateTemp("", "history-*.db")
+		if err != nil {
+			return nil, err
+		}
+		file.Close()
+		path = file.Name()
+	} else if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: historyOpenTimeout})
+	if temporary {
+		// The open database outlives its directory entry.
+		os.Remove(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := &HistoryStore{config: config, now: time.Now, db: db}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{historyRecordsBucket, historyResultsBucket, historyByURLBucket, historyBySourceBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return h.prune(tx)
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// Close closes the history database.
+func (h *HistoryStore) Close() error {
+	return h.db.Close()
+}
+
+// Add stores an analysis of pageURL and returns the new record without its result.
+func (h *HistoryStore) Add(pageURL string, options AnalysisOptions, result *AnalysisServiceResultDTO) (HistoryRecord, error) {
+	stored := *result
+	stored.FromCache = false
+	stored.CacheAge = 0
+
+	record := HistoryRecord{
+		URL:        pageURL,
+		Source:     HistorySourceAnalysis,
+		AnalyzedAt: h.now(),
+		Options:    options,
+		Summary:    summarize(&stored),
+	}
+	if options.Batch {
+		record.Source = HistorySourceBatch
+	}
+	encodedResult, err := json.Marshal(&stored)
+	if err != nil {
+		return HistoryRecord{}, err
+	}
+
+	err = h.db.Update(func(tx *bolt.Tx) error {
+		records := tx.Bucket(historyRecordsBucket)
+		id, err := records.NextSequence()
+		if err != nil {
+			return err
+		}
+		record.ID = int64(id)
+		encodedRecord, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		key := historyKey(record.ID)
+		if err := records.Put(key, encodedRecord); err != nil {
+			return err
+		}
+		if err := tx.Bucket(historyResultsBucket).Put(key, encodedResult); err != nil {
+			return err
+		}
+		if err := tx.Bucket(historyByURLBucket).Put(historyIndexKey(normalizeLinkURL(pageURL), record.ID), nil); err != nil {
+			return err
+		}
+		if err := tx.Bucket(historyBySourceBucket).Put(historyIndexKey(record.Source, record.ID), nil); err != nil {
+			return err
+		}
+		return h.prune(tx)
+	})
+	if err != nil {
+		return HistoryRecord{}, err
+	}
+	return record, nil
+}
+
+// List returns the records matching filter, newest first, without their results.
+func (h *HistoryStore) List(filter HistoryFilter) []HistoryRecord {
+	var out []HistoryRecord
+	h.db.View(func(tx *bolt.Tx) error {
+		records := tx.Bucket(historyRecordsBucket)
+		ids := h.newestFirst(tx, filter.URL)
+		for key, ok := ids(); ok; key, ok = ids() {
+			var record HistoryRecord
+			if err := json.Unmarshal(records.Get(key), &record); err != nil {
+				continue
+			}
+			// IDs increase with time, so no older record can match.
+			if h.expired(record) || (!filter.Since.IsZero() && record.AnalyzedAt.Before(filter.Since)) {
+				break
+			}
+			if !filter.Until.IsZero() && record.AnalyzedAt.After(filter.Until) {
+				continue
+			}
+			out = append(out, record)
+			if filter.Limit > 0 && len(out) == filter.Limit {
+				break
+			}
+		}
+		return nil
+	})
+	return out
+}
+
+// Get returns the record with the given ID, including its result.
+func (h *HistoryStore) Get(id int64) (HistoryRecord, bool) {
+	var record HistoryRecord
+	var found bool
+	h.db.View(func(tx *bolt.Tx) error {
+		key := historyKey(id)
+		data := tx.Bucket(historyRecordsBucket).Get(key)
+		if data == nil || json.Unmarshal(data, &record) != nil || h.expired(record) {
+			return nil
+		}
+		var result AnalysisServiceResultDTO
+		if err := json.Unmarshal(tx.Bucket(historyResultsBucket).Get(key), &result); err != nil {
+			return nil
+		}
+		record.Result = &result
+		found = true
+		return nil
+	})
+	return record, found
+}
+
+// newestFirst returns an iterator over the keys of the records of pageURL,
+// or of every record when pageURL is empty, newest first. The iterator is
+// only valid within tx.
+func (h *HistoryStore) newestFirst(tx *bolt.Tx, pageURL string) func() ([]byte, bool) {
+	if pageURL == "" {
+		cursor := tx.Bucket(historyRecordsBucket).Cursor()
+		key, _ := cursor.Last()
+		return func() ([]byte, bool) {
+			if key == nil {
+				return nil, false
+			}
+			current := key
+			key, _ = cursor.Prev()
+			return current, true
+		}
+	}
+
+	prefix := historyIndexPrefix(normalizeLinkURL(pageURL))
+	cursor := tx.Bucket(historyByURLBucket).Cursor()
+	// Seek past the last key with the prefix and step back onto it.
+	key, _ := cursor.Seek(append(bytes.Clone(prefix), 0xff))
+	if key == nil {
+		key, _ = cursor.Last()
+	} else {
+		key, _ = cursor.Prev()
+	}
+	return func() ([]byte, bool) {
+		if key == nil || !bytes.HasPrefix(key, prefix) {
+			return nil, false
+		}
+		current := key[len(prefix):]
+		key, _ = cursor.Prev()
+		return current, true
+	}
+}
+
+// prune deletes records older than MaxAge and those of each source beyond
+// its cap.
+func (h *HistoryStore) prune(tx *bolt.Tx) error {
+	var drop [][]byte
+	if h.config.MaxAge > 0 {
+		cursor := tx.Bucket(historyRecordsBucket).Cursor()
+		for key, data := cursor.First(); key != nil; key, data = cursor.Next() {
+			var record HistoryRecord
+			if json.Unmarshal(data, &record) == nil && !h.expired(record) {
+				break
+			}
+			drop = append(drop, bytes.Clone(key))
+		}
+	}
+	caps := map[string]int{HistorySourceAnalysis: h.config.MaxEntries, HistorySourceBatch: h.config.MaxBatchEntries}
+	for source, limit := range caps {
+		if limit <= 0 {
+			continue
+		}
+		prefix := historyIndexPrefix(source)
+		var ids [][]byte
+		cursor := tx.Bucket(historyBySourceBucket).Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			ids = append(ids, bytes.Clone(key[len(prefix):]))
+		}
+		if len(ids) > limit {
+			drop = append(drop, ids[:len(ids)-limit]...)
+		}
+	}
+
+	for _, key := range drop {
+		if err := h.delete(tx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// delete removes the record stored under key together with its result and
+// index entries.
+func (h *HistoryStore) delete(tx *bolt.Tx, key []byte) error {
+	records := tx.Bucket(historyRecordsBucket)
+	data := records.Get(key)
+	if data == nil {
+		return nil
+	}
+	var record HistoryRecord
+	if err := json.Unmarshal(data, &record); err == nil {
+		id := int64(binary.BigEndian.Uint64(key))
+		if err := tx.Bucket(historyByURLBucket).Delete(historyIndexKey(normalizeLinkURL(record.URL), id)); err != nil {
+			return err
+		}
+		if err := tx.Bucket(historyBySourceBucket).Delete(historyIndexKey(record.Source, id)); err != nil {
+			return err
+		}
+	}
+	if err := tx.Bucket(historyResultsBucket).Delete(key); err != nil {
+		return err
+	}
+	return records.Delete(key)
+}
+
+func (h *HistoryStore) expired(record HistoryRecord) bool {
+	return h.config.MaxAge > 0 && h.now().Sub(record.AnalyzedAt) >= h.config.MaxAge
+}
+
+// historyKey encodes id so that keys sort in ID order.
+func historyKey(id int64) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(id))
+}
+
+func historyIndexPrefix(value string) []byte {
+	return append([]byte(value), 0)
+}
+
+func historyIndexKey(value string, id int64) []byte {
+	return append(historyIndexPrefix(value), historyKey(id)...)
+}
+
+func summarize(result *AnalysisServiceResultDTO) HistorySummary {
+	return HistorySummary{
+		Title:                result.Title,
+		HTMLVersion:          result.HTMLVersion,
+		HasLoginForm:         result.HasLoginForm,
+		InternalLinks:        result.InternalLinksCount,
+		ExternalLinks:        result.ExternalLinksCount,
+		InaccessibleLinks:    result.InaccessibleInternalLinksCount + result.InaccessibleExternalLinksCount,
+		HostUnavailableLinks: len(result.HostUnavailableLinks),
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+func newTestHistoryStore(t *testing.T, config HistoryConfig) *HistoryStore {
+	t.Helper()
+	store, err := NewHistoryStore(config)
+	if err != nil {
+		t.Fatalf("NewHistoryStore() returned error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestHistoryStore_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "history.db")
+	store := newTestHistoryStore(t, HistoryConfig{Path: path})
+
+	result := &AnalysisServiceResultDTO{
+		AnalysisResult:                 analyzer.AnalysisResult{Title: "Example"},
+		InternalLinksCount:             3,
+		InaccessibleExternalLinksCount: 1,
+	}
+	added, err := store.Add("https://example.com", AnalysisOptions{}, result)
+	if err != nil {
+		t.Fatalf("Add() returned error: %v", err)
+	}
+	if added.Result != nil {
+		t.Error("Expected Add() to return the record without its result")
+	}
+	store.Close()
+
+	reopened := newTestHistoryStore(t, HistoryConfig{Path: path})
+	record, ok := reopened.Get(added.ID)
+	if !ok {
+		t.Fatal("Expected record to be loaded from disk")
+	}
+	if record.Result == nil || record.Result.Title != "Example" {
+		t.Errorf("Expected full result to be stored, got %+v", record.Result)
+	}
+	if record.Summary.InternalLinks != 3 || record.Summary.InaccessibleLinks != 1 {
+		t.Errorf("Unexpected summary: %+v", record.Summary)
+	}
+
+	next, _ := reopened.Add("https://example.com", AnalysisOptions{}, result)
+	if next.ID <= added.ID {
+		t.Errorf("Expected IDs to keep increasing after reopening, got %d after %d", next.ID, added.ID)
+	}
+}
+
+func TestHistoryStore_ListFilters(t *testing.T) {
+	store := newTestHistoryStore(t, HistoryConfig{})
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	for _, u := range []string{"https://a.example.com/", "https://b.example.com/", "HTTPS://A.example.com:443/"} {
+		store.Add(u, AnalysisOptions{}, &AnalysisServiceResultDTO{})
+		now = now.Add(time.Hour)
+	}
+
+	records := store.List(HistoryFilter{URL: "https://a.example.com"})
+	if len(records) != 2 || records[0].ID != 3 || records[1].ID != 1 {
+		t.Fatalf("Expected both analyses of a.example.com newest first, got %+v", records)
+	}
+	if records[0].Result != nil {
+		t.Error("Expected List() to omit results")
+	}
+
+	since := time.Date(2026, 1, 1, 12, 30, 0, 0, time.UTC)
+	if records := store.List(HistoryFilter{Since: since}); len(records) != 2 {
+		t.Errorf("Expected 2 records since %v, got %d", since, len(records))
+	}
+	if records := store.List(HistoryFilter{Limit: 1}); len(records) != 1 || records[0].ID != 3 {
+		t.Errorf("Expected only the newest record, got %+v", records)
+	}
+}
+
+func TestHistoryStore_Retention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store := newTestHistoryStore(t, HistoryConfig{Path: path, MaxEntries: 2, MaxAge: time.Hour})
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 4; i++ {
+		store.Add("https://example.com", AnalysisOptions{}, &AnalysisServiceResultDTO{})
+	}
+	if records := store.List(HistoryFilter{}); len(records) != 2 || records[0].ID != 4 {
+		t.Fatalf("Expected the 2 newest records to be kept, got %+v", records)
+	}
+	if _, ok := store.Get(2); ok {
+		t.Error("Expected records beyond the cap to be deleted")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if records := store.List(HistoryFilter{}); len(records) != 0 {
+		t.Errorf("Expected expired records to be hidden, got %+v", records)
+	}
+	if _, ok := store.Get(4); ok {
+		t.Error("Expected expired record to be unavailable")
+	}
+}
+
+func TestHistoryStore_BatchRecordsCappedSeparately(t *testing.T) {
+	store := newTestHistoryStore(t, HistoryConfig{MaxEntries: 2, MaxBatchEntries: 3})
+
+	for _, u := range []string{"https://a.example.com", "https://b.example.com"} {
+		store.Add(u, AnalysisOptions{}, &AnalysisServiceResultDTO{})
+	}
+	for i := 0; i < 5; i++ {
+		store.Add("https://batch.example.com", AnalysisOptions{Batch: true}, &AnalysisServiceResultDTO{})
+	}
+
+	if records := store.List(HistoryFilter{URL: "https://a.example.com"}); len(records) != 1 || records[0].Source != HistorySourceAnalysis {
+		t.Errorf("Expected batch analyses not to evict other records, got %+v", records)
+	}
+	records := store.List(HistoryFilter{URL: "https://batch.example.com"})
+	if len(records) != 3 || records[0].ID != 7 || records[2].ID != 5 || records[0].Source != HistorySourceBatch {
+		t.Errorf("Expected the 3 newest batch records, got %+v", records)
+	}
+}
+
+func TestHistoryConfigFromEnv_AbsolutePath(t *testing.T) {
+	t.Setenv("HISTORY_PATH", filepath.Join("data", "history.db"))
+	if config := HistoryConfigFromEnv(); !filepath.IsAbs(config.Path) {
+		t.Errorf("Expected HISTORY_PATH to be made absolute, got %q", config.Path)
+	}
+	t.Setenv("HISTORY_PATH", "none")
+	if config := HistoryConfigFromEnv(); config.Path != "" {
+		t.Errorf("Expected \"none\" to disable the history file, got %q", config.Path)
+	}
+	if path := DefaultHistoryConfig().Path; !filepath.IsAbs(path) {
+		t.Errorf("Expected an absolute default path, got %q", path)
+	}
+}
+
+func TestAnalyzePage_RecordsHistory(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			return createMockResponse(200, `<html><head><title>Recorded</title></head></html>`), nil
+		},
+	}
+	service := &AnalysisService{
+		httpClient: mockClient,
+		history:    newTestHistoryStore(t, HistoryConfig{}),
+	}
+
+	if _, err := service.AnalyzePage(context.Background(), "https://example.com"); err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	records := service.History(HistoryFilter{URL: "https://example.com"})
+	if len(records) != 1 || records[0].Summary.Title != "Recorded" {
+		t.Fatalf("Expected one recorded analysis, got %+v", records)
+	}
+	if record, ok := service.HistoryRecord(records[0].ID); !ok || record.Result == nil {
+		t.Error("Expected the full result to be retrievable")
+	}
+}
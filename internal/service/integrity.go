@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"hash"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+// Integrity check statuses.
+const (
+	IntegrityVerified   = "verified"
+	IntegrityMismatch   = "mismatch"
+	IntegrityUnverified = "unverified"
+)
+
+// IntegrityCheck is the outcome of verifying the integrity attribute of a
+// script or stylesheet against the resource served at its URL.
+type IntegrityCheck struct {
+	URL       string `json:"url"`
+	Integrity string `json:"integrity"`
+	Status    string `json:"status"`
+	// Algorithm is the strongest algorithm of Integrity, the one browsers
+	// check, and Digest the base64 digest of the fetched resource with it.
+	Algorithm string `json:"algorithm,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	// Error explains why a resource could not be verified.
+	Error string `json:"error,omitempty"`
+}
+
+var integrityHashes = map[string]func() hash.Hash{
+	"sha256": sha256.New,
+	"sha384": sha512.New384,
+	"sha512": sha512.New,
+}
+
+// VerifyIntegrity checks body against an integrity attribute as a browser
+// does, with the hashes of its strongest algorithm. It returns the algorithm
+// and the digest of body, and whether the digest is one of the hashes. An
+// attribute without a supported hash does not restrict body.
+func VerifyIntegrity(body []byte, integrity string) (algorithm, digest string, ok bool) {
+	hashes := analyzer.StrongestIntegrity(analyzer.ParseIntegrity(integrity))
+	if len(hashes) == 0 {
+		return "", "", true
+	}
+	algorithm = hashes[0].Algorithm
+	h := integrityHashes[algorithm]()
+	h.Write(body)
+	digest = base64.StdEncoding.EncodeToString(h.Sum(nil))
+	for _, hash := range hashes {
+		if subtle.ConstantTimeCompare([]byte(hash.Digest), []byte(digest)) == 1 {
+			return algorithm, digest, true
+		}
+	}
+	return algorithm, digest, false
+}
+
+// checkIntegrity fetches the scripts and stylesheets of result that have a
+// valid integrity attribute, through the link scheduler, and verifies them.
+// Each URL and integrity pair is checked once, in page order.
+func (s *AnalysisService) checkIntegrity(ctx context.Context, result *analyzer.AnalysisResult) []IntegrityCheck {
+	type key struct{ url, integrity string }
+	seen := make(map[key]bool)
+	var checks []IntegrityCheck
+	for _, group := range result.ResourceOrigins {
+		for _, resource := range group.Resources {
+			k := key{resource.URL, resource.Integrity}
+			if seen[k] || len(analyzer.ParseIntegrity(resource.Integrity)) == 0 {
+				continue
+			}
+			seen[k] = true
+			checks = append(checks, IntegrityCheck{URL: resource.URL, Integrity: resource.Integrity})
+		}
+	}
+
+	links := make([]string, len(checks))
+	for i, check := range checks {
+		links[i] = check.URL
+	}
+	for i, fetched := range s.fetchAll(ctx, links) {
+		check := &checks[i]
+		if fetched.body == nil {
+			logger.WithContext(ctx).WithField("link", check.URL).WithField("error", fetched.Error).Debug("Skipping integrity check")
+			check.Status, check.Error = IntegrityUnverified, fetched.Error
+			continue
+		}
+		var ok bool
+		check.Algorithm, check.Digest, ok = VerifyIntegrity(fetched.body.body, check.Integrity)
+		check.Status = IntegrityVerified
+		if !ok {
+			check.Status = IntegrityMismatch
+		}
+	}
+	return checks
+}
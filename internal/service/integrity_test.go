@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func integrityOf(algorithm string, sum []byte) string {
+	return algorithm + "-" + base64.StdEncoding.EncodeToString(sum)
+}
+
+func TestVerifyIntegrity(t *testing.T) {
+	body := []byte("alert(1)")
+	sum256 := sha256.Sum256(body)
+	sum384 := sha512.Sum384(body)
+
+	if algorithm, _, ok := VerifyIntegrity(body, integrityOf("sha384", sum384[:])); !ok || algorithm != "sha384" {
+		t.Errorf("Expected a sha384 match, got %s %v", algorithm, ok)
+	}
+	// Only the strongest algorithm counts, and any of its hashes may match.
+	if _, _, ok := VerifyIntegrity(body, integrityOf("sha256", sum256[:])+" sha384-wrong"); ok {
+		t.Error("Expected a mismatch when the sha384 hash is wrong, whatever the sha256 hash")
+	}
+	if _, _, ok := VerifyIntegrity(body, "sha384-wrong "+integrityOf("sha384", sum384[:])); !ok {
+		t.Error("Expected a match when one of the sha384 hashes matches")
+	}
+	if algorithm, digest, ok := VerifyIntegrity(body, "sha256-wrong"); ok || digest != base64.StdEncoding.EncodeToString(sum256[:]) || algorithm != "sha256" {
+		t.Errorf("Expected a mismatch with the actual digest, got %s %s %v", algorithm, digest, ok)
+	}
+	if _, _, ok := VerifyIntegrity(body, "md5-whatever"); !ok {
+		t.Error("Expected an attribute without a supported hash not to restrict the resource")
+	}
+}
+
+func TestAnalyzePage_Integrity(t *testing.T) {
+	script := "console.log('ok')"
+	sum := sha256.Sum256([]byte(script))
+	page := `<html><head>
+		<script src="https://cdn.example.net/ok.js" integrity="` + integrityOf("sha256", sum[:]) + `" crossorigin></script>
+		<script src="https://cdn.example.net/changed.js" integrity="` + integrityOf("sha256", sum[:]) + `" crossorigin></script>
+		<script src="https://cdn.example.net/missing.js" integrity="sha256-abc" crossorigin></script>
+		<script src="https://cdn.example.net/plain.js"></script>
+	</head><body></body></html>`
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			switch req.URL.Path {
+			case "/ok.js":
+				return createMockResponse(200, script), nil
+			case "/changed.js":
+				return createMockResponse(200, script+";"), nil
+			case "/missing.js":
+				return createMockResponse(404, ""), nil
+			}
+			return createMockResponse(200, page), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+	result, err := service.AnalyzePage(context.Background(), "https://example.com/")
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	expected := []struct{ url, status string }{
+		{"https://cdn.example.net/ok.js", IntegrityVerified},
+		{"https://cdn.example.net/changed.js", IntegrityMismatch},
+		{"https://cdn.example.net/missing.js", IntegrityUnverified},
+	}
+	if len(result.Integrity) != len(expected) {
+		t.Fatalf("Expected %d integrity checks, got %+v", len(expected), result.Integrity)
+	}
+	for i, check := range result.Integrity {
+		if check.URL != expected[i].url || check.Status != expected[i].status {
+			t.Errorf("Check %d: expected %s to be %s, got %+v", i, expected[i].url, expected[i].status, check)
+		}
+	}
+	if result.Integrity[2].Error != "status 404" {
+		t.Errorf("Expected the fetch error, got %q", result.Integrity[2].Error)
+	}
+	if len(result.ResourceOrigins) != 1 || len(result.ResourceOrigins[0].Resources) != 4 {
+		t.Errorf("Expected the four CDN scripts, got %+v", result.ResourceOrigins)
+	}
+
+	var mismatches int
+	for _, check := range reportChecks(result) {
+		if check.Rule == RuleIntegrityMismatch && check.Failed && check.Name == "https://cdn.example.net/changed.js" {
+			mismatches++
+		}
+	}
+	if mismatches != 1 {
+		t.Errorf("Expected one failed integrity check in reports, got %d", mismatches)
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+const (
+	defaultLinkCacheSuccessTTL    = time.Hour
+	defaultLinkCacheFailureTTL    = 5 * time.Minute
+	defaultLinkCacheMaxEntries    = 10000
+	defaultLinkCacheFlushInterval = 30 * time.Second
+)
+
+// Cache annotations reported on each LinkCheckResult.
+const (
+	CacheMiss        = "miss"
+	CacheHit         = "hit"
+	CacheRevalidated = "revalidated"
+)
+
+// LinkCacheConfig controls how long link check results are reused and where
+// they are persisted.
+type LinkCacheConfig struct {
+	// SuccessTTL is how long an accessible result is served without revalidation.
+	SuccessTTL time.Duration
+	// FailureTTL is how long an inaccessible result is served before the link is checked again.
+	FailureTTL time.Duration
+	// MaxEntries caps the number of cached links; the oldest checks are
+	// evicted first.
+	MaxEntries int
+	// Path is an optional JSON file the cache is loaded from and saved to.
+	Path string
+	// FlushInterval is how long changes are collected before the cache is
+	// written to Path.
+	FlushInterval time.Duration
+}
+
+// DefaultLinkCacheConfig returns the configuration used when none is provided.
+func DefaultLinkCacheConfig() LinkCacheConfig {
+	return LinkCacheConfig{
+		SuccessTTL:    defaultLinkCacheSuccessTTL,
+		FailureTTL:    defaultLinkCacheFailureTTL,
+		MaxEntries:    defaultLinkCacheMaxEntries,
+		FlushInterval: defaultLinkCacheFlushInterval,
+	}
+}
+
+// LinkCacheEntry is a cached link check outcome together with the validators
+// needed to revalidate it once it goes stale.
+type LinkCacheEntry struct {
+	Accessible   bool      `json:"accessible"`
+	StatusCode   int       `json:"status_code"`
+	FinalURL     string    `json:"final_url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	CheckedAt    time.Time `json:"checked_at"`
+	Error        string    `json:"error,omitempty"`
+	TLSFailure   string    `json:"tls_failure,omitempty"`
+	// TLS describes the connection to the link's host when it was checked.
+	TLS *TLSInfo `json:"tls,omitempty"`
+}
+
+// LinkCache stores link check results keyed by normalized URL.
+type LinkCache struct {
+	config LinkCacheConfig
+	now    func() time.Time
+
+	mu         sync.RWMutex
+	entries    map[string]LinkCacheEntry
+	dirty      bool
+	flushTimer *time.Timer
+	prunedAt   time.Time
+}
+
+// NewLinkCache creates a cache, loading previously persisted entries when a path is configured.
+func NewLinkCache(config LinkCacheConfig) (*LinkCache, error) {
+	if config.SuccessTTL <= 0 {
+		config.SuccessTTL = defaultLinkCacheSuccessTTL
+	}
+	if config.FailureTTL <= 0 {
+		config.FailureTTL = defaultLinkCacheFailureTTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultLinkCacheMaxEntries
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaultLinkCacheFlushInterval
+	}
+
+	c := &LinkCache{
+		config:  config,
+		now:     time.Now,
+		entries: make(map[string]LinkCacheEntry),
+	}
+	if config.Path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.entries); err != nil {
+		return nil, err
+	}
+	c.prune()
+	return c, nil
+}
+
+// Get returns the entry for link and whether it is still fresh.
+func (c *LinkCache) Get(link string) (LinkCacheEntry, bool, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[normalizeLinkURL(link)]
+	c.mu.RUnlock()
+	if !ok {
+		return LinkCacheEntry{}, false, false
+	}
+	return entry, true, c.fresh(entry)
+}
+
+// Put stores the outcome of a check for link. Expired entries are pruned
+// every FailureTTL and whenever the cache outgrows MaxEntries, and the cache
+// is written to Path once FlushInterval has passed.
+func (c *LinkCache) Put(link string, entry LinkCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[normalizeLinkURL(link)] = entry
+	c.dirty = true
+	if len(c.entries) > c.config.MaxEntries || c.now().Sub(c.prunedAt) >= c.config.FailureTTL {
+		c.prune()
+	}
+	if c.config.Path != "" && c.flushTimer == nil {
+		c.flushTimer = time.AfterFunc(c.config.FlushInterval, func() {
+			if err := c.Flush(); err != nil {
+				logger.WithField("error", err).Warn("Failed to persist link cache")
+			}
+		})
+	}
+}
+
+// Flush writes the cache to disk when a path is configured and something
+// changed since the last flush.
+func (c *LinkCache) Flush() error {
+	if c.config.Path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	if c.flushTimer != nil {
+		c.flushTimer.Stop()
+		c.flushTimer = nil
+	}
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	data, err := json.Marshal(c.entries)
+	c.dirty = false
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.config.Path), ".link-cache-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.config.Path)
+}
+
+// prune drops expired entries and, if the cache is still over MaxEntries,
+// evicts the oldest checks until it is a tenth below the cap so that eviction
+// does not run on every Put. Callers must hold c.mu.
+func (c *LinkCache) prune() {
+	c.prunedAt = c.now()
+	for key, entry := range c.entries {
+		// Stale entries with validators are still useful for revalidation.
+		if !c.fresh(entry) && entry.ETag == "" && entry.LastModified == "" {
+			delete(c.entries, key)
+		}
+	}
+	if len(c.entries) <= c.config.MaxEntries {
+		return
+	}
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return c.entries[a].CheckedAt.Compare(c.entries[b].CheckedAt)
+	})
+	for _, key := range keys[:len(keys)-c.config.MaxEntries*9/10] {
+		delete(c.entries, key)
+	}
+}
+
+func (c *LinkCache) fresh(entry LinkCacheEntry) bool {
+	ttl := c.config.SuccessTTL
+	if !entry.Accessible {
+		ttl = c.config.FailureTTL
+	}
+	return c.now().Sub(entry.CheckedAt) < ttl
+}
+
+// normalizeLinkURL produces the cache key for a link: scheme and host are
+// lowercased, default ports and fragments dropped and an empty path becomes "/".
+func normalizeLinkURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	port := u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	switch {
+	case port != "":
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
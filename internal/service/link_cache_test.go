@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestLinkCache(t *testing.T, config LinkCacheConfig) *LinkCache {
+	t.Helper()
+	cache, err := NewLinkCache(config)
+	if err != nil {
+		t.Fatalf("NewLinkCache() returned error: %v", err)
+	}
+	return cache
+}
+
+func TestNormalizeLinkURL(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"HTTPS://Example.COM", "https://example.com/"},
+		{"https://example.com:443/path", "https://example.com/path"},
+		{"http://example.com:80/path", "http://example.com/path"},
+		{"http://example.com:8080/path", "http://example.com:8080/path"},
+		{"https://example.com/page#section", "https://example.com/page"},
+		{"https://example.com/search?q=Go", "https://example.com/search?q=Go"},
+		{"http://[::1]:80/", "http://[::1]/"},
+	}
+
+	for _, tc := range testCases {
+		if got := normalizeLinkURL(tc.input); got != tc.expected {
+			t.Errorf("normalizeLinkURL(%q) = %q, expected %q", tc.input, got, tc.expected)
+		}
+	}
+}
+
+func TestLinkCache_TTLBySuccess(t *testing.T) {
+	cache := newTestLinkCache(t, LinkCacheConfig{SuccessTTL: time.Hour, FailureTTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Put("https://example.com/ok", LinkCacheEntry{Accessible: true, StatusCode: 200, CheckedAt: now})
+	cache.Put("https://example.com/broken", LinkCacheEntry{Accessible: false, StatusCode: 404, CheckedAt: now})
+
+	now = now.Add(10 * time.Minute)
+
+	if _, found, fresh := cache.Get("https://EXAMPLE.com/ok#top"); !found || !fresh {
+		t.Errorf("Expected successful result to be fresh after 10m, found=%v fresh=%v", found, fresh)
+	}
+	if _, found, fresh := cache.Get("https://example.com/broken"); !found || fresh {
+		t.Errorf("Expected failed result to be stale after 10m, found=%v fresh=%v", found, fresh)
+	}
+	if _, found, _ := cache.Get("https://example.com/missing"); found {
+		t.Error("Expected no entry for an unknown link")
+	}
+}
+
+func TestLinkCache_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+
+	cache := newTestLinkCache(t, LinkCacheConfig{Path: path})
+	cache.Put("https://example.com/ok", LinkCacheEntry{
+		Accessible: true,
+		StatusCode: 200,
+		FinalURL:   "https://example.com/ok/",
+		ETag:       `"v1"`,
+		CheckedAt:  time.Now(),
+	})
+	if err := cache.Flush(); err != nil {
+		t.Fatalf("Flush() returned error: %v", err)
+	}
+
+	reloaded := newTestLinkCache(t, LinkCacheConfig{Path: path})
+	entry, found, fresh := reloaded.Get("https://example.com/ok")
+	if !found || !fresh {
+		t.Fatalf("Expected persisted entry to be found and fresh, found=%v fresh=%v", found, fresh)
+	}
+	if entry.FinalURL != "https://example.com/ok/" || entry.ETag != `"v1"` {
+		t.Errorf("Unexpected persisted entry: %+v", entry)
+	}
+}
+
+func TestLinkCache_PrunesWithoutPath(t *testing.T) {
+	cache := newTestLinkCache(t, LinkCacheConfig{SuccessTTL: time.Hour, FailureTTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Put("https://example.com/broken", LinkCacheEntry{Accessible: false, StatusCode: 404, CheckedAt: now})
+	now = now.Add(2 * time.Minute)
+	cache.Put("https://example.com/ok", LinkCacheEntry{Accessible: true, StatusCode: 200, CheckedAt: now})
+
+	if _, found, _ := cache.Get("https://example.com/broken"); found {
+		t.Error("Expected the expired entry to be pruned by a later Put")
+	}
+	if _, found, _ := cache.Get("https://example.com/ok"); !found {
+		t.Error("Expected the fresh entry to be kept")
+	}
+}
+
+func TestLinkCache_MaxEntries(t *testing.T) {
+	cache := newTestLinkCache(t, LinkCacheConfig{MaxEntries: 10})
+	start := time.Now()
+	for i := 0; i < 25; i++ {
+		cache.Put(fmt.Sprintf("https://example.com/%d", i), LinkCacheEntry{Accessible: true, CheckedAt: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	if n := len(cache.entries); n > 10 {
+		t.Errorf("Expected at most 10 entries, got %d", n)
+	}
+	if _, found, _ := cache.Get("https://example.com/24"); !found {
+		t.Error("Expected the latest check to be kept")
+	}
+	if _, found, _ := cache.Get("https://example.com/0"); found {
+		t.Error("Expected the oldest check to be evicted")
+	}
+}
+
+func TestLinkCache_DebouncesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "links.json")
+	cache := newTestLinkCache(t, LinkCacheConfig{Path: path, FlushInterval: 50 * time.Millisecond})
+
+	cache.Put("https://example.com/1", LinkCacheEntry{Accessible: true, CheckedAt: time.Now()})
+	cache.Put("https://example.com/2", LinkCacheEntry{Accessible: true, CheckedAt: time.Now()})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected no write before the flush interval, got %v", err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	reloaded := newTestLinkCache(t, LinkCacheConfig{Path: path})
+	for _, link := range []string{"https://example.com/1", "https://example.com/2"} {
+		if _, found, _ := reloaded.Get(link); !found {
+			t.Errorf("Expected %s to be written after the flush interval", link)
+		}
+	}
+}
+
+func TestCheckLink_CacheHitAndMiss(t *testing.T) {
+	var calls int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			atomic.AddInt64(&calls, 1)
+			return createMockResponse(200, ""), nil
+		},
+	}
+
+	service := &AnalysisService{
+		httpClient: mockClient,
+		linkCache:  newTestLinkCache(t, DefaultLinkCacheConfig()),
+	}
+	ctx := context.Background()
+
+	first := service.checkLink(ctx, "https://example.com/page")
+	second := service.checkLink(ctx, "https://example.com/page")
+
+	if first.Cache != CacheMiss {
+		t.Errorf("Expected first check to be a cache miss, got %q", first.Cache)
+	}
+	if second.Cache != CacheHit || !second.Accessible || second.StatusCode != 200 {
+		t.Errorf("Expected second check to be an accessible cache hit, got %+v", second)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 outbound request, got %d", calls)
+	}
+}
+
+func TestCheckLink_CachesFailureReason(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			return nil, errors.New("connection refused")
+		},
+	}
+	service := &AnalysisService{
+		httpClient: mockClient,
+		linkCache:  newTestLinkCache(t, DefaultLinkCacheConfig()),
+	}
+	ctx := context.Background()
+
+	service.checkLink(ctx, "https://example.com/down")
+	cached := service.checkLink(ctx, "https://example.com/down")
+	if cached.Cache != CacheHit || cached.Accessible || !strings.Contains(cached.Error, "connection refused") {
+		t.Errorf("Expected a cached failure with its reason, got %+v", cached)
+	}
+}
+
+func TestCheckLink_RevalidatesStaleEntry(t *testing.T) {
+	var conditional string
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			conditional = req.Header.Get("If-None-Match")
+			if conditional == `"v1"` {
+				return createMockResponse(http.StatusNotModified, ""), nil
+			}
+			resp := createMockResponse(200, "")
+			resp.Header.Set("ETag", `"v1"`)
+			return resp, nil
+		},
+	}
+
+	cache := newTestLinkCache(t, LinkCacheConfig{SuccessTTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	service := &AnalysisService{httpClient: mockClient, linkCache: cache}
+	ctx := context.Background()
+
+	service.checkLink(ctx, "https://example.com/page")
+	now = now.Add(2 * time.Minute)
+	result := service.checkLink(ctx, "https://example.com/page")
+
+	if conditional != `"v1"` {
+		t.Errorf("Expected revalidation request with If-None-Match, got %q", conditional)
+	}
+	if result.Cache != CacheRevalidated || !result.Accessible || result.StatusCode != 200 {
+		t.Errorf("Expected an accessible revalidated result with the cached status, got %+v", result)
+	}
+}
+
+func TestAnalyzePage_LinkResultsAnnotated(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				return createMockResponse(200, sampleHTML), nil
+			}
+			if req.URL.Host == "external.com" {
+				return createMockResponse(404, ""), nil
+			}
+			return createMockResponse(200, ""), nil
+		},
+	}
+
+	service := &AnalysisService{
+		httpClient: mockClient,
+		scheduler:  NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 4, MaxPerHost: 4}),
+		linkCache:  newTestLinkCache(t, DefaultLinkCacheConfig()),
+	}
+
+	result, err := service.AnalyzePage(context.Background(), "https://example.com/test")
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	if len(result.LinkResults) != 2 {
+		t.Fatalf("Expected 2 link results, got %d", len(result.LinkResults))
+	}
+	for _, link := range result.LinkResults {
+		if link.Cache != CacheMiss {
+			t.Errorf("Expected %s to be a cache miss, got %q", link.URL, link.Cache)
+		}
+	}
+	if len(result.InaccessibleExternalLinks) != 1 || result.InaccessibleExternalLinks[0] != "https://external.com" {
+		t.Errorf("Expected https://external.com to be inaccessible, got %v", result.InaccessibleExternalLinks)
+	}
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxConcurrentChecks = 20
+	defaultMaxChecksPerHost    = 4
+	defaultHostDelay           = 100 * time.Millisecond
+)
+
+// LinkCheckFunc performs a single link accessibility check.
+type LinkCheckFunc func(ctx context.Context, link string) LinkCheckResult
+
+// LinkSchedulerConfig controls how many link checks run at once and how
+// politely individual hosts are treated.
+type LinkSchedulerConfig struct {
+	// MaxConcurrent caps the number of checks in flight across the process.
+	MaxConcurrent int
+	// MaxPerHost caps the number of checks in flight against a single host.
+	MaxPerHost int
+	// HostDelay is the minimum spacing between two checks started against the same host.
+	HostDelay time.Duration
+}
+
+// DefaultLinkSchedulerConfig returns the configuration used when none is provided.
+func DefaultLinkSchedulerConfig() LinkSchedulerConfig {
+	return LinkSchedulerConfig{
+		MaxConcurrent: defaultMaxConcurrentChecks,
+		MaxPerHost:    defaultMaxChecksPerHost,
+		HostDelay:     defaultHostDelay,
+	}
+}
+
+// LinkScheduler runs link checks for every analysis in the process through one
+// bounded pool. Batches submitted by concurrent requests are served round-robin,
+// and identical URLs requested while a check is in flight share its result.
+type LinkScheduler struct {
+	config LinkSchedulerConfig
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	batches  []*linkBatch
+	next     int
+	inflight map[string]*linkCall
+	hosts    map[string]*hostState
+	busy     int
+	// wake is when a pending timer will next wake waiting workers.
+	wake time.Time
+}
+
+// LinkSchedulerStats is a snapshot of the scheduler's utilization.
+type LinkSchedulerStats struct {
+	Workers int
+	Busy    int
+	Queued  int
+}
+
+type linkBatch struct {
+	jobs []*linkJob
+}
+
+type linkJob struct {
+	ctx   context.Context
+	key   string
+	link  string
+	host  string
+	check LinkCheckFunc
+	call  *linkCall
+}
+
+// linkCall is a single in-flight check shared by every caller waiting on the same URL.
+type linkCall struct {
+	done    chan struct{}
+	result  LinkCheckResult
+	waiters int
+}
+
+type hostState struct {
+	active      int
+	nextAllowed time.Time
+}
+
+// NewLinkScheduler creates a scheduler and starts its workers.
+func NewLinkScheduler(config LinkSchedulerConfig) *LinkScheduler {
+	if config.MaxConcurrent <= 0 {
+		config.MaxConcurrent = defaultMaxConcurrentChecks
+	}
+	if config.MaxPerHost <= 0 {
+		config.MaxPerHost = defaultMaxChecksPerHost
+	}
+	if config.HostDelay < 0 {
+		config.HostDelay = 0
+	}
+
+	s := &LinkScheduler{
+		config:   config,
+		inflight: make(map[string]*linkCall),
+		hosts:    make(map[string]*hostState),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	for w := 0; w < config.MaxConcurrent; w++ {
+		go s.worker()
+	}
+	return s
+}
+
+// CheckAll checks every link and returns the results in the same order as
+// links. Links whose check cannot complete before ctx is done are reported as
+// inaccessible.
+func (s *LinkScheduler) CheckAll(ctx context.Context, links []string, check LinkCheckFunc) []LinkCheckResult {
+	return s.schedule(ctx, "", links, check)
+}
+
+// schedule is CheckAll for checks of the given kind. Only checks of the same
+// kind share an in-flight call, so that a GET of a link is not answered with
+// the result of a HEAD check.
+func (s *LinkScheduler) schedule(ctx context.Context, kind string, links []string, check LinkCheckFunc) []LinkCheckResult {
+	results := make([]LinkCheckResult, len(links))
+	if len(links) == 0 {
+		return results
+	}
+
+	calls := make([]*linkCall, len(links))
+	batch := &linkBatch{}
+
+	s.mu.Lock()
+	for i, link := range links {
+		key := kind + " " + link
+		call, ok := s.inflight[key]
+		if !ok {
+			call = &linkCall{done: make(chan struct{})}
+			s.inflight[key] = call
+			batch.jobs = append(batch.jobs, &linkJob{
+				ctx:   ctx,
+				key:   key,
+				link:  link,
+				host:  hostOf(link),
+				check: check,
+				call:  call,
+			})
+		}
+		call.waiters++
+		calls[i] = call
+	}
+	if len(batch.jobs) > 0 {
+		s.batches = append(s.batches, batch)
+		s.cond.Broadcast()
+	}
+	s.mu.Unlock()
+
+	for i, call := range calls {
+		select {
+		case <-call.done:
+			results[i] = call.result
+		case <-ctx.Done():
+			results[i] = LinkCheckResult{URL: links[i], Error: ctx.Err().Error()}
+		}
+	}
+
+	s.mu.Lock()
+	for _, call := range calls {
+		call.waiters--
+	}
+	s.mu.Unlock()
+
+	return results
+}
+
+func (s *LinkScheduler) worker() {
+	for {
+		s.run(s.dequeue())
+	}
+}
+
+// dequeue blocks until a job whose host has spare capacity and is past its
+// delay is available. Batches are visited round-robin so one large page cannot
+// starve other requests, and jobs for a host that is still in its delay stay
+// queued instead of holding a worker.
+func (s *LinkScheduler) dequeue() *linkJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		now := time.Now()
+		var wake time.Time
+		for n := 0; n < len(s.batches); n++ {
+			idx := (s.next + n) % len(s.batches)
+			batch := s.batches[idx]
+			for j, job := range batch.jobs {
+				host := s.host(job.host)
+				if host.active >= s.config.MaxPerHost {
+					continue
+				}
+				if host.nextAllowed.After(now) {
+					if wake.IsZero() || host.nextAllowed.Before(wake) {
+						wake = host.nextAllowed
+					}
+					continue
+				}
+
+				batch.jobs = append(batch.jobs[:j], batch.jobs[j+1:]...)
+				if len(batch.jobs) == 0 {
+					s.batches = append(s.batches[:idx], s.batches[idx+1:]...)
+					s.next = idx
+				} else {
+					s.next = idx + 1
+				}
+
+				host.active++
+				host.nextAllowed = now.Add(s.config.HostDelay)
+				s.busy++
+				return job
+			}
+		}
+		if !wake.IsZero() {
+			s.wakeAt(wake)
+		}
+		s.cond.Wait()
+	}
+}
+
+// wakeAt arranges for waiting workers to be woken at t, when a delayed host
+// becomes available again. Callers must hold s.mu.
+func (s *LinkScheduler) wakeAt(t time.Time) {
+	if !s.wake.IsZero() && !s.wake.After(t) {
+		return
+	}
+	s.wake = t
+	time.AfterFunc(time.Until(t), func() {
+		s.mu.Lock()
+		if s.wake.Equal(t) {
+			s.wake = time.Time{}
+		}
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+}
+
+func (s *LinkScheduler) run(job *linkJob) {
+	result := LinkCheckResult{URL: job.link, Error: context.Canceled.Error()}
+	if s.hasWaiters(job.call) {
+		// The check outlives the submitting request so that other callers
+		// sharing this URL still receive a result.
+		result = job.check(context.WithoutCancel(job.ctx), job.link)
+	}
+
+	s.mu.Lock()
+	host := s.hosts[job.host]
+	host.active--
+	s.release(job.host, host)
+	delete(s.inflight, job.key)
+	s.busy--
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	job.call.result = result
+	close(job.call.done)
+}
+
+// release forgets an idle host once its delay has passed, so that the host
+// table only holds hosts that are busy or were checked within HostDelay.
+// Callers must hold s.mu.
+func (s *LinkScheduler) release(name string, host *hostState) {
+	if host.active > 0 {
+		return
+	}
+	if wait := time.Until(host.nextAllowed); wait > 0 {
+		time.AfterFunc(wait, func() {
+			s.mu.Lock()
+			if s.hosts[name] == host {
+				s.release(name, host)
+			}
+			s.mu.Unlock()
+		})
+		return
+	}
+	delete(s.hosts, name)
+}
+
+// Stats returns the number of workers, how many are running a check and how
+// many checks are waiting for a worker.
+func (s *LinkScheduler) Stats() LinkSchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queued := 0
+	for _, batch := range s.batches {
+		queued += len(batch.jobs)
+	}
+	return LinkSchedulerStats{Workers: s.config.MaxConcurrent, Busy: s.busy, Queued: queued}
+}
+
+func (s *LinkScheduler) hasWaiters(call *linkCall) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return call.waiters > 0
+}
+
+// host returns the bookkeeping for a host. Callers must hold s.mu.
+func (s *LinkScheduler) host(name string) *hostState {
+	h, ok := s.hosts[name]
+	if !ok {
+		h = &hostState{}
+		s.hosts[name] = h
+	}
+	return h
+}
+
+func hostOf(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
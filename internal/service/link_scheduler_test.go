@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLinkScheduler_ResultsInOrder(t *testing.T) {
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 4, MaxPerHost: 4})
+
+	links := []string{
+		"https://a.example.com/ok",
+		"https://b.example.com/broken",
+		"https://c.example.com/ok",
+	}
+	results := scheduler.CheckAll(context.Background(), links, func(_ context.Context, link string) LinkCheckResult {
+		return LinkCheckResult{URL: link, Accessible: link != "https://b.example.com/broken"}
+	})
+
+	expected := []bool{true, false, true}
+	for i := range expected {
+		if results[i].Accessible != expected[i] {
+			t.Errorf("Expected result[%d] to be %v, got %v", i, expected[i], results[i].Accessible)
+		}
+	}
+}
+
+func TestLinkScheduler_EmptyList(t *testing.T) {
+	scheduler := NewLinkScheduler(DefaultLinkSchedulerConfig())
+
+	results := scheduler.CheckAll(context.Background(), nil, func(_ context.Context, _ string) LinkCheckResult {
+		t.Error("check should not be called for an empty list")
+		return LinkCheckResult{Accessible: true}
+	})
+
+	if len(results) != 0 {
+		t.Errorf("Expected no results, got %d", len(results))
+	}
+}
+
+func TestLinkScheduler_GlobalConcurrencyLimit(t *testing.T) {
+	const limit = 3
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: limit, MaxPerHost: 100})
+
+	var active, peak int64
+	check := func(_ context.Context, _ string) LinkCheckResult {
+		current := atomic.AddInt64(&active, 1)
+		for {
+			old := atomic.LoadInt64(&peak)
+			if current <= old || atomic.CompareAndSwapInt64(&peak, old, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt64(&active, -1)
+		return LinkCheckResult{Accessible: true}
+	}
+
+	var links []string
+	for i := 0; i < 20; i++ {
+		links = append(links, fmt.Sprintf("https://host%d.example.com", i))
+	}
+
+	var wg sync.WaitGroup
+	for r := 0; r < 3; r++ {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			batch := make([]string, len(links))
+			for i, link := range links {
+				batch[i] = fmt.Sprintf("%s/request%d", link, r)
+			}
+			scheduler.CheckAll(context.Background(), batch, check)
+		}(r)
+	}
+	wg.Wait()
+
+	if peak > limit {
+		t.Errorf("Expected at most %d concurrent checks, got %d", limit, peak)
+	}
+}
+
+func TestLinkScheduler_PerHostLimitAndDelay(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 10, MaxPerHost: 1, HostDelay: delay})
+
+	var mu sync.Mutex
+	var starts []time.Time
+	var active, peak int64
+	check := func(_ context.Context, _ string) LinkCheckResult {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+
+		current := atomic.AddInt64(&active, 1)
+		if current > atomic.LoadInt64(&peak) {
+			atomic.StoreInt64(&peak, current)
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt64(&active, -1)
+		return LinkCheckResult{Accessible: true}
+	}
+
+	links := []string{
+		"https://example.com/1",
+		"https://example.com/2",
+		"https://example.com/3",
+	}
+	scheduler.CheckAll(context.Background(), links, check)
+
+	if peak != 1 {
+		t.Errorf("Expected at most 1 concurrent check per host, got %d", peak)
+	}
+	for i := 1; i < len(starts); i++ {
+		// Allow a little scheduling slack below the configured delay.
+		if gap := starts[i].Sub(starts[i-1]); gap < delay-5*time.Millisecond {
+			t.Errorf("Expected checks against the same host to be spaced by %v, got %v", delay, gap)
+		}
+	}
+}
+
+func TestLinkScheduler_DeduplicatesConcurrentRequests(t *testing.T) {
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 4, MaxPerHost: 4})
+
+	var calls int64
+	release := make(chan struct{})
+	check := func(_ context.Context, _ string) LinkCheckResult {
+		atomic.AddInt64(&calls, 1)
+		<-release
+		return LinkCheckResult{Accessible: true}
+	}
+
+	var wg sync.WaitGroup
+	results := make([][]LinkCheckResult, 5)
+	for r := range results {
+		wg.Add(1)
+		go func(r int) {
+			defer wg.Done()
+			results[r] = scheduler.CheckAll(context.Background(), []string{"https://example.com/shared"}, check)
+		}(r)
+	}
+
+	// Give every caller time to register before the single check completes.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected 1 check for a URL requested concurrently, got %d", calls)
+	}
+	for r, res := range results {
+		if len(res) != 1 || !res[0].Accessible {
+			t.Errorf("Expected caller %d to receive the shared result, got %v", r, res)
+		}
+	}
+}
+
+func TestLinkScheduler_FairQueuing(t *testing.T) {
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 1, MaxPerHost: 100})
+
+	var mu sync.Mutex
+	var order []string
+	block := make(chan struct{})
+	check := func(_ context.Context, link string) LinkCheckResult {
+		if link == "https://blocker.example.com" {
+			<-block
+			return LinkCheckResult{Accessible: true}
+		}
+		mu.Lock()
+		order = append(order, link[:len("https://x")])
+		mu.Unlock()
+		return LinkCheckResult{Accessible: true}
+	}
+
+	// Occupy the only worker so both batches are queued before any runs.
+	go scheduler.CheckAll(context.Background(), []string{"https://blocker.example.com"}, check)
+	time.Sleep(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for _, prefix := range []string{"a", "b"} {
+		wg.Add(1)
+		go func(prefix string) {
+			defer wg.Done()
+			var links []string
+			for i := 0; i < 3; i++ {
+				links = append(links, fmt.Sprintf("https://%s%d.example.com", prefix, i))
+			}
+			scheduler.CheckAll(context.Background(), links, check)
+		}(prefix)
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(block)
+	wg.Wait()
+
+	expected := []string{"https://a", "https://b", "https://a", "https://b", "https://a", "https://b"}
+	for i := range expected {
+		if i >= len(order) || order[i] != expected[i] {
+			t.Fatalf("Expected batches to be served round-robin %v, got %v", expected, order)
+		}
+	}
+}
+
+func TestLinkScheduler_ContextCancellation(t *testing.T) {
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 1, MaxPerHost: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := scheduler.CheckAll(ctx, []string{"https://example.com/slow"}, func(_ context.Context, _ string) LinkCheckResult {
+		time.Sleep(50 * time.Millisecond)
+		return LinkCheckResult{Accessible: true}
+	})
+
+	if results[0].Accessible {
+		t.Error("Expected link to be reported inaccessible when the context is cancelled")
+	}
+}
+
+func TestLinkScheduler_HostDelayDoesNotHoldWorkers(t *testing.T) {
+	const delay = 500 * time.Millisecond
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 1, MaxPerHost: 1, HostDelay: delay})
+	check := func(_ context.Context, link string) LinkCheckResult {
+		return LinkCheckResult{URL: link, Accessible: true}
+	}
+
+	// The second check against a.example.com must wait out the host delay.
+	go scheduler.CheckAll(context.Background(), []string{"https://a.example.com/1", "https://a.example.com/2"}, check)
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	results := scheduler.CheckAll(context.Background(), []string{"https://b.example.com/1"}, check)
+	if !results[0].Accessible {
+		t.Fatalf("Expected the check to succeed, got %+v", results[0])
+	}
+	if elapsed := time.Since(start); elapsed > delay/2 {
+		t.Errorf("Expected a check against another host to run while a.example.com is delayed, took %v", elapsed)
+	}
+}
+
+func TestLinkScheduler_ForgetsIdleHosts(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	scheduler := NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 4, MaxPerHost: 1, HostDelay: delay})
+
+	var links []string
+	for i := 0; i < 10; i++ {
+		links = append(links, fmt.Sprintf("https://host%d.example.com", i))
+	}
+	scheduler.CheckAll(context.Background(), links, func(_ context.Context, link string) LinkCheckResult {
+		return LinkCheckResult{URL: link, Accessible: true}
+	})
+
+	time.Sleep(5 * delay)
+	scheduler.mu.Lock()
+	hosts := len(scheduler.hosts)
+	scheduler.mu.Unlock()
+	if hosts != 0 {
+		t.Errorf("Expected idle hosts to be forgotten after the host delay, got %d", hosts)
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/metrics"
+)
+
+// Analysis phases reported by analysisPhaseDuration.
+const (
+	phaseFetch           = "fetch"
+	phaseParse           = "parse"
+	phaseLinkChecks      = "link_checks"
+	phaseAnchorChecks    = "anchor_checks"
+	phaseIntegrityChecks = "integrity_checks"
+)
+
+const defaultMetricsMaxHosts = 100
+
+var (
+	analysisDuration = metrics.NewHistogramVec(
+		"analyzer_analysis_duration_seconds",
+		"Time taken by a full page analysis, by outcome.",
+		nil, "outcome")
+	analysisPhaseDuration = metrics.NewHistogramVec(
+		"analyzer_analysis_phase_duration_seconds",
+		"Time spent in each analysis phase.",
+		nil, "phase")
+	outboundRequests = metrics.NewCounterVec(
+		"analyzer_outbound_requests_total",
+		"Outbound HTTP requests, by target host (the first hosts contacted, then \"other\") and response status class.",
+		"host", "status_class")
+	linkCacheLookups = metrics.NewCounterVec(
+		"analyzer_link_cache_lookups_total",
+		"Link cache lookups, by result (hit, revalidated or miss).",
+		"result")
+	fetchCacheLookups = metrics.NewCounterVec(
+		"analyzer_fetch_cache_lookups_total",
+		"Lookups of the bodies fetched for fragment and integrity checks, by result (hit, revalidated or miss).",
+		"result")
+)
+
+func init() {
+	metrics.NewGaugeFunc("analyzer_link_check_workers", "Number of link check workers.", func() float64 {
+		return float64(sharedLinkScheduler().Stats().Workers)
+	})
+	metrics.NewGaugeFunc("analyzer_link_check_workers_busy", "Number of link check workers running a check.", func() float64 {
+		return float64(sharedLinkScheduler().Stats().Busy)
+	})
+	metrics.NewGaugeFunc("analyzer_link_check_queue_depth", "Number of link checks waiting for a worker.", func() float64 {
+		return float64(sharedLinkScheduler().Stats().Queued)
+	})
+	metrics.NewGaugeFunc("analyzer_result_cache_entries", "Number of analyses held in the in-memory result cache.", func() float64 {
+		return float64(SharedResultCacheStats().Entries)
+	})
+	metrics.NewCounterFunc("analyzer_result_cache_hits_total", "Result cache lookups served from memory or disk.", func() float64 {
+		stats := SharedResultCacheStats()
+		return float64(stats.Hits + stats.DiskHits)
+	})
+	metrics.NewCounterFunc("analyzer_result_cache_misses_total", "Result cache lookups that required a fresh analysis.", func() float64 {
+		return float64(SharedResultCacheStats().Misses)
+	})
+	metrics.NewCounterFunc("analyzer_result_cache_evictions_total", "Analyses evicted from the in-memory result cache.", func() float64 {
+		return float64(SharedResultCacheStats().Evictions)
+	})
+}
+
+// outboundHosts limits the host label of outboundRequests to the first
+// METRICS_MAX_HOSTS hosts contacted; later hosts are counted as "other".
+var outboundHosts = sync.OnceValue(func() *metrics.LabelLimiter {
+	return metrics.NewLabelLimiter(envInt("METRICS_MAX_HOSTS", defaultMetricsMaxHosts))
+})
+
+// instrumentedTransport counts outbound requests by host and status class.
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := outboundHosts().Value(req.URL.Host)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		outboundRequests.WithLabelValues(host, "error").Inc()
+		return nil, err
+	}
+	outboundRequests.WithLabelValues(host, metrics.StatusClass(resp.StatusCode)).Inc()
+	return resp, nil
+}
+
+// observePhase records the duration of an analysis phase and logs it with
+// the job's fields.
+func observePhase(ctx context.Context, phase string, start time.Time) {
+	duration := time.Since(start)
+	analysisPhaseDuration.WithLabelValues(phase).Observe(duration.Seconds())
+	logger.WithContext(ctx).WithFields(logrus.Fields{
+		"phase":    phase,
+		"duration": duration.String(),
+	}).Debug("Analysis phase completed")
+}
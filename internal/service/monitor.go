@@ -0,0 +1,501 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+const (
+	monitorsFileName = "monitors.json"
+	// legacyMonitorsPath is where monitors were saved, relative to the working
+	// directory, before they moved to the data directory.
+	legacyMonitorsPath = "data/monitors.json"
+)
+
+// Alert conditions and events.
+const (
+	ConditionBrokenLinks    = "broken_links"
+	ConditionAnalysisFailed = "analysis_failed"
+
+	EventAlertFiring   = "alert.firing"
+	EventAlertResolved = "alert.resolved"
+)
+
+var (
+	// ErrMonitorNotFound is returned for an unknown monitor ID.
+	ErrMonitorNotFound = errors.New("monitor not found")
+	// ErrInvalidMonitor wraps validation errors returned by MonitorRunner.Add.
+	ErrInvalidMonitor = errors.New("invalid monitor")
+)
+
+// MonitorConfig controls where monitors are stored and where alerts are sent.
+type MonitorConfig struct {
+	// Path is the JSON file monitors and their alert state are saved to.
+	// Empty keeps them in memory only.
+	Path string
+	// WebhookURL receives alerts for monitors that do not set their own.
+	WebhookURL string
+	// WebhookSecret signs every webhook delivery when set.
+	WebhookSecret string
+}
+
+// AlertConditions decide when a monitor alerts.
+type AlertConditions struct {
+	// BrokenLinksAbove fires when more than this many links are
+	// inaccessible. A negative value disables the condition.
+	BrokenLinksAbove int `json:"broken_links_above"`
+	// FailedRuns fires after this many consecutive analyses fail. Zero
+	// disables the condition.
+	FailedRuns int `json:"failed_runs"`
+}
+
+// DefaultAlertConditions alerts on any broken link and on three failed runs in a row.
+func DefaultAlertConditions() AlertConditions {
+	return AlertConditions{BrokenLinksAbove: 0, FailedRuns: 3}
+}
+
+// AlertState is an alert that is currently firing.
+type AlertState struct {
+	Since time.Time `json:"since"`
+	// Links are the inaccessible links last reported for a broken_links alert.
+	Links []string `json:"links,omitempty"`
+}
+
+// Monitor is a URL that is analyzed on a schedule.
+type Monitor struct {
+	ID         string          `json:"id"`
+	URL        string          `json:"url"`
+	Schedule   string          `json:"schedule"`
+	Conditions AlertConditions `json:"conditions"`
+	// WebhookURL overrides MonitorConfig.WebhookURL for this monitor.
+	WebhookURL string    `json:"webhook_url,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	LastRunAt           time.Time              `json:"last_run_at,omitzero"`
+	NextRunAt           time.Time              `json:"next_run_at"`
+	LastError           string                 `json:"last_error,omitempty"`
+	ConsecutiveFailures int                    `json:"consecutive_failures"`
+	Alerts              map[string]*AlertState `json:"alerts,omitempty"`
+}
+
+// AlertEvent is the JSON payload sent to webhooks.
+type AlertEvent struct {
+	Event     string    `json:"event"`
+	MonitorID string    `json:"monitor_id"`
+	URL       string    `json:"url"`
+	Condition string    `json:"condition"`
+	Message   string    `json:"message"`
+	Since     time.Time `json:"since"`
+	At        time.Time `json:"at"`
+	// BrokenLinks lists every inaccessible link when a broken_links alert fires.
+	BrokenLinks []string `json:"broken_links,omitempty"`
+	// NewlyBroken and NewlyFixed are relative to the previous run.
+	NewlyBroken []string `json:"newly_broken,omitempty"`
+	NewlyFixed  []string `json:"newly_fixed,omitempty"`
+}
+
+// MonitorRunner analyzes registered URLs on their schedules and sends an
+// alert when a condition starts firing, when new links break while it is
+// firing, and when it resolves. Repeated runs in the same state send nothing.
+type MonitorRunner struct {
+	config  MonitorConfig
+	analyze func(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error)
+	send    func(ctx context.Context, webhookURL, deliveryID string, event AlertEvent) error
+	now     func() time.Time
+
+	mu       sync.Mutex
+	monitors map[string]*monitorEntry
+	wake     chan struct{}
+	wg       sync.WaitGroup
+}
+
+type monitorEntry struct {
+	Monitor
+	schedule Schedule
+	previous *AnalysisServiceResultDTO
+	running  bool
+}
+
+// NewMonitorRunner loads saved monitors. analyze runs one analysis.
+func NewMonitorRunner(config MonitorConfig, analyze func(ctx context.Context, pageURL string) (*AnalysisServiceResultDTO, error)) (*MonitorRunner, error) {
+	m := &MonitorRunner{
+		config:   config,
+		analyze:  analyze,
+		now:      time.Now,
+		monitors: make(map[string]*monitorEntry),
+		wake:     make(chan struct{}, 1),
+	}
+	m.send = func(ctx context.Context, webhookURL, deliveryID string, event AlertEvent) error {
+		return NewWebhook(webhookURL, config.WebhookSecret).Send(ctx, deliveryID, event)
+	}
+	if config.Path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var saved []Monitor
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("invalid monitors file %s: %w", config.Path, err)
+	}
+	for _, monitor := range saved {
+		schedule, err := ParseSchedule(monitor.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		m.monitors[monitor.ID] = &monitorEntry{Monitor: monitor, schedule: schedule}
+	}
+	return m, nil
+}
+
+// Add registers a monitor. URL and Schedule are required; ID, CreatedAt and
+// the run state are filled in. Validation errors wrap ErrInvalidMonitor; any
+// other error means the monitor was registered but could not be saved.
+func (m *MonitorRunner) Add(monitor Monitor) (Monitor, error) {
+	parsed, err := url.Parse(monitor.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return Monitor{}, fmt.Errorf("%w: url %q is not an absolute http or https URL", ErrInvalidMonitor, monitor.URL)
+	}
+	if monitor.WebhookURL != "" {
+		if parsed, err := url.Parse(monitor.WebhookURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return Monitor{}, fmt.Errorf("%w: webhook_url %q is not an http or https URL", ErrInvalidMonitor, monitor.WebhookURL)
+		}
+	}
+	schedule, err := ParseSchedule(monitor.Schedule)
+	if err != nil {
+		return Monitor{}, fmt.Errorf("%w: %w", ErrInvalidMonitor, err)
+	}
+
+	now := m.now()
+	monitor.NextRunAt = schedule.Next(now)
+	if monitor.NextRunAt.IsZero() {
+		return Monitor{}, fmt.Errorf("%w: schedule %q never fires", ErrInvalidMonitor, monitor.Schedule)
+	}
+	monitor.ID = newID()
+	monitor.CreatedAt = now
+	monitor.LastRunAt = time.Time{}
+	monitor.LastError = ""
+	monitor.ConsecutiveFailures = 0
+	monitor.Alerts = nil
+
+	m.mu.Lock()
+	m.monitors[monitor.ID] = &monitorEntry{Monitor: monitor, schedule: schedule}
+	err = m.save()
+	m.mu.Unlock()
+	m.notify()
+	return monitor, err
+}
+
+// Remove deletes a monitor.
+func (m *MonitorRunner) Remove(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.monitors[id]; !ok {
+		return ErrMonitorNotFound
+	}
+	delete(m.monitors, id)
+	return m.save()
+}
+
+// Get returns a monitor and its current state.
+func (m *MonitorRunner) Get(id string) (Monitor, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.monitors[id]
+	if !ok {
+		return Monitor{}, false
+	}
+	return entry.snapshot(), true
+}
+
+// List returns all monitors ordered by creation time.
+func (m *MonitorRunner) List() []Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Monitor, 0, len(m.monitors))
+	for _, entry := range m.monitors {
+		out = append(out, entry.snapshot())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// RunNow schedules an immediate run of a monitor.
+func (m *MonitorRunner) RunNow(id string) error {
+	m.mu.Lock()
+	entry, ok := m.monitors[id]
+	if ok {
+		entry.NextRunAt = m.now()
+	}
+	m.mu.Unlock()
+	if !ok {
+		return ErrMonitorNotFound
+	}
+	m.notify()
+	return nil
+}
+
+// Run starts due monitors until ctx is cancelled and then waits for
+// in-flight runs to finish.
+func (m *MonitorRunner) Run(ctx context.Context) {
+	for {
+		next := m.startDue(ctx)
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = max(next.Sub(m.now()), 0)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			m.wg.Wait()
+			return
+		case <-timer.C:
+		case <-m.wake:
+			timer.Stop()
+		}
+	}
+}
+
+// startDue starts every due monitor that is not already running and returns
+// the earliest upcoming run time.
+func (m *MonitorRunner) startDue(ctx context.Context) time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var next time.Time
+	for _, entry := range m.monitors {
+		// A zero NextRunAt means the schedule never fires again.
+		if !entry.running && !entry.NextRunAt.IsZero() && !entry.NextRunAt.After(now) {
+			entry.running = true
+			entry.NextRunAt = entry.schedule.Next(now)
+			m.wg.Add(1)
+			go func(entry *monitorEntry) {
+				defer m.wg.Done()
+				m.check(ctx, entry)
+			}(entry)
+		}
+		if entry.NextRunAt.IsZero() {
+			continue
+		}
+		if next.IsZero() || entry.NextRunAt.Before(next) {
+			next = entry.NextRunAt
+		}
+	}
+	return next
+}
+
+// check runs one analysis for entry and delivers any resulting alerts. The
+// alert state only changes once the alert announcing it is delivered, so an
+// alert that fails to deliver is evaluated, and sent, again on the next run.
+func (m *MonitorRunner) check(ctx context.Context, entry *monitorEntry) {
+	ctx = logger.ContextWithFields(ctx, logrus.Fields{"monitor_id": entry.ID})
+	dto, err := m.analyze(ctx, entry.URL)
+
+	m.mu.Lock()
+	transitions := m.evaluate(entry, dto, err)
+	webhookURL := entry.WebhookURL
+	if webhookURL == "" {
+		webhookURL = m.config.WebhookURL
+	}
+	m.mu.Unlock()
+
+	var delivered []alertTransition
+	for _, transition := range transitions {
+		event := transition.event
+		log := logger.WithContext(ctx).WithFields(logrus.Fields{"event": event.Event, "condition": event.Condition})
+		if webhookURL == "" {
+			log.Warn(event.Message)
+			delivered = append(delivered, transition)
+			continue
+		}
+		if err := m.send(ctx, webhookURL, newID(), event); err != nil {
+			log.WithField("error", err).Error("Failed to deliver alert, retrying on the next run")
+			continue
+		}
+		log.Info("Alert delivered")
+		delivered = append(delivered, transition)
+	}
+
+	m.mu.Lock()
+	for _, transition := range delivered {
+		if transition.state == nil {
+			delete(entry.Alerts, transition.event.Condition)
+		} else {
+			entry.Alerts[transition.event.Condition] = transition.state
+		}
+	}
+	entry.running = false
+	if _, ok := m.monitors[entry.ID]; ok {
+		if err := m.save(); err != nil {
+			logger.WithContext(ctx).WithField("error", err).Warn("Failed to save monitors")
+		}
+	}
+	m.mu.Unlock()
+	m.notify()
+}
+
+// alertTransition is an alert to send and the state its condition moves to
+// once it is delivered. A nil state means the alert resolved.
+type alertTransition struct {
+	event AlertEvent
+	state *AlertState
+}
+
+// evaluate updates the run state of entry with the outcome of a run and
+// returns the alerts to send. Changes to firing alerts that need no delivery
+// are applied directly. Callers must hold m.mu.
+func (m *MonitorRunner) evaluate(entry *monitorEntry, dto *AnalysisServiceResultDTO, runErr error) []alertTransition {
+	now := m.now()
+	entry.LastRunAt = now
+	if entry.Alerts == nil {
+		entry.Alerts = make(map[string]*AlertState)
+	}
+	event := func(name, condition, message string) AlertEvent {
+		since := now
+		if state := entry.Alerts[condition]; state != nil {
+			since = state.Since
+		}
+		return AlertEvent{Event: name, MonitorID: entry.ID, URL: entry.URL, Condition: condition, Message: message, Since: since, At: now}
+	}
+
+	var transitions []alertTransition
+	if runErr != nil {
+		entry.LastError = runErr.Error()
+		entry.ConsecutiveFailures++
+		threshold := entry.Conditions.FailedRuns
+		if threshold > 0 && entry.ConsecutiveFailures >= threshold && entry.Alerts[ConditionAnalysisFailed] == nil {
+			transitions = append(transitions, alertTransition{
+				event: event(EventAlertFiring, ConditionAnalysisFailed,
+					fmt.Sprintf("Analysis of %s failed %d times in a row: %s", entry.URL, entry.ConsecutiveFailures, runErr)),
+				state: &AlertState{Since: now},
+			})
+		}
+		return transitions
+	}
+
+	entry.LastError = ""
+	entry.ConsecutiveFailures = 0
+	if entry.Alerts[ConditionAnalysisFailed] != nil {
+		transitions = append(transitions, alertTransition{
+			event: event(EventAlertResolved, ConditionAnalysisFailed,
+				fmt.Sprintf("Analysis of %s succeeded again", entry.URL)),
+		})
+	}
+
+	// Compare with the links reported by a firing alert so that only new
+	// breakage triggers another delivery, otherwise with the previous run.
+	broken := sortedKeys(brokenSet(dto))
+	var previous []string
+	if state := entry.Alerts[ConditionBrokenLinks]; state != nil {
+		previous = state.Links
+	} else if entry.previous != nil {
+		previous = sortedKeys(brokenSet(entry.previous))
+	}
+	newlyBroken := missingFrom(toSet(broken), toSet(previous))
+	newlyFixed := missingFrom(toSet(previous), toSet(broken))
+	entry.previous = dto
+
+	threshold := entry.Conditions.BrokenLinksAbove
+	state := entry.Alerts[ConditionBrokenLinks]
+	switch {
+	case threshold >= 0 && len(broken) > threshold:
+		next := &AlertState{Since: now, Links: broken}
+		if state != nil {
+			next.Since = state.Since
+		}
+		if state != nil && len(newlyBroken) == 0 {
+			// Only fixes since the last delivery: nothing to announce.
+			entry.Alerts[ConditionBrokenLinks] = next
+			break
+		}
+		e := event(EventAlertFiring, ConditionBrokenLinks,
+			fmt.Sprintf("%s has %d inaccessible links", entry.URL, len(broken)))
+		e.BrokenLinks, e.NewlyBroken, e.NewlyFixed = broken, newlyBroken, newlyFixed
+		transitions = append(transitions, alertTransition{event: e, state: next})
+	case state != nil:
+		e := event(EventAlertResolved, ConditionBrokenLinks,
+			fmt.Sprintf("%s has %d inaccessible links", entry.URL, len(broken)))
+		e.BrokenLinks, e.NewlyFixed = broken, newlyFixed
+		transitions = append(transitions, alertTransition{event: e})
+	}
+	return transitions
+}
+
+func (e *monitorEntry) snapshot() Monitor {
+	monitor := e.Monitor
+	if e.Alerts != nil {
+		monitor.Alerts = make(map[string]*AlertState, len(e.Alerts))
+		for condition, state := range e.Alerts {
+			copied := *state
+			copied.Links = slices.Clone(state.Links)
+			monitor.Alerts[condition] = &copied
+		}
+	}
+	return monitor
+}
+
+// save writes all monitors to the configured file. Callers must hold m.mu.
+func (m *MonitorRunner) save() error {
+	if m.config.Path == "" {
+		return nil
+	}
+	monitors := make([]Monitor, 0, len(m.monitors))
+	for _, entry := range m.monitors {
+		monitors = append(monitors, entry.Monitor)
+	}
+	sort.Slice(monitors, func(i, j int) bool { return monitors[i].CreatedAt.Before(monitors[j].CreatedAt) })
+	data, err := json.MarshalIndent(monitors, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(m.config.Path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".monitors-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), m.config.Path)
+}
+
+// notify wakes Run so that it picks up new or rescheduled monitors.
+func (m *MonitorRunner) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	return missingFrom(set, nil)
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scriptedMonitor returns a runner whose analyses return the inaccessible
+// links or errors queued in results, and which records delivered alerts.
+func scriptedMonitor(t *testing.T, config MonitorConfig) (*MonitorRunner, func(broken []string, err error), func() []AlertEvent) {
+	t.Helper()
+	var mu sync.Mutex
+	var queued []func() (*AnalysisServiceResultDTO, error)
+	var sent []AlertEvent
+
+	runner, err := NewMonitorRunner(config, func(context.Context, string) (*AnalysisServiceResultDTO, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		next := queued[0]
+		queued = queued[1:]
+		return next()
+	})
+	if err != nil {
+		t.Fatalf("NewMonitorRunner() returned error: %v", err)
+	}
+	runner.send = func(_ context.Context, _, _ string, event AlertEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, event)
+		return nil
+	}
+
+	queue := func(broken []string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		queued = append(queued, func() (*AnalysisServiceResultDTO, error) {
+			if err != nil {
+				return nil, err
+			}
+			return &AnalysisServiceResultDTO{InaccessibleExternalLinks: broken}, nil
+		})
+	}
+	drain := func() []AlertEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		out := sent
+		sent = nil
+		return out
+	}
+	return runner, queue, drain
+}
+
+func addTestMonitor(t *testing.T, runner *MonitorRunner, conditions AlertConditions) *monitorEntry {
+	t.Helper()
+	monitor, err := runner.Add(Monitor{URL: "https://example.com", Schedule: "@every 1h", Conditions: conditions, WebhookURL: "https://hooks.example.com"})
+	if err != nil {
+		t.Fatalf("Add() returned error: %v", err)
+	}
+	return runner.monitors[monitor.ID]
+}
+
+func TestMonitor_BrokenLinksAlertLifecycle(t *testing.T) {
+	runner, queue, drain := scriptedMonitor(t, MonitorConfig{})
+	entry := addTestMonitor(t, runner, AlertConditions{BrokenLinksAbove: 0})
+	ctx := context.Background()
+
+	queue(nil, nil)
+	runner.check(ctx, entry)
+	if events := drain(); len(events) != 0 {
+		t.Fatalf("Expected no alerts for a healthy page, got %+v", events)
+	}
+
+	queue([]string{"https://a.example.com"}, nil)
+	runner.check(ctx, entry)
+	events := drain()
+	if len(events) != 1 || events[0].Event != EventAlertFiring || events[0].Condition != ConditionBrokenLinks {
+		t.Fatalf("Expected a broken_links alert, got %+v", events)
+	}
+	if !slices.Equal(events[0].NewlyBroken, []string{"https://a.example.com"}) {
+		t.Errorf("Expected newly broken link in payload, got %v", events[0].NewlyBroken)
+	}
+
+	queue([]string{"https://a.example.com"}, nil)
+	runner.check(ctx, entry)
+	if events := drain(); len(events) != 0 {
+		t.Fatalf("Expected a duplicate alert to be suppressed, got %+v", events)
+	}
+
+	queue([]string{"https://a.example.com", "https://b.example.com"}, nil)
+	runner.check(ctx, entry)
+	events = drain()
+	if len(events) != 1 || !slices.Equal(events[0].NewlyBroken, []string{"https://b.example.com"}) {
+		t.Fatalf("Expected an alert for the newly broken link only, got %+v", events)
+	}
+
+	queue(nil, nil)
+	runner.check(ctx, entry)
+	events = drain()
+	if len(events) != 1 || events[0].Event != EventAlertResolved || len(events[0].NewlyFixed) != 2 {
+		t.Fatalf("Expected a recovery notification, got %+v", events)
+	}
+	if monitor, _ := runner.Get(entry.ID); len(monitor.Alerts) != 0 {
+		t.Errorf("Expected no firing alerts after recovery, got %+v", monitor.Alerts)
+	}
+}
+
+func TestMonitor_FailedRunsAlert(t *testing.T) {
+	runner, queue, drain := scriptedMonitor(t, MonitorConfig{})
+	entry := addTestMonitor(t, runner, AlertConditions{BrokenLinksAbove: -1, FailedRuns: 2})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		queue(nil, errors.New("connection refused"))
+		runner.check(ctx, entry)
+	}
+	events := drain()
+	if len(events) != 1 || events[0].Condition != ConditionAnalysisFailed || events[0].Event != EventAlertFiring {
+		t.Fatalf("Expected one analysis_failed alert after the second failure, got %+v", events)
+	}
+	if monitor, _ := runner.Get(entry.ID); monitor.ConsecutiveFailures != 3 || monitor.LastError != "connection refused" {
+		t.Errorf("Unexpected monitor state: %+v", monitor)
+	}
+
+	queue([]string{"https://a.example.com"}, nil)
+	runner.check(ctx, entry)
+	events = drain()
+	if len(events) != 1 || events[0].Event != EventAlertResolved {
+		t.Fatalf("Expected only a recovery notification with broken_links disabled, got %+v", events)
+	}
+}
+
+func TestMonitor_RetriesUndeliveredAlerts(t *testing.T) {
+	runner, queue, drain := scriptedMonitor(t, MonitorConfig{})
+	entry := addTestMonitor(t, runner, AlertConditions{BrokenLinksAbove: 0})
+	ctx := context.Background()
+	deliver := runner.send
+	runner.send = func(context.Context, string, string, AlertEvent) error {
+		return errors.New("webhook unavailable")
+	}
+
+	queue([]string{"https://a.example.com"}, nil)
+	runner.check(ctx, entry)
+	if monitor, _ := runner.Get(entry.ID); len(monitor.Alerts) != 0 {
+		t.Fatalf("Expected no alert state before the alert is delivered, got %+v", monitor.Alerts)
+	}
+
+	runner.send = deliver
+	queue([]string{"https://a.example.com"}, nil)
+	runner.check(ctx, entry)
+	events := drain()
+	if len(events) != 1 || events[0].Event != EventAlertFiring || !slices.Equal(events[0].BrokenLinks, []string{"https://a.example.com"}) {
+		t.Fatalf("Expected the undelivered alert to be sent on the next run, got %+v", events)
+	}
+	if monitor, _ := runner.Get(entry.ID); monitor.Alerts[ConditionBrokenLinks] == nil {
+		t.Errorf("Expected the alert to be firing once delivered, got %+v", monitor.Alerts)
+	}
+}
+
+func TestMonitor_RejectsSchedulesThatNeverFire(t *testing.T) {
+	runner, _, _ := scriptedMonitor(t, MonitorConfig{})
+	if _, err := runner.Add(Monitor{URL: "https://example.com", Schedule: "0 0 30 2 *"}); !errors.Is(err, ErrInvalidMonitor) {
+		t.Fatalf("Expected ErrInvalidMonitor for a schedule that never fires, got %v", err)
+	}
+
+	// A monitor whose schedule has no further runs must not be started
+	// over and over.
+	entry := addTestMonitor(t, runner, DefaultAlertConditions())
+	entry.NextRunAt = time.Time{}
+	if next := runner.startDue(context.Background()); !next.IsZero() || entry.running {
+		t.Errorf("Expected a monitor without a next run to stay idle, got next %v, running %t", next, entry.running)
+	}
+}
+
+func TestMonitor_PersistsMonitorsAndAlertState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "monitors.json")
+	runner, queue, _ := scriptedMonitor(t, MonitorConfig{Path: path})
+	entry := addTestMonitor(t, runner, DefaultAlertConditions())
+
+	queue([]string{"https://a.example.com"}, nil)
+	runner.check(context.Background(), entry)
+
+	reloaded, queue, drain := scriptedMonitor(t, MonitorConfig{Path: path})
+	monitor, ok := reloaded.Get(entry.ID)
+	if !ok || monitor.Alerts[ConditionBrokenLinks] == nil {
+		t.Fatalf("Expected monitor and firing alert to be reloaded, got %+v", monitor)
+	}
+
+	queue([]string{"https://a.example.com"}, nil)
+	reloaded.check(context.Background(), reloaded.monitors[entry.ID])
+	if events := drain(); len(events) != 0 {
+		t.Errorf("Expected alert deduplication to survive a restart, got %+v", events)
+	}
+
+	if err := reloaded.Remove(entry.ID); err != nil {
+		t.Fatalf("Remove() returned error: %v", err)
+	}
+	again, _, _ := scriptedMonitor(t, MonitorConfig{Path: path})
+	if len(again.List()) != 0 {
+		t.Error("Expected removal to be persisted")
+	}
+}
+
+func TestMonitor_AddValidation(t *testing.T) {
+	runner, _, _ := scriptedMonitor(t, MonitorConfig{})
+	for _, monitor := range []Monitor{
+		{URL: "example.com", Schedule: "@hourly"},
+		{URL: "ftp://example.com", Schedule: "@hourly"},
+		{URL: "https://example.com", Schedule: "every hour"},
+		{URL: "https://example.com", Schedule: "@hourly", WebhookURL: "mailto:ops@example.com"},
+	} {
+		if _, err := runner.Add(monitor); !errors.Is(err, ErrInvalidMonitor) {
+			t.Errorf("Expected ErrInvalidMonitor for %+v, got %v", monitor, err)
+		}
+	}
+}
+
+func TestMonitor_RunExecutesDueMonitors(t *testing.T) {
+	runner, queue, drain := scriptedMonitor(t, MonitorConfig{})
+	entry := addTestMonitor(t, runner, DefaultAlertConditions())
+	queue([]string{"https://a.example.com"}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runner.Run(ctx)
+		close(done)
+	}()
+	if err := runner.RunNow(entry.ID); err != nil {
+		t.Fatalf("RunNow() returned error: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if monitor, _ := runner.Get(entry.ID); !monitor.LastRunAt.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the monitor to run")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if events := drain(); len(events) != 1 {
+		t.Errorf("Expected one alert from the scheduled run, got %+v", events)
+	}
+	if monitor, _ := runner.Get(entry.ID); !monitor.NextRunAt.After(time.Now()) {
+		t.Errorf("Expected the next run to be scheduled in the future, got %v", monitor.NextRunAt)
+	}
+}
+
+func TestMonitorConfigFromEnv_AbsolutePath(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	// Setenv restores MONITORS_PATH once the test ends.
+	t.Setenv("MONITORS_PATH", "")
+	os.Unsetenv("MONITORS_PATH")
+	if config := MonitorConfigFromEnv(); config.Path != filepath.Join(dataHome, "web-page-analyzer", "monitors.json") {
+		t.Errorf("Expected monitors in the data directory, got %q", config.Path)
+	}
+	t.Setenv("MONITORS_PATH", filepath.Join("data", "monitors.json"))
+	if config := MonitorConfigFromEnv(); !filepath.IsAbs(config.Path) {
+		t.Errorf("Expected MONITORS_PATH to be made absolute, got %q", config.Path)
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/tracing"
+)
+
+const (
+	// DefaultQueryLimit is the number of matches listed per selector when a
+	// query does not set a limit.
+	DefaultQueryLimit = 20
+	// MaxQueryLimit bounds the matches listed per selector.
+	MaxQueryLimit = 1000
+	// MaxQuerySelectors bounds the selectors run by one query.
+	MaxQuerySelectors = 20
+)
+
+// QueryResponse is the outcome of running selectors against a page.
+type QueryResponse struct {
+	// URL is the page queried; it is empty for submitted documents.
+	URL       string                 `json:"url,omitempty"`
+	Results   []analyzer.QueryResult `json:"results"`
+	QueriedAt time.Time              `json:"queried_at"`
+}
+
+// QueryPage fetches pageURL and runs the selectors against it, listing up to
+// limit matches per selector. Queries are not cached, since they are meant
+// for ad-hoc questions about the page as it is now.
+func (s *AnalysisService) QueryPage(ctx context.Context, pageURL string, selectors []string, limit int) (*QueryResponse, error) {
+	ctx = logger.ContextWithFields(ctx, logrus.Fields{"url": pageURL, "job_id": newID()})
+	ctx, span := tracing.Start(ctx, "QueryPage", trace.WithAttributes(
+		attribute.String("url.full", pageURL),
+		attribute.Int("query.selectors", len(selectors)),
+	))
+	defer span.End()
+
+	// Reject invalid selectors without fetching the page.
+	if err := validateSelectors(selectors); err != nil {
+		return nil, err
+	}
+	response, err := s.fetchPage(ctx, pageURL)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	query, err := s.query(ctx, response.Body, selectors, limit)
+	if err != nil {
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	query.URL = pageURL
+	return query, nil
+}
+
+// QueryDocument runs the selectors against HTML read from body.
+func (s *AnalysisService) QueryDocument(ctx context.Context, body io.Reader, selectors []string, limit int) (*QueryResponse, error) {
+	ctx, span := tracing.Start(ctx, "QueryDocument", trace.WithAttributes(
+		attribute.Int("query.selectors", len(selectors)),
+	))
+	defer span.End()
+	return s.query(ctx, body, selectors, limit)
+}
+
+func (s *AnalysisService) query(ctx context.Context, body io.Reader, selectors []string, limit int) (*QueryResponse, error) {
+	start := time.Now()
+	results, err := analyzer.Query(ctx, body, selectors, min(limit, MaxQueryLimit))
+	observePhase(ctx, phaseParse, start)
+	if err != nil {
+		return nil, err
+	}
+	logger.WithContext(ctx).WithField("selectors", len(selectors)).Info("Query completed")
+	return &QueryResponse{Results: results, QueriedAt: time.Now().UTC()}, nil
+}
+
+func validateSelectors(selectors []string) error {
+	for _, selector := range selectors {
+		if _, err := analyzer.ParseSelector(selector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
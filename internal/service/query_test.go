@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+func TestQueryPage(t *testing.T) {
+	fetches := 0
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			fetches++
+			return createMockResponse(200, `<p class="price" id="price">$10</p><p class="price">$12</p>`), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+
+	result, err := service.QueryPage(context.Background(), "https://example.com/item", []string{"#price", ".price"}, 5)
+	if err != nil {
+		t.Fatalf("QueryPage() returned error: %v", err)
+	}
+	if result.URL != "https://example.com/item" || len(result.Results) != 2 {
+		t.Fatalf("Unexpected result %+v", result)
+	}
+	if result.Results[0].Matches[0].Text != "$10" || result.Results[1].Count != 2 {
+		t.Errorf("Unexpected matches %+v", result.Results)
+	}
+
+	if _, err := service.QueryPage(context.Background(), "https://example.com/item", []string{"p >"}, 5); !errors.Is(err, analyzer.ErrInvalidSelector) {
+		t.Errorf("Expected ErrInvalidSelector, got %v", err)
+	}
+	if fetches != 1 {
+		t.Errorf("Expected an invalid selector to be rejected without fetching, got %d fetches", fetches)
+	}
+}
+
+func TestQueryPage_FetchError(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(404, "not found"), nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+	if _, err := service.QueryPage(context.Background(), "https://example.com/missing", []string{"p"}, 5); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected the status code error, got %v", err)
+	}
+}
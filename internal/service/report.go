@@ -0,0 +1,591 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+// Report check rules, used as JUnit test case classes and SARIF rule IDs.
+const (
+	RulePageTitle     = "page-title"
+	RuleBrokenLink    = "broken-link"
+	RuleUncheckedLink = "unchecked-link"
+	RuleBrokenAnchor  = "broken-anchor"
+
+	RuleActiveMixedContent  = "mixed-content-active"
+	RulePassiveMixedContent = "mixed-content-passive"
+	RuleInsecureFormAction  = "insecure-form-action"
+	RuleIntegrityMismatch   = "sri-mismatch"
+	RuleCertificateExpiring = "tls-certificate-expiring"
+)
+
+// mixedContentRules maps mixed content kinds to their report rules.
+var mixedContentRules = map[string]string{
+	analyzer.MixedContentActive:  RuleActiveMixedContent,
+	analyzer.MixedContentPassive: RulePassiveMixedContent,
+	analyzer.MixedContentForm:    RuleInsecureFormAction,
+}
+
+// ReportWriter renders an analysis result in one output format.
+type ReportWriter interface {
+	// ContentType is the media type of the output.
+	ContentType() string
+	// Extension is the file extension used for downloads, without the dot.
+	Extension() string
+	Write(w io.Writer, result *AnalysisServiceResultDTO) error
+}
+
+var (
+	reportWritersMu sync.RWMutex
+	reportWriters   = map[string]ReportWriter{
+		"json":     jsonReport{},
+		"csv":      csvReport{},
+		"markdown": markdownReport{},
+		"junit":    junitReport{},
+		"sarif":    sarifReport{},
+	}
+)
+
+// RegisterReportWriter makes a report format available under name, replacing
+// any writer already registered for it.
+func RegisterReportWriter(name string, writer ReportWriter) {
+	reportWritersMu.Lock()
+	defer reportWritersMu.Unlock()
+	reportWriters[name] = writer
+}
+
+// ReportWriterFor returns the writer registered for format.
+func ReportWriterFor(format string) (ReportWriter, error) {
+	reportWritersMu.RLock()
+	defer reportWritersMu.RUnlock()
+	writer, ok := reportWriters[strings.ToLower(format)]
+	if !ok {
+		return nil, fmt.Errorf("unknown report format %q: expected one of %s", format, strings.Join(reportFormatsLocked(), ", "))
+	}
+	return writer, nil
+}
+
+// ReportFormats lists the registered report formats in alphabetical order.
+func ReportFormats() []string {
+	reportWritersMu.RLock()
+	defer reportWritersMu.RUnlock()
+	return reportFormatsLocked()
+}
+
+func reportFormatsLocked() []string {
+	formats := make([]string, 0, len(reportWriters))
+	for name := range reportWriters {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// reportCheck is one pass/fail check derived from a result. JUnit reports
+// every check; SARIF and Markdown report the ones that did not pass.
+type reportCheck struct {
+	Rule    string
+	Name    string
+	Failed  bool
+	Skipped bool
+	Message string
+	// Level overrides the SARIF level derived from the rule. It is set for
+	// module findings, which carry their own severity.
+	Level string
+	// Element locates a module finding or mixed content within the page.
+	Element string
+}
+
+// reportLink is a link of the page together with its check, if any.
+type reportLink struct {
+	URL      string
+	Internal bool
+	Result   *LinkCheckResult
+}
+
+// reportLinks lists every link of result once, in page order. Relative
+// internal links are resolved against the page, as buildResult does, so they
+// match their checks.
+func reportLinks(result *AnalysisServiceResultDTO) []reportLink {
+	checks := make(map[string]*LinkCheckResult, len(result.LinkResults))
+	for i := range result.LinkResults {
+		checks[result.LinkResults[i].URL] = &result.LinkResults[i]
+	}
+	var page *url.URL
+	if result.URL != "" {
+		page, _ = url.Parse(result.URL)
+	}
+
+	seen := make(map[string]bool)
+	var links []reportLink
+	add := func(link string, internal bool) {
+		link = resolveReportLink(page, link, result.Document)
+		if seen[link] {
+			return
+		}
+		seen[link] = true
+		links = append(links, reportLink{URL: link, Internal: internal, Result: checks[link]})
+	}
+	for _, link := range result.InternalLinks {
+		add(link, true)
+	}
+	for _, link := range result.ExternalLinks {
+		add(link, false)
+	}
+	// Results loaded from older records may have checks without link lists.
+	for _, check := range result.LinkResults {
+		add(check.URL, hostOf(check.URL) == hostOf(result.URL))
+	}
+	return links
+}
+
+// resolveReportLink resolves link against page the way buildResult does
+// before checking it: links starting with "/" always, and for documents every
+// other relative link except fragment-only ones.
+func resolveReportLink(page *url.URL, link string, document bool) string {
+	if page == nil || strings.HasPrefix(link, "#") || (!document && !strings.HasPrefix(link, "/")) {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil || ref.Scheme != "" || ref.Host != "" {
+		return link
+	}
+	return page.ResolveReference(ref).String()
+}
+
+func reportChecks(result *AnalysisServiceResultDTO) []reportCheck {
+	checks := []reportCheck{{Rule: RulePageTitle, Name: "Page has a title"}}
+	if strings.TrimSpace(result.Title) == "" {
+		checks[0].Failed = true
+		checks[0].Message = "The page has no <title>"
+	}
+	for _, link := range reportLinks(result) {
+		check := reportCheck{Rule: RuleBrokenLink, Name: link.URL}
+		switch r := link.Result; {
+		case r == nil:
+			continue
+		case r.HostUnavailable:
+			check.Rule = RuleUncheckedLink
+			check.Skipped = true
+			check.Message = "Link not checked: " + errHostUnavailable
+		case !r.Accessible:
+			check.Failed = true
+			check.Message = "Link is inaccessible: " + linkStatus(*r)
+		}
+		checks = append(checks, check)
+	}
+	for _, link := range result.BrokenAnchorLinks {
+		checks = append(checks, reportCheck{
+			Rule:    RuleBrokenAnchor,
+			Name:    link,
+			Failed:  true,
+			Message: "Link points to a missing anchor",
+		})
+	}
+	for _, ref := range result.MixedContent {
+		message := fmt.Sprintf("%s mixed content: <%s> loads %s over plain HTTP", strings.ToUpper(ref.Kind[:1])+ref.Kind[1:], ref.Element, ref.URL)
+		if ref.Kind == analyzer.MixedContentForm {
+			message = fmt.Sprintf("<%s> submits to %s over plain HTTP", ref.Element, ref.URL)
+		}
+		checks = append(checks, reportCheck{
+			Rule:    mixedContentRules[ref.Kind],
+			Name:    ref.URL,
+			Failed:  true,
+			Message: message + " at " + ref.Path,
+			Element: ref.Path,
+		})
+	}
+	hosts := result.LinkHostsTLS
+	if result.TLS != nil {
+		hosts = append([]TLSInfo{*result.TLS}, hosts...)
+	}
+	for _, host := range hosts {
+		if host.ExpiresSoon {
+			checks = append(checks, reportCheck{
+				Rule:    RuleCertificateExpiring,
+				Name:    host.Host,
+				Failed:  true,
+				Message: fmt.Sprintf("TLS certificate expires in %d days, on %s", host.DaysRemaining, host.NotAfter.UTC().Format(time.DateOnly)),
+			})
+		}
+	}
+	for _, check := range result.Integrity {
+		if check.Status == IntegrityMismatch {
+			checks = append(checks, reportCheck{
+				Rule:    RuleIntegrityMismatch,
+				Name:    check.URL,
+				Failed:  true,
+				Message: fmt.Sprintf("Resource does not match its integrity attribute: its %s digest is %s", check.Algorithm, check.Digest),
+			})
+		}
+	}
+	// Informational findings pass: they are reported, but fail no build.
+	for _, finding := range result.Findings {
+		check := reportCheck{
+			Rule:    finding.Module + "/" + finding.Rule,
+			Name:    finding.Message,
+			Failed:  finding.Severity != analyzer.SeverityInfo,
+			Message: finding.Message,
+			Level:   "warning",
+			Element: finding.Element,
+		}
+		if level, ok := sarifLevels[finding.Severity]; ok {
+			check.Level = level
+		}
+		if finding.Element != "" {
+			check.Name += " " + finding.Element
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// reportSubject names what was analyzed.
+func reportSubject(result *AnalysisServiceResultDTO) string {
+	if result.URL == "" {
+		return "submitted HTML"
+	}
+	return result.URL
+}
+
+func linkStatus(r LinkCheckResult) string {
+	switch {
+	case r.StatusCode != 0:
+		return fmt.Sprintf("HTTP %d", r.StatusCode)
+	case r.TLSFailure != "" && r.Error != "":
+		return fmt.Sprintf("TLS %s (%s)", r.TLSFailure, r.Error)
+	case r.TLSFailure != "":
+		return "TLS " + r.TLSFailure
+	}
+	return r.Error
+}
+
+type jsonReport struct{}
+
+func (jsonReport) ContentType() string { return "application/json" }
+func (jsonReport) Extension() string   { return "json" }
+
+func (jsonReport) Write(w io.Writer, result *AnalysisServiceResultDTO) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// csvReport writes one row per link.
+type csvReport struct{}
+
+func (csvReport) ContentType() string { return "text/csv; charset=utf-8" }
+func (csvReport) Extension() string   { return "csv" }
+
+func (csvReport) Write(w io.Writer, result *AnalysisServiceResultDTO) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"url", "type", "checked", "accessible", "status_code", "final_url", "error", "attempts"})
+	for _, link := range reportLinks(result) {
+		kind := "external"
+		if link.Internal {
+			kind = "internal"
+		}
+		row := []string{link.URL, kind, "false", "", "", "", "", ""}
+		if r := link.Result; r != nil && !r.HostUnavailable {
+			row[2], row[3] = "true", strconv.FormatBool(r.Accessible)
+			if r.StatusCode != 0 {
+				row[4] = strconv.Itoa(r.StatusCode)
+			}
+			row[5], row[6], row[7] = r.FinalURL, r.Error, strconv.Itoa(r.Attempts)
+		} else if r != nil {
+			row[6] = r.Error
+		}
+		for i := range row {
+			row[i] = csvSafe(row[i])
+		}
+		writer.Write(row)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// markdownReport writes a summary suitable for a pull request comment.
+type markdownReport struct{}
+
+func (markdownReport) ContentType() string { return "text/markdown; charset=utf-8" }
+func (markdownReport) Extension() string   { return "md" }
+
+func (markdownReport) Write(w io.Writer, result *AnalysisServiceResultDTO) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Web page analysis: %s\n\n", markdownEscape(reportSubject(result)))
+	b.WriteString("| Check | Result |\n|---|---|\n")
+	fmt.Fprintf(&b, "| Title | %s |\n", markdownEscape(result.Title))
+	fmt.Fprintf(&b, "| HTML version | %s |\n", markdownEscape(result.HTMLVersion))
+	fmt.Fprintf(&b, "| Login form | %s |\n", yesNo(result.HasLoginForm))
+	fmt.Fprintf(&b, "| Internal links | %d |\n", result.InternalLinksCount)
+	fmt.Fprintf(&b, "| External links | %d |\n", result.ExternalLinksCount)
+	fmt.Fprintf(&b, "| Inaccessible links | %d |\n", result.InaccessibleInternalLinksCount+result.InaccessibleExternalLinksCount)
+	if result.BrokenAnchorLinksCount > 0 {
+		fmt.Fprintf(&b, "| Links to missing anchors | %d |\n", result.BrokenAnchorLinksCount)
+	}
+	if len(result.HostUnavailableLinks) > 0 {
+		fmt.Fprintf(&b, "| Not checked (host unavailable) | %d |\n", len(result.HostUnavailableLinks))
+	}
+
+	if len(result.Headings) > 0 {
+		levels := make([]string, 0, len(result.Headings))
+		for level := range result.Headings {
+			levels = append(levels, level)
+		}
+		sort.Strings(levels)
+		b.WriteString("\n**Headings:**")
+		for _, level := range levels {
+			fmt.Fprintf(&b, " %s × %d", level, result.Headings[level])
+		}
+		b.WriteString("\n")
+	}
+
+	if security := result.Security; security != nil {
+		fmt.Fprintf(&b, "\n**Security headers:** grade %s (%d/100)\n", security.Grade, security.Score)
+		for _, check := range security.Checks {
+			if check.Status != SecurityPass {
+				fmt.Fprintf(&b, "- %s: %s\n", markdownEscape(check.Header), markdownEscape(strings.Join(check.Issues, "; ")))
+			}
+		}
+	}
+
+	if info := result.TLS; info != nil {
+		fmt.Fprintf(&b, "\n**TLS:** %s, certificate issued by %s, expires %s (%d days)\n",
+			info.Version, markdownEscape(info.Issuer), info.NotAfter.UTC().Format(time.DateOnly), info.DaysRemaining)
+	}
+
+	if content := result.Content; content != nil && content.WordCount > 0 {
+		fmt.Fprintf(&b, "\n**Content:** %d words, %d %s, %.0f%% text to HTML",
+			content.WordCount, content.SentenceCount, plural(content.SentenceCount, "sentence", "sentences"), 100*content.TextToHTMLRatio)
+		if content.Language != "" {
+			fmt.Fprintf(&b, ", language %s", content.Language)
+		}
+		if content.Readability != nil {
+			fmt.Fprintf(&b, ", Flesch reading ease %.1f", content.Readability.FleschReadingEase)
+		}
+		b.WriteString("\n")
+		if len(content.Keywords) > 0 {
+			b.WriteString("\n**Top keywords:**")
+			for i, keyword := range content.Keywords {
+				if i > 0 {
+					b.WriteString(",")
+				}
+				fmt.Fprintf(&b, " %s × %d", markdownEscape(keyword.Term), keyword.Count)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	var failed, skipped []reportCheck
+	failedFindings := 0
+	for _, check := range reportChecks(result) {
+		switch {
+		case check.Level != "":
+			// Listed in the findings table below.
+			if check.Failed {
+				failedFindings++
+			}
+		case check.Failed:
+			failed = append(failed, check)
+		case check.Skipped:
+			skipped = append(skipped, check)
+		}
+	}
+	if len(failed) == 0 && failedFindings == 0 {
+		b.WriteString("\n:white_check_mark: All checks passed.\n")
+	} else if len(failed) > 0 {
+		fmt.Fprintf(&b, "\n### :x: %d failed %s\n\n", len(failed), plural(len(failed), "check", "checks"))
+		for _, check := range failed {
+			fmt.Fprintf(&b, "- %s: %s\n", markdownEscape(check.Name), markdownEscape(check.Message))
+		}
+	}
+	if len(skipped) > 0 {
+		b.WriteString("\n<details><summary>Links not checked</summary>\n\n")
+		for _, check := range skipped {
+			fmt.Fprintf(&b, "- %s\n", markdownEscape(check.Name))
+		}
+		b.WriteString("\n</details>\n")
+	}
+	if len(result.Findings) > 0 {
+		fmt.Fprintf(&b, "\n### Findings\n\n| Severity | Module | Rule | Message | Element |\n| --- | --- | --- | --- | --- |\n")
+		for _, finding := range result.Findings {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", finding.Severity, markdownEscape(finding.Module),
+				markdownEscape(finding.Rule), markdownEscape(finding.Message), markdownEscape(finding.Element))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "|", `\|`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", "&lt;", ">", "&gt;", "\n", " ",
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "Yes"
+	}
+	return "No"
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// junitReport writes every check as a test case of a single test suite.
+type junitReport struct{}
+
+func (junitReport) ContentType() string { return "application/xml" }
+func (junitReport) Extension() string   { return "xml" }
+
+type junitTestSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func (junitReport) Write(w io.Writer, result *AnalysisServiceResultDTO) error {
+	suite := junitSuite{Name: reportSubject(result)}
+	if !result.AnalyzedAt.IsZero() {
+		suite.Timestamp = result.AnalyzedAt.UTC().Format("2006-01-02T15:04:05")
+	}
+	for _, check := range reportChecks(result) {
+		testCase := junitTestCase{Name: check.Name, ClassName: check.Rule}
+		switch {
+		case check.Failed:
+			suite.Failures++
+			testCase.Failure = &junitMessage{Message: check.Message}
+		case check.Skipped:
+			suite.Skipped++
+			testCase.Skipped = &junitMessage{Message: check.Message}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	suite.Tests = len(suite.Cases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(junitTestSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// sarifReport writes failed and skipped checks as SARIF 2.1.0 results located
+// at the analyzed page.
+type sarifReport struct{}
+
+func (sarifReport) ContentType() string { return "application/sarif+json" }
+func (sarifReport) Extension() string   { return "sarif" }
+
+var sarifRules = []map[string]any{
+	{"id": RulePageTitle, "shortDescription": map[string]string{"text": "Page has no title"}},
+	{"id": RuleBrokenLink, "shortDescription": map[string]string{"text": "Link is inaccessible"}},
+	{"id": RuleUncheckedLink, "shortDescription": map[string]string{"text": "Link could not be checked"}},
+	{"id": RuleBrokenAnchor, "shortDescription": map[string]string{"text": "Link fragment names no element on the target page"}},
+	{"id": RuleActiveMixedContent, "shortDescription": map[string]string{"text": "HTTPS page loads a script, stylesheet or frame over plain HTTP"}},
+	{"id": RulePassiveMixedContent, "shortDescription": map[string]string{"text": "HTTPS page loads an image or media over plain HTTP"}},
+	{"id": RuleInsecureFormAction, "shortDescription": map[string]string{"text": "HTTPS page submits a form over plain HTTP"}},
+	{"id": RuleIntegrityMismatch, "shortDescription": map[string]string{"text": "Script or stylesheet does not match its integrity attribute"}},
+	{"id": RuleCertificateExpiring, "shortDescription": map[string]string{"text": "TLS certificate expires soon"}},
+}
+
+// sarifLevels maps finding severities to SARIF levels.
+var sarifLevels = map[string]string{
+	analyzer.SeverityError:   "error",
+	analyzer.SeverityWarning: "warning",
+	analyzer.SeverityInfo:    "note",
+}
+
+func (sarifReport) Write(w io.Writer, result *AnalysisServiceResultDTO) error {
+	results := []map[string]any{}
+	for _, check := range reportChecks(result) {
+		level := "error"
+		switch {
+		case check.Level != "":
+			level = check.Level
+		case check.Skipped:
+			level = "note"
+		case !check.Failed:
+			continue
+		case check.Rule == RulePageTitle, check.Rule == RulePassiveMixedContent, check.Rule == RuleInsecureFormAction, check.Rule == RuleCertificateExpiring:
+			level = "warning"
+		}
+		entry := map[string]any{
+			"ruleId":  check.Rule,
+			"level":   level,
+			"message": map[string]string{"text": check.Message},
+		}
+		if result.URL != "" {
+			entry["locations"] = []map[string]any{{
+				"physicalLocation": map[string]any{"artifactLocation": map[string]string{"uri": result.URL}},
+			}}
+		}
+		switch {
+		case check.Level != "":
+			if check.Element != "" {
+				entry["partialFingerprints"] = map[string]string{"element": check.Element}
+			}
+		case check.Element != "":
+			entry["partialFingerprints"] = map[string]string{"element": check.Element, "link": check.Name}
+		case check.Rule != RulePageTitle:
+			entry["partialFingerprints"] = map[string]string{"link": check.Name}
+		}
+		results = append(results, entry)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]any{{
+			"tool": map[string]any{"driver": map[string]any{
+				"name":  "web-page-analyzer",
+				"rules": sarifRules,
+			}},
+			"results": results,
+		}},
+	})
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+func reportFixture() *AnalysisServiceResultDTO {
+	return &AnalysisServiceResultDTO{
+		URL: "https://example.com/page",
+		AnalysisResult: analyzer.AnalysisResult{
+			HTMLVersion: "HTML5",
+			Title:       "Example | Home",
+			Headings:    map[string]int{"h1": 1},
+		},
+		InternalLinksCount:             2,
+		ExternalLinksCount:             2,
+		InaccessibleExternalLinksCount: 1,
+		InternalLinks:                  []string{"/ok", "https://example.com/absolute"},
+		ExternalLinks:                  []string{"https://broken.example.org", "https://down.example.net"},
+		InaccessibleExternalLinks:      []string{"https://broken.example.org"},
+		HostUnavailableLinks:           []string{"https://down.example.net"},
+		LinkResults: []LinkCheckResult{
+			{URL: "https://example.com/ok", Accessible: true, StatusCode: 200, FinalURL: "https://example.com/ok", Attempts: 1},
+			{URL: "https://broken.example.org", StatusCode: 404, FinalURL: "https://broken.example.org", Attempts: 1},
+			{URL: "https://down.example.net", HostUnavailable: true, Error: errHostUnavailable},
+		},
+		AnalyzedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func writeReportFixture(t *testing.T, format string, result *AnalysisServiceResultDTO) []byte {
+	t.Helper()
+	writer, err := ReportWriterFor(format)
+	if err != nil {
+		t.Fatalf("ReportWriterFor(%q) returned error: %v", format, err)
+	}
+	var out bytes.Buffer
+	if err := writer.Write(&out, result); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestReport_CSVHasOneRowPerLink(t *testing.T) {
+	records, err := csv.NewReader(bytes.NewReader(writeReportFixture(t, "csv", reportFixture()))).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got error: %v", err)
+	}
+	expected := [][]string{
+		{"url", "type", "checked", "accessible", "status_code", "final_url", "error", "attempts"},
+		{"https://example.com/ok", "internal", "true", "true", "200", "https://example.com/ok", "", "1"},
+		{"https://example.com/absolute", "internal", "false", "", "", "", "", ""},
+		{"https://broken.example.org", "external", "true", "false", "404", "https://broken.example.org", "", "1"},
+		{"https://down.example.net", "external", "false", "", "", "", errHostUnavailable, ""},
+	}
+	if len(records) != len(expected) {
+		t.Fatalf("Expected %d rows, got %v", len(expected), records)
+	}
+	for i := range expected {
+		if strings.Join(records[i], ",") != strings.Join(expected[i], ",") {
+			t.Errorf("Row %d: expected %v, got %v", i, expected[i], records[i])
+		}
+	}
+}
+
+func TestReport_DocumentRelativeLinks(t *testing.T) {
+	service := &AnalysisService{httpClient: &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(200, ""), nil
+		},
+	}}
+	html := `<title>Doc</title><a href="about.html">About</a><a href="/contact">Contact</a><a href="https://other.example.org/">Other</a>`
+	result, err := service.AnalyzeDocument(context.Background(), strings.NewReader(html), DocumentOptions{
+		BaseURL:    "https://example.com/docs/index.html",
+		CheckLinks: true,
+	})
+	if err != nil {
+		t.Fatalf("AnalyzeDocument() returned error: %v", err)
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(writeReportFixture(t, "csv", result))).ReadAll()
+	if err != nil {
+		t.Fatalf("Expected valid CSV, got error: %v", err)
+	}
+	expected := []string{"https://example.com/docs/about.html", "https://example.com/contact", "https://other.example.org/"}
+	if len(records) != len(expected)+1 {
+		t.Fatalf("Expected one row per link, got %v", records)
+	}
+	for i, link := range expected {
+		if row := records[i+1]; row[0] != link || row[2] != "true" {
+			t.Errorf("Row %d: expected a checked row for %s, got %v", i+1, link, row)
+		}
+	}
+}
+
+func TestReport_JUnit(t *testing.T) {
+	var suites struct {
+		Suites []struct {
+			Name     string `xml:"name,attr"`
+			Tests    int    `xml:"tests,attr"`
+			Failures int    `xml:"failures,attr"`
+			Skipped  int    `xml:"skipped,attr"`
+			Cases    []struct {
+				Name    string    `xml:"name,attr"`
+				Failure *struct{} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(writeReportFixture(t, "junit", reportFixture()), &suites); err != nil {
+		t.Fatalf("Expected valid XML, got error: %v", err)
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("Expected one test suite, got %+v", suites)
+	}
+	suite := suites.Suites[0]
+	// The title check plus the three checked links.
+	if suite.Name != "https://example.com/page" || suite.Tests != 4 || suite.Failures != 1 || suite.Skipped != 1 {
+		t.Errorf("Unexpected suite totals: %+v", suite)
+	}
+	if suite.Cases[2].Name != "https://broken.example.org" || suite.Cases[2].Failure == nil {
+		t.Errorf("Expected the broken link to be a failed test case, got %+v", suite.Cases[2])
+	}
+}
+
+func TestReport_SARIF(t *testing.T) {
+	result := reportFixture()
+	result.Title = ""
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(writeReportFixture(t, "sarif", result), &log); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("Unexpected SARIF log: %+v", log)
+	}
+	var got []string
+	for _, r := range log.Runs[0].Results {
+		got = append(got, r.RuleID+":"+r.Level)
+		if r.Locations[0].PhysicalLocation.ArtifactLocation.URI != result.URL {
+			t.Errorf("Expected results located at the page, got %+v", r.Locations)
+		}
+	}
+	expected := "page-title:warning broken-link:error unchecked-link:note"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected results %q, got %q", expected, strings.Join(got, " "))
+	}
+}
+
+func TestReport_Findings(t *testing.T) {
+	result := reportFixture()
+	result.LinkResults = nil
+	result.Findings = []analyzer.Finding{
+		{Module: "accessibility", Rule: "img-alt", Severity: analyzer.SeverityError, Message: "Image has no alt attribute", Element: `<img src="a.png">`},
+		{Module: "seo", Rule: "canonical-missing", Severity: analyzer.SeverityInfo, Message: "No canonical URL"},
+	}
+
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				RuleID string `json:"ruleId"`
+				Level  string `json:"level"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(writeReportFixture(t, "sarif", result), &log); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	var got []string
+	for _, r := range log.Runs[0].Results {
+		got = append(got, r.RuleID+":"+r.Level)
+	}
+	expected := "accessibility/img-alt:error seo/canonical-missing:note"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected results %q, got %q", expected, strings.Join(got, " "))
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(writeReportFixture(t, "junit", result), &suites); err != nil {
+		t.Fatalf("Expected valid XML, got error: %v", err)
+	}
+	if suites.Suites[0].Failures != 1 {
+		t.Errorf("Expected only the error finding to fail, got %d failures", suites.Suites[0].Failures)
+	}
+
+	markdown := string(writeReportFixture(t, "markdown", result))
+	if !strings.Contains(markdown, "| error | accessibility | img-alt | Image has no alt attribute | &lt;img src=\"a.png\"&gt; |") {
+		t.Errorf("Expected a findings table, got:\n%s", markdown)
+	}
+}
+
+func TestReport_MixedContent(t *testing.T) {
+	result := reportFixture()
+	result.LinkResults = nil
+	result.MixedContent = []analyzer.MixedContent{
+		{URL: "http://cdn.example.com/app.js", Kind: analyzer.MixedContentActive, Element: "script", Attribute: "src", Path: "html > head > script"},
+		{URL: "http://example.com/a.png", Kind: analyzer.MixedContentPassive, Element: "img", Attribute: "src", Path: "html > body > img"},
+		{URL: "http://example.com/login", Kind: analyzer.MixedContentForm, Element: "form", Attribute: "action", Path: "html > body > form"},
+	}
+
+	var log struct {
+		Runs []struct {
+			Results []struct {
+				RuleID              string            `json:"ruleId"`
+				Level               string            `json:"level"`
+				PartialFingerprints map[string]string `json:"partialFingerprints"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(writeReportFixture(t, "sarif", result), &log); err != nil {
+		t.Fatalf("Expected valid JSON, got error: %v", err)
+	}
+	var got []string
+	for _, r := range log.Runs[0].Results {
+		got = append(got, r.RuleID+":"+r.Level)
+	}
+	expected := "mixed-content-active:error mixed-content-passive:warning insecure-form-action:warning"
+	if strings.Join(got, " ") != expected {
+		t.Errorf("Expected results %q, got %q", expected, strings.Join(got, " "))
+	}
+	if fingerprint := log.Runs[0].Results[0].PartialFingerprints; fingerprint["element"] != "html > head > script" || fingerprint["link"] != "http://cdn.example.com/app.js" {
+		t.Errorf("Expected the element path and URL as fingerprint, got %v", fingerprint)
+	}
+
+	markdown := string(writeReportFixture(t, "markdown", result))
+	if !strings.Contains(markdown, "3 failed checks") || !strings.Contains(markdown, "Active mixed content: &lt;script&gt; loads http://cdn.example.com/app.js over plain HTTP at html &gt; head &gt; script") {
+		t.Errorf("Expected the mixed content among the failed checks, got:\n%s", markdown)
+	}
+}
+
+func TestReport_MarkdownEscapesPageContent(t *testing.T) {
+	out := string(writeReportFixture(t, "markdown", reportFixture()))
+	for _, want := range []string{
+		"| Title | Example \\| Home |",
+		"| Inaccessible links | 1 |",
+		"1 failed check",
+		"- https://broken.example.org: Link is inaccessible: HTTP 404",
+		"<details><summary>Links not checked</summary>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected Markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestReportWriterFor_UnknownFormat(t *testing.T) {
+	_, err := ReportWriterFor("pdf")
+	if err == nil || !strings.Contains(err.Error(), "csv, json, junit, markdown, sarif") {
+		t.Errorf("Expected an error listing the formats, got %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultResultCacheEntries      = 256
+	defaultResultCacheTTL          = 10 * time.Minute
+	defaultResultCacheMaxDiskBytes = 256 << 20
+)
+
+// ResultCacheConfig controls the full-page result cache.
+type ResultCacheConfig struct {
+	// MaxEntries caps the number of results kept in memory.
+	MaxEntries int
+	// TTL is how long a cached analysis is served before the page is analyzed again.
+	TTL time.Duration
+	// Dir is an optional directory used as a second tier behind the in-memory LRU.
+	Dir string
+	// MaxDiskBytes caps the size of Dir; the oldest results are removed first.
+	MaxDiskBytes int64
+}
+
+// DefaultResultCacheConfig returns the configuration used when none is provided.
+func DefaultResultCacheConfig() ResultCacheConfig {
+	return ResultCacheConfig{
+		MaxEntries:   defaultResultCacheEntries,
+		TTL:          defaultResultCacheTTL,
+		MaxDiskBytes: defaultResultCacheMaxDiskBytes,
+	}
+}
+
+// ResultCacheStats is a snapshot of the result cache counters.
+type ResultCacheStats struct {
+	Entries    int    `json:"entries"`
+	MaxEntries int    `json:"max_entries"`
+	Hits       uint64 `json:"hits"`
+	DiskHits   uint64 `json:"disk_hits"`
+	Misses     uint64 `json:"misses"`
+	Evictions  uint64 `json:"evictions"`
+}
+
+// ResultCache is an LRU cache of analysis results keyed by URL and options,
+// optionally backed by a directory of JSON files. Results are kept encoded, so
+// callers always receive their own copy and cannot change a cached result.
+type ResultCache struct {
+	config ResultCacheConfig
+	now    func() time.Time
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	stats   ResultCacheStats
+}
+
+type resultCacheEntry struct {
+	Key      string          `json:"key"`
+	CachedAt time.Time       `json:"cached_at"`
+	Result   json.RawMessage `json:"result"`
+}
+
+// NewResultCache creates a result cache, creating the disk directory when one is configured.
+func NewResultCache(config ResultCacheConfig) (*ResultCache, error) {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultResultCacheEntries
+	}
+	if config.TTL <= 0 {
+		config.TTL = defaultResultCacheTTL
+	}
+	if config.MaxDiskBytes <= 0 {
+		config.MaxDiskBytes = defaultResultCacheMaxDiskBytes
+	}
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &ResultCache{
+		config:  config,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+		stats:   ResultCacheStats{MaxEntries: config.MaxEntries},
+	}, nil
+}
+
+// Get returns a copy of the cached result for pageURL and options together
+// with the time it was cached.
+func (c *ResultCache) Get(pageURL string, options AnalysisOptions) (*AnalysisServiceResultDTO, time.Time, bool) {
+	key := resultCacheKey(pageURL, options)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*resultCacheEntry)
+		if c.expired(entry) {
+			c.remove(elem)
+		} else if result, err := entry.decode(); err == nil {
+			c.order.MoveToFront(elem)
+			c.stats.Hits++
+			return result, entry.CachedAt, true
+		}
+	}
+
+	if entry, ok := c.readDisk(key); ok {
+		if result, err := entry.decode(); err == nil {
+			c.add(entry)
+			c.stats.DiskHits++
+			return result, entry.CachedAt, true
+		}
+	}
+
+	c.stats.Misses++
+	return nil, time.Time{}, false
+}
+
+// Put stores a copy of result for pageURL and options.
+func (c *ResultCache) Put(pageURL string, options AnalysisOptions, result *AnalysisServiceResultDTO) error {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	entry := &resultCacheEntry{
+		Key:      resultCacheKey(pageURL, options),
+		CachedAt: c.now(),
+		Result:   encoded,
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[entry.Key]; ok {
+		c.remove(elem)
+	}
+	c.add(entry)
+	c.mu.Unlock()
+
+	if err := c.writeDisk(entry); err != nil {
+		return err
+	}
+	return c.trimDisk(entry.Key)
+}
+
+// Stats returns a snapshot of the cache counters.
+func (c *ResultCache) Stats() ResultCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	return stats
+}
+
+// add inserts entry at the front, evicting the least recently used entry
+// when the cache is full. Callers must hold c.mu.
+func (c *ResultCache) add(entry *resultCacheEntry) {
+	c.entries[entry.Key] = c.order.PushFront(entry)
+	for c.order.Len() > c.config.MaxEntries {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// remove drops elem from memory. Callers must hold c.mu.
+func (c *ResultCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*resultCacheEntry).Key)
+}
+
+func (entry *resultCacheEntry) decode() (*AnalysisServiceResultDTO, error) {
+	var result AnalysisServiceResultDTO
+	if err := json.Unmarshal(entry.Result, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *ResultCache) expired(entry *resultCacheEntry) bool {
+	return c.now().Sub(entry.CachedAt) >= c.config.TTL
+}
+
+func (c *ResultCache) readDisk(key string) (*resultCacheEntry, bool) {
+	if c.config.Dir == "" {
+		return nil, false
+	}
+	data, err := os.ReadFile(c.diskPath(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry resultCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || len(entry.Result) == 0 {
+		return nil, false
+	}
+	if c.expired(&entry) {
+		os.Remove(c.diskPath(key))
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *ResultCache) writeDisk(entry *resultCacheEntry) error {
+	if c.config.Dir == "" {
+		return nil
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.config.Dir, ".result-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.diskPath(entry.Key))
+}
+
+// trimDisk removes the oldest results from Dir, other than the one just
+// written for keep, until it fits MaxDiskBytes.
+func (c *ResultCache) trimDisk(keep string) error {
+	if c.config.Dir == "" {
+		return nil
+	}
+	dirEntries, err := os.ReadDir(c.config.Dir)
+	if err != nil {
+		return err
+	}
+	var files []os.FileInfo
+	var total int64
+	for _, dirEntry := range dirEntries {
+		if dirEntry.IsDir() || strings.HasPrefix(dirEntry.Name(), ".") || !strings.HasSuffix(dirEntry.Name(), ".json") {
+			continue
+		}
+		info, err := dirEntry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+	slices.SortFunc(files, func(a, b os.FileInfo) int { return a.ModTime().Compare(b.ModTime()) })
+	for _, file := range files {
+		if total <= c.config.MaxDiskBytes {
+			break
+		}
+		if file.Name() == keep+".json" {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.config.Dir, file.Name())); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		total -= file.Size()
+	}
+	return nil
+}
+
+func (c *ResultCache) diskPath(key string) string {
+	return filepath.Join(c.config.Dir, key+".json")
+}
+
+// resultCacheKey hashes the normalized URL together with every option that
+// affects the result. ForceRefresh only controls cache use and is excluded.
+func resultCacheKey(pageURL string, options AnalysisOptions) string {
+	options.ForceRefresh = false
+	encoded, _ := json.Marshal(options)
+	sum := sha256.Sum256(append([]byte(normalizeLinkURL(pageURL)+"\n"), encoded...))
+	return hex.EncodeToString(sum[:])
+}
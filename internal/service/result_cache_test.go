@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+func newTestResultCache(t *testing.T, config ResultCacheConfig) *ResultCache {
+	t.Helper()
+	cache, err := NewResultCache(config)
+	if err != nil {
+		t.Fatalf("NewResultCache() returned error: %v", err)
+	}
+	return cache
+}
+
+func TestResultCache_LRUEviction(t *testing.T) {
+	cache := newTestResultCache(t, ResultCacheConfig{MaxEntries: 2, TTL: time.Hour})
+	options := AnalysisOptions{}
+
+	for _, u := range []string{"https://a.example.com", "https://b.example.com"} {
+		if err := cache.Put(u, options, &AnalysisServiceResultDTO{}); err != nil {
+			t.Fatalf("Put() returned error: %v", err)
+		}
+	}
+	// Touch a so that b becomes the least recently used entry.
+	cache.Get("https://a.example.com", options)
+	cache.Put("https://c.example.com", options, &AnalysisServiceResultDTO{})
+
+	if _, _, ok := cache.Get("https://b.example.com", options); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, _, ok := cache.Get("https://a.example.com", options); !ok {
+		t.Error("Expected recently used entry to survive eviction")
+	}
+
+	stats := cache.Stats()
+	if stats.Entries != 2 || stats.Evictions != 1 {
+		t.Errorf("Expected 2 entries and 1 eviction, got %+v", stats)
+	}
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %+v", stats)
+	}
+}
+
+func TestResultCache_TTL(t *testing.T) {
+	cache := newTestResultCache(t, ResultCacheConfig{TTL: time.Minute})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Put("https://example.com", AnalysisOptions{}, &AnalysisServiceResultDTO{})
+	now = now.Add(2 * time.Minute)
+
+	if _, _, ok := cache.Get("https://example.com", AnalysisOptions{}); ok {
+		t.Error("Expected expired entry to be a miss")
+	}
+}
+
+func TestResultCache_DiskBackend(t *testing.T) {
+	dir := t.TempDir()
+	cache := newTestResultCache(t, ResultCacheConfig{Dir: dir})
+
+	result := &AnalysisServiceResultDTO{InternalLinksCount: 3}
+	result.Title = "Cached Page"
+	if err := cache.Put("https://example.com", AnalysisOptions{}, result); err != nil {
+		t.Fatalf("Put() returned error: %v", err)
+	}
+
+	reopened := newTestResultCache(t, ResultCacheConfig{Dir: dir})
+	cached, _, ok := reopened.Get("https://EXAMPLE.com", AnalysisOptions{})
+	if !ok {
+		t.Fatal("Expected result to be loaded from disk")
+	}
+	if cached.Title != "Cached Page" || cached.InternalLinksCount != 3 {
+		t.Errorf("Unexpected cached result: %+v", cached)
+	}
+	if stats := reopened.Stats(); stats.DiskHits != 1 {
+		t.Errorf("Expected 1 disk hit, got %+v", stats)
+	}
+}
+
+func TestResultCache_ReturnsCopies(t *testing.T) {
+	cache := newTestResultCache(t, ResultCacheConfig{})
+	result := &AnalysisServiceResultDTO{InternalLinks: []string{"https://example.com/a"}}
+	cache.Put("https://example.com", AnalysisOptions{}, result)
+
+	// Neither the caller's result nor a returned copy may change the cache.
+	result.InternalLinks[0] = "changed by caller"
+	first, _, _ := cache.Get("https://example.com", AnalysisOptions{})
+	first.InternalLinks[0] = "changed by reader"
+	first.URL = "changed by reader"
+
+	second, _, _ := cache.Get("https://example.com", AnalysisOptions{})
+	if second.InternalLinks[0] != "https://example.com/a" || second.URL != "" {
+		t.Errorf("Expected the cached result to be unchanged, got %+v", second)
+	}
+}
+
+func TestResultCache_DiskSizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	cache := newTestResultCache(t, ResultCacheConfig{Dir: dir, MaxDiskBytes: 1024})
+
+	result := &AnalysisServiceResultDTO{}
+	result.Title = strings.Repeat("x", 400)
+	for i := 0; i < 5; i++ {
+		if err := cache.Put(fmt.Sprintf("https://example.com/%d", i), AnalysisOptions{}, result); err != nil {
+			t.Fatalf("Put() returned error: %v", err)
+		}
+	}
+
+	files, _ := os.ReadDir(dir)
+	var total int64
+	for _, file := range files {
+		info, _ := file.Info()
+		total += info.Size()
+	}
+	if total > 1024 || len(files) == 0 {
+		t.Errorf("Expected the disk tier to stay within 1024 bytes, got %d bytes in %d files", total, len(files))
+	}
+	reopened := newTestResultCache(t, ResultCacheConfig{Dir: dir})
+	if _, _, ok := reopened.Get("https://example.com/4", AnalysisOptions{}); !ok {
+		t.Error("Expected the latest result to stay on disk")
+	}
+}
+
+func TestAnalyzePageWithOptions_ResultCache(t *testing.T) {
+	var pageFetches int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				atomic.AddInt64(&pageFetches, 1)
+				return createMockResponse(200, sampleHTML), nil
+			}
+			return createMockResponse(200, ""), nil
+		},
+	}
+
+	service := &AnalysisService{
+		httpClient:  mockClient,
+		resultCache: newTestResultCache(t, DefaultResultCacheConfig()),
+	}
+	ctx := context.Background()
+
+	first, err := service.AnalyzePageWithOptions(ctx, "https://example.com/test", AnalysisOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+	second, err := service.AnalyzePageWithOptions(ctx, "https://example.com/test", AnalysisOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+
+	if first.FromCache {
+		t.Error("Expected first analysis not to come from the cache")
+	}
+	if !second.FromCache || second.Title != "Test Page" {
+		t.Errorf("Expected second analysis to be served from the cache, got %+v", second)
+	}
+	if pageFetches != 1 {
+		t.Errorf("Expected 1 page fetch, got %d", pageFetches)
+	}
+
+	refreshed, err := service.AnalyzePageWithOptions(ctx, "https://example.com/test", AnalysisOptions{ForceRefresh: true})
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+	if refreshed.FromCache || pageFetches != 2 {
+		t.Errorf("Expected force refresh to re-fetch the page, fromCache=%v fetches=%d", refreshed.FromCache, pageFetches)
+	}
+}
+
+func TestAnalyzePageWithOptions_Modules(t *testing.T) {
+	var pageFetches int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			if req.Method == http.MethodGet {
+				atomic.AddInt64(&pageFetches, 1)
+				return createMockResponse(200, sampleHTML), nil
+			}
+			return createMockResponse(200, ""), nil
+		},
+	}
+	service := &AnalysisService{
+		httpClient:  mockClient,
+		resultCache: newTestResultCache(t, DefaultResultCacheConfig()),
+	}
+	ctx := context.Background()
+
+	if _, err := service.AnalyzePageWithOptions(ctx, "https://example.com/test", AnalysisOptions{Modules: []string{"nope"}}); !errors.Is(err, analyzer.ErrUnknownModule) {
+		t.Errorf("Expected ErrUnknownModule, got %v", err)
+	}
+	if pageFetches != 0 {
+		t.Errorf("Expected an unknown module to be rejected before fetching, got %d fetches", pageFetches)
+	}
+
+	plain, err := service.AnalyzePageWithOptions(ctx, "https://example.com/test", AnalysisOptions{})
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+	withSEO, err := service.AnalyzePageWithOptions(ctx, "https://example.com/test", AnalysisOptions{Modules: []string{"seo", "accessibility"}})
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+	reordered, err := service.AnalyzePageWithOptions(ctx, "https://example.com/test", AnalysisOptions{Modules: []string{"accessibility", "seo"}})
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+
+	if len(plain.Findings) != 0 {
+		t.Errorf("Expected no findings without modules, got %v", plain.Findings)
+	}
+	if withSEO.FromCache || len(withSEO.Findings) == 0 {
+		t.Errorf("Expected a fresh analysis with findings for other modules, fromCache=%v findings=%v", withSEO.FromCache, withSEO.Findings)
+	}
+	if !reordered.FromCache || pageFetches != 2 {
+		t.Errorf("Expected the same modules in another order to hit the cache, fromCache=%v fetches=%d", reordered.FromCache, pageFetches)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxRetries     = 2
+	defaultRetryBaseDelay = 200 * time.Millisecond
+	defaultRetryMaxDelay  = 5 * time.Second
+)
+
+// RetryPolicy controls how failed link checks are retried. The zero value
+// disables retries.
+type RetryPolicy struct {
+	// MaxRetries is the number of additional attempts after the first one.
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on every attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff, including delays requested through Retry-After.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used when none is provided.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: defaultMaxRetries,
+		BaseDelay:  defaultRetryBaseDelay,
+		MaxDelay:   defaultRetryMaxDelay,
+	}
+}
+
+// do sends the request built by newRequest, retrying timeouts, connection
+// resets, 429 and 502/503/504 responses with exponential backoff and jitter.
+// It returns the last response or error together with the number of attempts made.
+func (p RetryPolicy) do(ctx context.Context, client interface {
+	Do(req *http.Request) (*http.Response, error)
+}, newRequest func() (*http.Request, error)) (*http.Response, int, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, attempt, err
+		}
+		resp, err := client.Do(req)
+		if attempt >= p.MaxRetries || !retryable(resp, err) {
+			return resp, attempt + 1, err
+		}
+
+		delay := p.backoff(attempt, resp)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, attempt + 1, ctx.Err()
+		}
+	}
+}
+
+// backoff returns the delay before the retry following attempt. A Retry-After
+// header on a 429 or 503 response takes precedence over the exponential delay.
+func (p RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(delay, p.MaxDelay)
+		}
+	}
+
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// Equal jitter: wait at least half of the delay, plus a random share of the rest.
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return true
+		}
+		return errors.Is(err, syscall.ECONNRESET)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given either in seconds or as an HTTP date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
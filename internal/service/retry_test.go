@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resp     *http.Response
+		err      error
+		expected bool
+	}{
+		{"OK", createMockResponse(200, ""), nil, false},
+		{"Not Found", createMockResponse(404, ""), nil, false},
+		{"Too Many Requests", createMockResponse(429, ""), nil, true},
+		{"Internal Server Error", createMockResponse(500, ""), nil, false},
+		{"Bad Gateway", createMockResponse(502, ""), nil, true},
+		{"Service Unavailable", createMockResponse(503, ""), nil, true},
+		{"Gateway Timeout", createMockResponse(504, ""), nil, true},
+		{"Connection Reset", nil, fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{"Timeout", nil, context.DeadlineExceeded, true},
+		{"Other Error", nil, errors.New("no such host"), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := retryable(tc.resp, tc.err); got != tc.expected {
+				t.Errorf("Expected retryable to be %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if delay, ok := retryAfter("3"); !ok || delay != 3*time.Second {
+		t.Errorf("Expected 3s from seconds form, got %v (ok=%v)", delay, ok)
+	}
+
+	at := time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)
+	if delay, ok := retryAfter(at); !ok || delay <= 0 || delay > 10*time.Second {
+		t.Errorf("Expected up to 10s from HTTP date form, got %v (ok=%v)", delay, ok)
+	}
+
+	if _, ok := retryAfter("soon"); ok {
+		t.Error("Expected invalid Retry-After to be ignored")
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	for attempt := 0; attempt < 6; attempt++ {
+		delay := policy.backoff(attempt, nil)
+		ceiling := min(policy.BaseDelay<<attempt, policy.MaxDelay)
+		if delay < ceiling/2 || delay > ceiling {
+			t.Errorf("Attempt %d: expected delay in [%v, %v], got %v", attempt, ceiling/2, ceiling, delay)
+		}
+	}
+
+	resp := createMockResponse(429, "")
+	resp.Header.Set("Retry-After", "120")
+	if delay := policy.backoff(0, resp); delay != time.Second {
+		t.Errorf("Expected Retry-After to be capped at MaxDelay, got %v", delay)
+	}
+}
+
+func TestRetryPolicyFromEnv_RejectsNonPositiveDelays(t *testing.T) {
+	t.Setenv("LINK_CHECK_RETRY_BASE_DELAY", "-1s")
+	t.Setenv("LINK_CHECK_RETRY_MAX_DELAY", "0s")
+	policy := RetryPolicyFromEnv()
+	if defaults := DefaultRetryPolicy(); policy.BaseDelay != defaults.BaseDelay || policy.MaxDelay != defaults.MaxDelay {
+		t.Errorf("Expected the default delays, got %+v", policy)
+	}
+	// Would panic with a negative delay.
+	policy.backoff(0, nil)
+}
+
+func TestCheckLink_RetriesTransientFailures(t *testing.T) {
+	var calls int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			if atomic.AddInt64(&calls, 1) < 3 {
+				return createMockResponse(503, ""), nil
+			}
+			return createMockResponse(200, ""), nil
+		},
+	}
+
+	service := &AnalysisService{
+		httpClient: mockClient,
+		retry:      RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	}
+
+	result := service.checkLink(context.Background(), "https://example.com/flaky")
+
+	if !result.Accessible || result.Attempts != 3 {
+		t.Errorf("Expected link to be accessible after 3 attempts, got %+v", result)
+	}
+}
+
+func TestCheckLink_GivesUpAfterMaxRetries(t *testing.T) {
+	var calls int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			atomic.AddInt64(&calls, 1)
+			return createMockResponse(504, ""), nil
+		},
+	}
+
+	service := &AnalysisService{
+		httpClient: mockClient,
+		retry:      RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond},
+	}
+
+	result := service.checkLink(context.Background(), "https://example.com/down")
+
+	if result.Accessible || result.StatusCode != 504 || calls != 2 {
+		t.Errorf("Expected an inaccessible 504 after 2 requests, got %+v with %d requests", result, calls)
+	}
+}
+
+func TestCheckLink_DoesNotRetryPermanentFailures(t *testing.T) {
+	var calls int64
+	mockClient := &MockHTTPClient{
+		DoFunc: func(_ *http.Request) (*http.Response, error) {
+			atomic.AddInt64(&calls, 1)
+			return createMockResponse(404, ""), nil
+		},
+	}
+
+	service := &AnalysisService{httpClient: mockClient, retry: DefaultRetryPolicy()}
+	service.checkLink(context.Background(), "https://example.com/missing")
+
+	if calls != 1 {
+		t.Errorf("Expected a 404 not to be retried, got %d requests", calls)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+// RulesFile is a declarative rules file, re-read whenever it changes so that
+// rules can be edited without restarting the service.
+type RulesFile struct {
+	path string
+
+	mu      sync.Mutex
+	rules   *analyzer.RuleSet
+	version string
+	modTime time.Time
+	size    int64
+}
+
+// RulesSnapshot is the rule set currently loaded from a rules file.
+type RulesSnapshot struct {
+	Path    string          `json:"path"`
+	Version string          `json:"version"`
+	Rules   []analyzer.Rule `json:"rules"`
+}
+
+// LoadRulesFile reads and compiles the rules file at path.
+func LoadRulesFile(path string) (*RulesFile, error) {
+	f := &RulesFile{path: path}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.load(info); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RulesFile) load(info os.FileInfo) error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	rules, err := analyzer.ParseRules(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", f.path, err)
+	}
+	sum := sha256.Sum256(data)
+	f.rules = rules
+	f.version = hex.EncodeToString(sum[:8])
+	f.modTime = info.ModTime()
+	f.size = info.Size()
+	return nil
+}
+
+// Current returns the rule set and a version identifying its content,
+// reloading the file first if it changed. A file that no longer loads is
+// logged and the previous rules are kept.
+func (f *RulesFile) Current() (*analyzer.RuleSet, string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, err := os.Stat(f.path)
+	if err == nil && (!info.ModTime().Equal(f.modTime) || info.Size() != f.size) {
+		err = f.load(info)
+		if err == nil {
+			logger.WithField("path", f.path).WithField("version", f.version).Info("Reloaded rules file")
+		}
+	}
+	if err != nil {
+		logger.WithField("path", f.path).WithField("error", err).Warn("Failed to reload rules file, keeping previous rules")
+		// Don't retry until the file changes again.
+		if info != nil {
+			f.modTime, f.size = info.ModTime(), info.Size()
+		}
+	}
+	return f.rules, f.version
+}
+
+// Snapshot describes the current rules.
+func (f *RulesFile) Snapshot() RulesSnapshot {
+	rules, version := f.Current()
+	return RulesSnapshot{Path: f.path, Version: version, Rules: rules.Rules()}
+}
+
+var activeRules atomic.Pointer[RulesFile]
+
+// RegisterRules makes the rules of f available as the "rules" analyzer module.
+func RegisterRules(f *RulesFile) {
+	activeRules.Store(f)
+	analyzer.RegisterModule(analyzer.RulesModule, "Team rules from "+f.path, func() analyzer.Module {
+		rules, _ := f.Current()
+		return rules.NewModule()
+	})
+}
+
+// LoadRules registers the rules file named by RULES_PATH, if any. A file that
+// fails to load is logged and no rules module is registered.
+func LoadRules() {
+	path := os.Getenv("RULES_PATH")
+	if path == "" {
+		return
+	}
+	f, err := LoadRulesFile(path)
+	if err != nil {
+		logger.WithField("path", path).WithField("error", err).Error("Failed to load rules file, continuing without rules")
+		return
+	}
+	RegisterRules(f)
+}
+
+// ActiveRules describes the registered rules, if any.
+func ActiveRules() (RulesSnapshot, bool) {
+	f := activeRules.Load()
+	if f == nil {
+		return RulesSnapshot{}, false
+	}
+	return f.Snapshot(), true
+}
+
+// rulesVersion identifies the content of the registered rules, so that
+// results are not served from the cache after the rules change.
+func rulesVersion() string {
+	f := activeRules.Load()
+	if f == nil {
+		return ""
+	}
+	_, version := f.Current()
+	return version
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+)
+
+func writeRulesFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	// Guard against coarse file system timestamps.
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Chtimes() returned error: %v", err)
+	}
+}
+
+func TestRulesFile_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	start := time.Now().Add(-time.Hour)
+	writeRulesFile(t, path, `{"rules": [{"id": "one-h1", "selector": "h1", "max": 1}]}`, start)
+
+	f, err := LoadRulesFile(path)
+	if err != nil {
+		t.Fatalf("LoadRulesFile() returned error: %v", err)
+	}
+	rules, version := f.Current()
+	if len(rules.Rules()) != 1 || version == "" {
+		t.Fatalf("Expected 1 rule and a version, got %v %q", rules.Rules(), version)
+	}
+
+	writeRulesFile(t, path, `{"rules": [{"id": "one-h1", "selector": "h1", "max": 1}, {"id": "no-blink", "selector": "blink"}]}`, start.Add(time.Minute))
+	rules, reloaded := f.Current()
+	if len(rules.Rules()) != 2 || reloaded == version {
+		t.Errorf("Expected the changed file to be reloaded, got %v %q", rules.Rules(), reloaded)
+	}
+
+	writeRulesFile(t, path, `{"rules": [{"id": "broken", "selector": "h1 >"}]}`, start.Add(2*time.Minute))
+	rules, kept := f.Current()
+	if len(rules.Rules()) != 2 || kept != reloaded {
+		t.Errorf("Expected an invalid file to keep the previous rules, got %v %q", rules.Rules(), kept)
+	}
+
+	if _, err := LoadRulesFile(path); err == nil {
+		t.Error("Expected LoadRulesFile() to reject an invalid file")
+	}
+}
+
+func TestAnalyzePageWithOptions_RulesVersionInCacheKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	start := time.Now().Add(-time.Hour)
+	writeRulesFile(t, path, `{"rules": [{"id": "one-h1", "selector": "h1", "max": 1}]}`, start)
+	f, err := LoadRulesFile(path)
+	if err != nil {
+		t.Fatalf("LoadRulesFile() returned error: %v", err)
+	}
+	RegisterRules(f)
+	defer activeRules.Store(nil)
+
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			return createMockResponse(200, `<html><title>T</title><h1>A</h1><p>x</p></html>`), nil
+		},
+	}
+	service := &AnalysisService{
+		httpClient:  mockClient,
+		resultCache: newTestResultCache(t, DefaultResultCacheConfig()),
+	}
+	ctx := context.Background()
+	options := AnalysisOptions{Modules: []string{analyzer.RulesModule}}
+
+	first, err := service.AnalyzePageWithOptions(ctx, "https://example.com/", options)
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+	if len(first.Findings) != 0 {
+		t.Errorf("Expected no findings, got %v", first.Findings)
+	}
+
+	writeRulesFile(t, path, `{"rules": [{"id": "no-paragraphs", "selector": "p"}]}`, start.Add(time.Minute))
+	second, err := service.AnalyzePageWithOptions(ctx, "https://example.com/", options)
+	if err != nil {
+		t.Fatalf("AnalyzePageWithOptions() returned error: %v", err)
+	}
+	if second.FromCache || len(second.Findings) != 1 || second.Findings[0].Rule != "no-paragraphs" {
+		t.Errorf("Expected changed rules to bypass the cached result, fromCache=%v findings=%v", second.FromCache, second.Findings)
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// Thursday 2026-01-01 10:17 UTC.
+	start := time.Date(2026, 1, 1, 10, 17, 30, 0, time.UTC)
+
+	testCases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"@every 15m", start.Add(15 * time.Minute)},
+		{"*/15 * * * *", time.Date(2026, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2026, 1, 1, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"30 9 * * 1-5", time.Date(2026, 1, 2, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2026, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 9 1,15 * *", time.Date(2026, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches.
+		{"0 0 13 * 5", time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			schedule, err := ParseSchedule(tc.spec)
+			if err != nil {
+				t.Fatalf("ParseSchedule() returned error: %v", err)
+			}
+			if next := schedule.Next(start); !next.Equal(tc.expected) {
+				t.Errorf("Next() = %v, expected %v", next, tc.expected)
+			}
+		})
+	}
+}
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"@every 10s",
+		"@every soon",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package service
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Security header check statuses.
+const (
+	SecurityPass = "pass"
+	SecurityWarn = "warn"
+	SecurityFail = "fail"
+)
+
+// minHSTSMaxAge is the HSTS lifetime, in seconds, below which it is flagged:
+// 180 days.
+const minHSTSMaxAge = 180 * 24 * 60 * 60
+
+// SecurityReport grades the security headers a page was served with.
+type SecurityReport struct {
+	// Score is between 0 and 100, the sum of the points of the checks.
+	Score  int                   `json:"score"`
+	Grade  string                `json:"grade"`
+	Checks []SecurityHeaderCheck `json:"checks"`
+	// CSP is the parsed Content-Security-Policy, or the report-only policy
+	// if there is no enforced one.
+	CSP     *ContentSecurityPolicy `json:"csp,omitempty"`
+	Cookies []CookieCheck          `json:"cookies,omitempty"`
+}
+
+// SecurityHeaderCheck is the audit of one header, or of related headers such
+// as the two ways to control framing.
+type SecurityHeaderCheck struct {
+	Header    string   `json:"header"`
+	Value     string   `json:"value,omitempty"`
+	Status    string   `json:"status"`
+	Points    int      `json:"points"`
+	MaxPoints int      `json:"max_points"`
+	Issues    []string `json:"issues,omitempty"`
+	// Recommendation says how to get the full points.
+	Recommendation string `json:"recommendation,omitempty"`
+}
+
+// ContentSecurityPolicy maps the directives of a policy to their sources.
+// When several policies are sent, the first occurrence of a directive wins.
+type ContentSecurityPolicy struct {
+	ReportOnly bool                `json:"report_only,omitempty"`
+	Directives map[string][]string `json:"directives"`
+}
+
+// CookieCheck is the audit of a cookie set by the page.
+type CookieCheck struct {
+	Name     string   `json:"name"`
+	Secure   bool     `json:"secure"`
+	HTTPOnly bool     `json:"http_only"`
+	SameSite string   `json:"same_site,omitempty"`
+	Issues   []string `json:"issues,omitempty"`
+}
+
+// ParseCSP parses a Content-Security-Policy header value.
+func ParseCSP(value string) *ContentSecurityPolicy {
+	policy := &ContentSecurityPolicy{Directives: make(map[string][]string)}
+	for _, directive := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		fields := strings.Fields(directive)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, ok := policy.Directives[name]; !ok {
+			policy.Directives[name] = fields[1:]
+		}
+	}
+	return policy
+}
+
+// sources returns the sources of directive, or of default-src if the
+// directive falls back to it.
+func (p *ContentSecurityPolicy) sources(directive string) ([]string, bool) {
+	if sources, ok := p.Directives[directive]; ok {
+		return sources, true
+	}
+	sources, ok := p.Directives["default-src"]
+	return sources, ok
+}
+
+// AuditSecurityHeaders grades header, the response headers of pageURL.
+func AuditSecurityHeaders(header http.Header, pageURL string) *SecurityReport {
+	report := &SecurityReport{}
+	parsed, err := url.Parse(pageURL)
+	https := err == nil && parsed.Scheme == "https"
+
+	policyValue := strings.Join(header.Values("Content-Security-Policy"), ", ")
+	if policyValue != "" {
+		report.CSP = ParseCSP(policyValue)
+	} else if reportOnly := strings.Join(header.Values("Content-Security-Policy-Report-Only"), ", "); reportOnly != "" {
+		report.CSP = ParseCSP(reportOnly)
+		report.CSP.ReportOnly = true
+		policyValue = reportOnly
+	}
+
+	report.Checks = []SecurityHeaderCheck{
+		auditCSP(report.CSP, policyValue),
+		auditHSTS(header.Get("Strict-Transport-Security"), https),
+		auditFraming(header.Get("X-Frame-Options"), report.CSP),
+		auditValue("X-Content-Type-Options", header.Get("X-Content-Type-Options"), 10, 0, []string{"nosniff"}, nil,
+			"Send X-Content-Type-Options: nosniff so browsers do not guess content types"),
+		// Browsers default to strict-origin-when-cross-origin.
+		auditValue("Referrer-Policy", header.Get("Referrer-Policy"), 10, 5,
+			[]string{"no-referrer", "same-origin", "strict-origin", "strict-origin-when-cross-origin"},
+			[]string{"unsafe-url", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin"},
+			"Send Referrer-Policy: strict-origin-when-cross-origin or stricter so full URLs do not leak to other sites"),
+		auditPresent("Permissions-Policy", header.Get("Permissions-Policy"), 5,
+			"Send a Permissions-Policy disabling the browser features the page does not use, e.g. camera=(), microphone=(), geolocation=()"),
+		auditValue("Cross-Origin-Opener-Policy", header.Get("Cross-Origin-Opener-Policy"), 5, 0,
+			[]string{"same-origin", "same-origin-allow-popups"}, []string{"unsafe-none"},
+			"Send Cross-Origin-Opener-Policy: same-origin to isolate the page from cross-origin windows"),
+		auditValue("Cross-Origin-Embedder-Policy", header.Get("Cross-Origin-Embedder-Policy"), 5, 0,
+			[]string{"require-corp", "credentialless"}, []string{"unsafe-none"},
+			"Send Cross-Origin-Embedder-Policy: require-corp (or credentialless) once every embedded resource allows it"),
+	}
+	cookies, cookieCheck := auditCookies(header.Values("Set-Cookie"), https)
+	report.Cookies = cookies
+	report.Checks = append(report.Checks, cookieCheck)
+
+	for _, check := range report.Checks {
+		report.Score += check.Points
+	}
+	report.Grade = securityGrade(report.Score)
+	return report
+}
+
+func securityGrade(score int) string {
+	switch {
+	case score >= 95:
+		return "A+"
+	case score >= 85:
+		return "A"
+	case score >= 70:
+		return "B"
+	case score >= 55:
+		return "C"
+	case score >= 40:
+		return "D"
+	}
+	return "F"
+}
+
+// finish sets the status of check from its points and issues.
+func (check SecurityHeaderCheck) finish() SecurityHeaderCheck {
+	check.Points = max(check.Points, 0)
+	switch {
+	case check.Points == check.MaxPoints && len(check.Issues) == 0:
+		check.Status = SecurityPass
+		check.Recommendation = ""
+	case check.Points == 0:
+		check.Status = SecurityFail
+	default:
+		check.Status = SecurityWarn
+	}
+	return check
+}
+
+func auditCSP(policy *ContentSecurityPolicy, value string) SecurityHeaderCheck {
+	check := SecurityHeaderCheck{Header: "Content-Security-Policy", Value: value, MaxPoints: 25, Points: 25,
+		Recommendation: "Send a Content-Security-Policy with default-src, script-src using nonces or hashes, object-src 'none' and base-uri 'self'"}
+	if policy == nil {
+		check.Points = 0
+		check.Issues = []string{"No Content-Security-Policy"}
+		return check.finish()
+	}
+	if policy.ReportOnly {
+		check.Header = "Content-Security-Policy-Report-Only"
+		check.Points = 5
+		check.Issues = append(check.Issues, "The policy is only reported, not enforced")
+	}
+	deduct := func(points int, issue string) {
+		check.Points -= points
+		check.Issues = append(check.Issues, issue)
+	}
+
+	scripts, ok := policy.sources("script-src")
+	if !ok {
+		deduct(10, "Neither script-src nor default-src restricts scripts")
+	}
+	// 'unsafe-inline' is ignored by browsers that support nonces or hashes
+	// when one is present.
+	nonceOrHash := slices.ContainsFunc(scripts, func(s string) bool {
+		s = strings.ToLower(s)
+		return strings.HasPrefix(s, "'nonce-") || strings.HasPrefix(s, "'sha256-") || strings.HasPrefix(s, "'sha384-") || strings.HasPrefix(s, "'sha512-")
+	})
+	for _, source := range scripts {
+		switch strings.ToLower(source) {
+		case "'unsafe-inline'":
+			if !nonceOrHash {
+				deduct(10, "script-src allows 'unsafe-inline', so injected inline scripts run")
+			}
+		case "'unsafe-eval'":
+			deduct(5, "script-src allows 'unsafe-eval'")
+		case "*", "http:", "https:", "data:":
+			deduct(10, fmt.Sprintf("script-src allows scripts from any source with %s", source))
+		}
+	}
+	directives := slices.Sorted(maps.Keys(policy.Directives))
+	for _, directive := range directives {
+		if directive == "script-src" || directive == "default-src" && policy.Directives["script-src"] == nil {
+			continue
+		}
+		if slices.Contains(policy.Directives[directive], "*") {
+			check.Issues = append(check.Issues, fmt.Sprintf("%s allows any source with *", directive))
+		}
+	}
+	if objects, ok := policy.sources("object-src"); !ok || !slices.Equal(objects, []string{"'none'"}) {
+		deduct(2, "object-src is not 'none', so plugins can load")
+	}
+	if _, ok := policy.Directives["base-uri"]; !ok {
+		check.Issues = append(check.Issues, "No base-uri, so injected <base> elements can redirect relative URLs")
+	}
+	return check.finish()
+}
+
+func auditHSTS(value string, https bool) SecurityHeaderCheck {
+	check := SecurityHeaderCheck{Header: "Strict-Transport-Security", Value: value, MaxPoints: 20,
+		Recommendation: "Serve the page over HTTPS with Strict-Transport-Security: max-age=31536000; includeSubDomains"}
+	if !https {
+		check.Issues = []string{"The page is not served over HTTPS"}
+		return check.finish()
+	}
+	if value == "" {
+		check.Issues = []string{"No Strict-Transport-Security"}
+		return check.finish()
+	}
+	maxAge := -1
+	subdomains := false
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			if n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(arg), `"`)); err == nil {
+				maxAge = n
+			}
+		case "includesubdomains":
+			subdomains = true
+		}
+	}
+	check.Points = 20
+	switch {
+	case maxAge < 0:
+		check.Points = 0
+		check.Issues = append(check.Issues, "max-age is missing or invalid")
+	case maxAge == 0:
+		check.Points = 0
+		check.Issues = append(check.Issues, "max-age=0 disables HSTS")
+	case maxAge < minHSTSMaxAge:
+		check.Points = 10
+		check.Issues = append(check.Issues, fmt.Sprintf("max-age=%d is shorter than 180 days", maxAge))
+	}
+	if !subdomains && check.Points > 0 {
+		check.Points -= 2
+		check.Issues = append(check.Issues, "includeSubDomains is not set")
+	}
+	return check.finish()
+}
+
+func auditFraming(value string, policy *ContentSecurityPolicy) SecurityHeaderCheck {
+	check := SecurityHeaderCheck{Header: "X-Frame-Options", Value: value, MaxPoints: 15,
+		Recommendation: "Send Content-Security-Policy: frame-ancestors 'self' (or X-Frame-Options: SAMEORIGIN) to prevent clickjacking"}
+	if policy != nil && !policy.ReportOnly {
+		if ancestors, ok := policy.Directives["frame-ancestors"]; ok {
+			check.Header = "frame-ancestors"
+			check.Value = strings.Join(ancestors, " ")
+			check.Points = 15
+			if slices.Contains(ancestors, "*") {
+				check.Points = 0
+				check.Issues = append(check.Issues, "frame-ancestors allows any site to frame the page")
+			}
+			return check.finish()
+		}
+	}
+	switch strings.ToUpper(strings.TrimSpace(value)) {
+	case "DENY", "SAMEORIGIN":
+		check.Points = 15
+	case "":
+		check.Issues = append(check.Issues, "Neither X-Frame-Options nor frame-ancestors prevents framing")
+	default:
+		check.Issues = append(check.Issues, fmt.Sprintf("X-Frame-Options %q is not supported by current browsers", value))
+	}
+	return check.finish()
+}
+
+// auditValue checks a header whose value should be one of good. A bad value
+// scores nothing, an unknown one half the points and none missing points.
+func auditValue(name, value string, points, missing int, good, bad []string, recommendation string) SecurityHeaderCheck {
+	check := SecurityHeaderCheck{Header: name, Value: value, MaxPoints: points, Recommendation: recommendation}
+	// Referrer-Policy may list fallbacks; the last one the browser knows wins.
+	values := strings.Split(strings.ToLower(value), ",")
+	effective := strings.TrimSpace(values[len(values)-1])
+	switch {
+	case value == "":
+		check.Points = missing
+		check.Issues = append(check.Issues, "No "+name)
+	case slices.Contains(good, effective):
+		check.Points = points
+	case slices.Contains(bad, effective):
+		check.Issues = append(check.Issues, fmt.Sprintf("%s %q is weak", name, effective))
+	default:
+		check.Points = points / 2
+		check.Issues = append(check.Issues, fmt.Sprintf("Unrecognized value %q", value))
+	}
+	return check.finish()
+}
+
+func auditPresent(name, value string, points int, recommendation string) SecurityHeaderCheck {
+	check := SecurityHeaderCheck{Header: name, Value: value, MaxPoints: points, Points: points, Recommendation: recommendation}
+	if strings.TrimSpace(value) == "" {
+		check.Points = 0
+		check.Issues = []string{"No " + name}
+	}
+	return check.finish()
+}
+
+// auditCookies checks the flags of each cookie. Every cookie with an issue
+// costs a point of the check.
+func auditCookies(lines []string, https bool) ([]CookieCheck, SecurityHeaderCheck) {
+	check := SecurityHeaderCheck{Header: "Set-Cookie", MaxPoints: 5, Points: 5,
+		Recommendation: "Set cookies with Secure, HttpOnly unless scripts need them, and SameSite=Lax or Strict"}
+	var cookies []CookieCheck
+	for _, line := range lines {
+		cookie, err := http.ParseSetCookie(line)
+		if err != nil {
+			continue
+		}
+		c := CookieCheck{Name: cookie.Name, Secure: cookie.Secure, HTTPOnly: cookie.HttpOnly}
+		switch cookie.SameSite {
+		case http.SameSiteStrictMode:
+			c.SameSite = "Strict"
+		case http.SameSiteLaxMode:
+			c.SameSite = "Lax"
+		case http.SameSiteNoneMode:
+			c.SameSite = "None"
+		}
+		if https && !c.Secure {
+			c.Issues = append(c.Issues, "not Secure, so it is also sent over plain HTTP")
+		}
+		if !c.HTTPOnly {
+			c.Issues = append(c.Issues, "not HttpOnly, so scripts can read it")
+		}
+		switch {
+		case c.SameSite == "":
+			c.Issues = append(c.Issues, "no SameSite attribute")
+		case c.SameSite == "None" && !c.Secure:
+			c.Issues = append(c.Issues, "SameSite=None without Secure is rejected by browsers")
+		}
+		if len(c.Issues) > 0 {
+			check.Points--
+			check.Issues = append(check.Issues, fmt.Sprintf("Cookie %s is %s", c.Name, strings.Join(c.Issues, ", ")))
+		}
+		cookies = append(cookies, c)
+	}
+	if len(cookies) > 0 {
+		check.Value = fmt.Sprintf("%d %s", len(cookies), plural(len(cookies), "cookie", "cookies"))
+	}
+	return cookies, check.finish()
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func securityCheck(t *testing.T, report *SecurityReport, header string) SecurityHeaderCheck {
+	t.Helper()
+	for _, check := range report.Checks {
+		if check.Header == header {
+			return check
+		}
+	}
+	t.Fatalf("No check for %s in %+v", header, report.Checks)
+	return SecurityHeaderCheck{}
+}
+
+func TestAuditSecurityHeaders_Strong(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'nonce-abc' 'unsafe-inline'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'")
+	header.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains; preload")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Referrer-Policy", "no-referrer, strict-origin-when-cross-origin")
+	header.Set("Permissions-Policy", "camera=(), geolocation=()")
+	header.Set("Cross-Origin-Opener-Policy", "same-origin")
+	header.Set("Cross-Origin-Embedder-Policy", "require-corp")
+	header.Add("Set-Cookie", "session=1; Secure; HttpOnly; SameSite=Lax")
+
+	report := AuditSecurityHeaders(header, "https://example.com/")
+	if report.Score != 100 || report.Grade != "A+" {
+		for _, check := range report.Checks {
+			t.Logf("%+v", check)
+		}
+		t.Fatalf("Expected a perfect score, got %d (%s)", report.Score, report.Grade)
+	}
+	if framing := securityCheck(t, report, "frame-ancestors"); framing.Status != SecurityPass || framing.Value != "'none'" {
+		t.Errorf("Expected frame-ancestors to protect against framing, got %+v", framing)
+	}
+	if !slices.Equal(report.CSP.Directives["script-src"], []string{"'self'", "'nonce-abc'", "'unsafe-inline'"}) {
+		t.Errorf("Expected the parsed policy, got %+v", report.CSP)
+	}
+	if len(report.Cookies) != 1 || !report.Cookies[0].Secure || !report.Cookies[0].HTTPOnly || report.Cookies[0].SameSite != "Lax" {
+		t.Errorf("Unexpected cookies %+v", report.Cookies)
+	}
+}
+
+func TestAuditSecurityHeaders_Weak(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Content-Security-Policy", "default-src *; script-src 'self' 'unsafe-inline' 'unsafe-eval' https:")
+	header.Set("Strict-Transport-Security", "max-age=86400")
+	header.Set("X-Frame-Options", "ALLOW-FROM https://partner.example.com")
+	header.Set("Referrer-Policy", "unsafe-url")
+	header.Add("Set-Cookie", "tracking=1")
+	header.Add("Set-Cookie", "prefs=dark; Secure; HttpOnly; SameSite=None")
+
+	report := AuditSecurityHeaders(header, "https://example.com/")
+	if report.Grade != "F" {
+		t.Errorf("Expected an F, got %d (%s)", report.Score, report.Grade)
+	}
+
+	csp := securityCheck(t, report, "Content-Security-Policy")
+	for _, issue := range []string{"'unsafe-inline'", "'unsafe-eval'", "with https:", "default-src allows any source", "object-src"} {
+		if !slices.ContainsFunc(csp.Issues, func(s string) bool { return strings.Contains(s, issue) }) {
+			t.Errorf("Expected a CSP issue about %s, got %v", issue, csp.Issues)
+		}
+	}
+	if csp.Points != 0 || csp.Status != SecurityFail || csp.Recommendation == "" {
+		t.Errorf("Expected the CSP check to fail with a recommendation, got %+v", csp)
+	}
+	if hsts := securityCheck(t, report, "Strict-Transport-Security"); hsts.Status != SecurityWarn || hsts.Points != 8 {
+		t.Errorf("Expected a short HSTS max-age without includeSubDomains to score 8, got %+v", hsts)
+	}
+	if framing := securityCheck(t, report, "X-Frame-Options"); framing.Points != 0 {
+		t.Errorf("Expected ALLOW-FROM to score nothing, got %+v", framing)
+	}
+	if referrer := securityCheck(t, report, "Referrer-Policy"); referrer.Points != 0 {
+		t.Errorf("Expected unsafe-url to score nothing, got %+v", referrer)
+	}
+	if nosniff := securityCheck(t, report, "X-Content-Type-Options"); nosniff.Status != SecurityFail {
+		t.Errorf("Expected a missing nosniff to fail, got %+v", nosniff)
+	}
+	if cookies := securityCheck(t, report, "Set-Cookie"); cookies.Points != 4 || len(cookies.Issues) != 1 || !strings.Contains(cookies.Issues[0], "tracking") {
+		t.Errorf("Expected only the tracking cookie to cost a point, got %+v", cookies)
+	}
+}
+
+func TestAuditSecurityHeaders_PlainHTTP(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Strict-Transport-Security", "max-age=31536000")
+	header.Set("Content-Security-Policy-Report-Only", "default-src 'self'; object-src 'none'; base-uri 'self'")
+	header.Add("Set-Cookie", "session=1; HttpOnly; SameSite=Strict")
+
+	report := AuditSecurityHeaders(header, "http://example.com/")
+	if hsts := securityCheck(t, report, "Strict-Transport-Security"); hsts.Points != 0 || hsts.Issues[0] != "The page is not served over HTTPS" {
+		t.Errorf("Expected HSTS to fail without HTTPS, got %+v", hsts)
+	}
+	if csp := securityCheck(t, report, "Content-Security-Policy-Report-Only"); csp.Points != 5 || csp.Status != SecurityWarn || !report.CSP.ReportOnly {
+		t.Errorf("Expected a report-only policy to score 5, got %+v", csp)
+	}
+	if cookies := securityCheck(t, report, "Set-Cookie"); cookies.Status != SecurityPass {
+		t.Errorf("Expected a cookie without Secure to be fine over plain HTTP, got %+v", cookies)
+	}
+}
+
+func TestAnalyzePage_SecurityHeaders(t *testing.T) {
+	mockClient := &MockHTTPClient{
+		DoFunc: func(req *http.Request) (*http.Response, error) {
+			response := createMockResponse(200, sampleHTML)
+			response.Header.Set("X-Content-Type-Options", "nosniff")
+			return response, nil
+		},
+	}
+	service := &AnalysisService{httpClient: mockClient}
+	result, err := service.AnalyzePage(context.Background(), "https://example.com/test")
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+	if result.Security == nil || securityCheck(t, result.Security, "X-Content-Type-Options").Status != SecurityPass {
+		t.Errorf("Expected the response headers to be audited, got %+v", result.Security)
+	}
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/snpiyasooriya/web-page-analyzer/internal/analyzer"
+	"github.com/snpiyasooriya/web-page-analyzer/internal/logger"
+)
+
+const defaultSiteBaseURL = "http://localhost/"
+
+// Kinds of problems found in a site.
+const (
+	SiteMissingFile    = "missing_file"
+	SiteMissingAnchor  = "missing_anchor"
+	SiteBrokenExternal = "broken_external"
+)
+
+// SiteOptions tunes the analysis of a static site directory.
+type SiteOptions struct {
+	// BaseURL is the URL the directory is served at. Absolute links under it
+	// are resolved against the directory like relative ones. Defaults to
+	// http://localhost/.
+	BaseURL string
+	// CheckExternal checks links outside BaseURL over HTTP. Without it the
+	// analysis needs no network access.
+	CheckExternal bool
+}
+
+// SitePage summarizes one HTML file of a site.
+type SitePage struct {
+	Path          string `json:"path"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	InternalLinks int    `json:"internal_links"`
+	ExternalLinks int    `json:"external_links"`
+}
+
+// SiteProblem is a broken link found in a site.
+type SiteProblem struct {
+	// Page is the path of the linking file, relative to the site root.
+	Page string `json:"page"`
+	// Link is the href as written.
+	Link string `json:"link"`
+	Kind string `json:"kind"`
+	// Target is the file the link resolved to, or the external URL.
+	Target string `json:"target"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SiteReport is the outcome of analyzing a site directory.
+type SiteReport struct {
+	Root     string        `json:"root"`
+	BaseURL  string        `json:"base_url"`
+	Pages    []SitePage    `json:"pages"`
+	Problems []SiteProblem `json:"problems"`
+	// ExternalLinks is the number of distinct links outside the site.
+	ExternalLinks int `json:"external_links"`
+	// ExternalChecked reports whether external links were checked.
+	ExternalChecked bool `json:"external_checked"`
+}
+
+// sitePage is a parsed HTML file of the site being analyzed.
+type sitePage struct {
+	path    string
+	url     *url.URL
+	result  *analyzer.AnalysisResult
+	anchors map[string]bool
+}
+
+// externalRef is a link out of the site and where it appears.
+type externalRef struct {
+	page string
+	link string
+}
+
+// AnalyzeSite walks root for HTML files, treating it as a site served at
+// options.BaseURL. Internal links are resolved against the filesystem and
+// their fragments against the anchors of the target page.
+func (s *AnalysisService) AnalyzeSite(ctx context.Context, root string, options SiteOptions) (*SiteReport, error) {
+	if options.BaseURL == "" {
+		options.BaseURL = defaultSiteBaseURL
+	}
+	base, err := url.Parse(options.BaseURL)
+	if err != nil || (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: expected an absolute http or https URL", options.BaseURL)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	base.RawQuery, base.Fragment = "", ""
+
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	ctx = logger.ContextWithFields(ctx, logrus.Fields{"site_root": root, "job_id": newID()})
+	pages, err := loadSitePages(ctx, root, base)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]*sitePage, len(pages))
+	for _, page := range pages {
+		byPath[page.path] = page
+	}
+
+	report := &SiteReport{Root: root, BaseURL: base.String(), ExternalChecked: options.CheckExternal, Problems: []SiteProblem{}}
+	external := make(map[string][]externalRef)
+	var externalOrder []string
+	for _, page := range pages {
+		summary := SitePage{Path: page.path, URL: page.url.String(), Title: page.result.Title}
+		for _, link := range page.result.Links {
+			target, err := page.url.Parse(link)
+			if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+				continue
+			}
+			rel, internal := siteRelativePath(base, target)
+			if !internal {
+				summary.ExternalLinks++
+				key := target.String()
+				if _, ok := external[key]; !ok {
+					externalOrder = append(externalOrder, key)
+				}
+				external[key] = append(external[key], externalRef{page.path, link})
+				continue
+			}
+			summary.InternalLinks++
+			if problem, ok := checkSiteLink(root, byPath, page, link, rel, target.Fragment); !ok {
+				report.Problems = append(report.Problems, problem)
+			}
+		}
+		report.Pages = append(report.Pages, summary)
+	}
+	report.ExternalLinks = len(externalOrder)
+
+	if options.CheckExternal && len(externalOrder) > 0 {
+		for _, result := range s.checkLinks(ctx, externalOrder) {
+			if result.Accessible || result.HostUnavailable {
+				continue
+			}
+			for _, ref := range external[result.URL] {
+				report.Problems = append(report.Problems, SiteProblem{
+					Page: ref.page, Link: ref.link, Kind: SiteBrokenExternal, Target: result.URL, Detail: linkStatus(result),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		return report.Problems[i].Page < report.Problems[j].Page
+	})
+	logger.WithContext(ctx).WithFields(logrus.Fields{
+		"pages":    len(report.Pages),
+		"problems": len(report.Problems),
+	}).Info("Site analysis completed")
+	return report, nil
+}
+
+// loadSitePages parses every .html and .htm file under root, in lexical order.
+func loadSitePages(ctx context.Context, root string, base *url.URL) ([]*sitePage, error) {
+	var pages []*sitePage
+	err := filepath.WalkDir(root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || !isHTMLFile(name) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		result, err := analyzer.AnalyzeContext(ctx, file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+
+		pages = append(pages, &sitePage{
+			path:    rel,
+			url:     base.ResolveReference(&url.URL{Path: rel}),
+			result:  result,
+			anchors: anchorSet(result.Anchors),
+		})
+		return nil
+	})
+	return pages, err
+}
+
+// siteRelativePath returns the path of target relative to base when target
+// lies under it.
+func siteRelativePath(base, target *url.URL) (string, bool) {
+	if !strings.EqualFold(target.Scheme, base.Scheme) || !strings.EqualFold(target.Host, base.Host) {
+		return "", false
+	}
+	targetPath := target.Path
+	if targetPath == "" {
+		targetPath = "/"
+	}
+	if targetPath+"/" == base.Path {
+		return "", true
+	}
+	rel, ok := strings.CutPrefix(targetPath, base.Path)
+	return rel, ok
+}
+
+// checkSiteLink resolves rel to a file the way a static file server would
+// (directories serve index.html, extensionless paths may be .html files) and
+// checks fragment against the target page's anchors.
+func checkSiteLink(root string, pages map[string]*sitePage, from *sitePage, link, rel, fragment string) (SiteProblem, bool) {
+	problem := SiteProblem{Page: from.path, Link: link}
+	file, ok := resolveSiteFile(root, rel)
+	if !ok {
+		problem.Kind = SiteMissingFile
+		problem.Target = path.Clean("/" + rel)[1:]
+		if problem.Target == "" {
+			problem.Target = "index.html"
+		}
+		return problem, false
+	}
+	target, ok := pages[file]
+	if !ok || fragmentExists(target.anchors, fragment) {
+		// Fragments of non-HTML files, such as PDFs, cannot be checked.
+		return problem, true
+	}
+	problem.Kind = SiteMissingAnchor
+	problem.Target = file
+	problem.Detail = fmt.Sprintf("no element with id or name %q", fragment)
+	return problem, false
+}
+
+// resolveSiteFile returns the slash-separated path, relative to root, of the
+// file served for rel.
+func resolveSiteFile(root, rel string) (string, bool) {
+	clean := path.Clean("/" + rel)[1:]
+	candidates := []string{clean}
+	if clean == "" {
+		candidates = []string{"index.html"}
+	} else if strings.HasSuffix(rel, "/") {
+		candidates = []string{clean + "/index.html"}
+	} else {
+		candidates = append(candidates, clean+"/index.html")
+		if path.Ext(clean) == "" {
+			candidates = append(candidates, clean+".html")
+		}
+	}
+	for _, candidate := range candidates {
+		info, err := os.Stat(filepath.Join(root, filepath.FromSlash(candidate)))
+		if err == nil && !info.IsDir() {
+			return candidate, true
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+func isHTMLFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSite(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestAnalyzeSite_ResolvesLinksAgainstFiles(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"index.html": `<title>Home</title>
+			<a href="docs/">Docs</a>
+			<a href="/docs/install#linux">Linux</a>
+			<a href="/docs/install#mac">Mac</a>
+			<a href="https://example.com/docs/guide.pdf#page=2">Guide</a>
+			<a href="blog/post.html">Post</a>
+			<a href="#top">Top</a>
+			<a href="#missing">Missing</a>
+			<a href="mailto:docs@example.com">Mail</a>
+			<a href="https://other.example.org/">Other</a>`,
+		"docs/index.html":   `<title>Docs</title><a href="../index.html">Home</a><a href="../../outside.html">Up</a>`,
+		"docs/install.html": `<title>Install</title><h2 id="linux">Linux</h2><a name="legacy"></a>`,
+		"docs/guide.pdf":    "%PDF",
+		"styles/site.css":   "body {}",
+	})
+	service := &AnalysisService{httpClient: &MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		t.Errorf("Unexpected request to %s", req.URL)
+		return createMockResponse(200, ""), nil
+	}}}
+
+	report, err := service.AnalyzeSite(context.Background(), root, SiteOptions{BaseURL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("AnalyzeSite() returned error: %v", err)
+	}
+
+	if len(report.Pages) != 3 || report.Pages[0].Path != "docs/index.html" || report.Pages[2].URL != "https://example.com/index.html" {
+		t.Errorf("Unexpected pages: %+v", report.Pages)
+	}
+	var got []string
+	for _, problem := range report.Problems {
+		got = append(got, problem.Page+" "+problem.Kind+" "+problem.Link)
+	}
+	expected := []string{
+		// Links above the site root are clamped to it, like a file server does.
+		"docs/index.html missing_file ../../outside.html",
+		"index.html missing_anchor /docs/install#mac",
+		"index.html missing_file blog/post.html",
+		"index.html missing_anchor #missing",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected problems:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+	if report.ExternalLinks != 1 || report.ExternalChecked {
+		t.Errorf("Expected one unchecked external link, got %+v", report)
+	}
+}
+
+func TestAnalyzeSite_ChecksExternalLinks(t *testing.T) {
+	root := writeSite(t, map[string]string{
+		"index.html": `<a href="https://broken.example.org/">A</a><a href="https://ok.example.org/">B</a>`,
+		"about.html": `<a href="https://broken.example.org/">A</a>`,
+	})
+	service := &AnalysisService{httpClient: &MockHTTPClient{DoFunc: func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "broken.example.org" {
+			return createMockResponse(404, ""), nil
+		}
+		return createMockResponse(200, ""), nil
+	}}}
+
+	report, err := service.AnalyzeSite(context.Background(), root, SiteOptions{CheckExternal: true})
+	if err != nil {
+		t.Fatalf("AnalyzeSite() returned error: %v", err)
+	}
+	if len(report.Problems) != 2 {
+		t.Fatalf("Expected the broken link reported on both pages, got %+v", report.Problems)
+	}
+	for _, problem := range report.Problems {
+		if problem.Kind != SiteBrokenExternal || problem.Detail != "HTTP 404" {
+			t.Errorf("Unexpected problem: %+v", problem)
+		}
+	}
+}
+
+func TestAnalyzeSite_InvalidInput(t *testing.T) {
+	service := &AnalysisService{}
+	if _, err := service.AnalyzeSite(context.Background(), t.TempDir(), SiteOptions{BaseURL: "example.com"}); err == nil {
+		t.Error("Expected a relative base URL to be rejected")
+	}
+	file := filepath.Join(t.TempDir(), "index.html")
+	os.WriteFile(file, nil, 0o644)
+	if _, err := service.AnalyzeSite(context.Background(), file, SiteOptions{}); err == nil {
+		t.Error("Expected a file root to be rejected")
+	}
+}
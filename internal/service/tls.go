@@ -0,0 +1,166 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// TLS failure classes of link checks and page fetches.
+const (
+	TLSExpired            = "expired"
+	TLSNotYetValid        = "not-yet-valid"
+	TLSHostnameMismatch   = "hostname-mismatch"
+	TLSUnknownAuthority   = "unknown-authority"
+	TLSInvalidCertificate = "invalid-certificate"
+	TLSHandshakeFailure   = "handshake-failure"
+)
+
+// defaultTLSExpiryWindow is how close to expiry a certificate is reported.
+const defaultTLSExpiryWindow = 30 * 24 * time.Hour
+
+// TLSInfo describes the TLS connection to a host and the certificate it
+// presented.
+type TLSInfo struct {
+	Host string `json:"host"`
+	// Version and CipherSuite are only known for connections that were
+	// established.
+	Version     string    `json:"version,omitempty"`
+	CipherSuite string    `json:"cipher_suite,omitempty"`
+	Subject     string    `json:"subject,omitempty"`
+	Issuer      string    `json:"issuer,omitempty"`
+	DNSNames    []string  `json:"dns_names,omitempty"`
+	NotBefore   time.Time `json:"not_before"`
+	NotAfter    time.Time `json:"not_after"`
+	// ChainValid is set when the certificate chain verified for Host.
+	ChainValid bool `json:"chain_valid"`
+	// Failure classifies, and Error explains, why it did not.
+	Failure string `json:"failure,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// DaysRemaining, Expired and ExpiresSoon are worked out when the result
+	// is assembled; ExpiresSoon is set within the configured warning window.
+	DaysRemaining int  `json:"days_remaining"`
+	Expired       bool `json:"expired,omitempty"`
+	ExpiresSoon   bool `json:"expires_soon,omitempty"`
+}
+
+// tlsHostOf returns the host whose TLS details a check of link reports: the
+// host of the last request made, the one that got resp or failed with err,
+// which differs from link's after a redirect. It returns "" when that request
+// was not made over HTTPS.
+func tlsHostOf(link string, resp *http.Response, err error) string {
+	var urlErr *url.Error
+	switch {
+	case resp != nil && resp.Request != nil && resp.Request.URL != nil:
+		link = resp.Request.URL.String()
+	case errors.As(err, &urlErr):
+		link = urlErr.URL
+	}
+	u, parseErr := url.Parse(link)
+	if parseErr != nil || !strings.EqualFold(u.Scheme, "https") {
+		return ""
+	}
+	return u.Host
+}
+
+// tlsInfoFromState describes an established connection to host, or returns
+// nil if host is empty.
+func tlsInfoFromState(host string, state *tls.ConnectionState) *TLSInfo {
+	if host == "" || state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+	info := describeCertificate(host, state.PeerCertificates[0])
+	info.Version = tls.VersionName(state.Version)
+	info.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	info.ChainValid = len(state.VerifiedChains) > 0
+	return info
+}
+
+// tlsInfoFromError describes the certificate of a connection to host that
+// failed verification, or returns nil if host is empty or err is not a
+// verification error.
+func tlsInfoFromError(host string, err error) *TLSInfo {
+	var verification *tls.CertificateVerificationError
+	if host == "" || !errors.As(err, &verification) || len(verification.UnverifiedCertificates) == 0 {
+		return nil
+	}
+	info := describeCertificate(host, verification.UnverifiedCertificates[0])
+	info.Failure = classifyTLSError(err)
+	info.Error = verification.Err.Error()
+	return info
+}
+
+func describeCertificate(host string, cert *x509.Certificate) *TLSInfo {
+	return &TLSInfo{
+		Host:      host,
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		DNSNames:  cert.DNSNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
+
+// assess works out the expiry fields of info at now.
+func (info *TLSInfo) assess(now time.Time, window time.Duration) {
+	remaining := info.NotAfter.Sub(now)
+	info.DaysRemaining = int(remaining.Hours() / 24)
+	info.Expired = remaining < 0
+	info.ExpiresSoon = !info.Expired && remaining < window
+}
+
+// classifyTLSError returns the TLS failure class of err, or "" if err is not
+// a TLS failure.
+func classifyTLSError(err error) string {
+	if err == nil {
+		return ""
+	}
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var authority x509.UnknownAuthorityError
+	var verification *tls.CertificateVerificationError
+	var alert tls.AlertError
+	var record tls.RecordHeaderError
+	switch {
+	case errors.As(err, &invalid):
+		if invalid.Reason != x509.Expired {
+			return TLSInvalidCertificate
+		}
+		if invalid.Cert != nil && time.Now().Before(invalid.Cert.NotBefore) {
+			return TLSNotYetValid
+		}
+		return TLSExpired
+	case errors.As(err, &hostname):
+		return TLSHostnameMismatch
+	case errors.As(err, &authority):
+		return TLSUnknownAuthority
+	case errors.As(err, &verification):
+		return TLSInvalidCertificate
+	case errors.As(err, &alert), errors.As(err, &record), strings.Contains(err.Error(), "tls: "):
+		return TLSHandshakeFailure
+	}
+	return ""
+}
+
+// tlsHosts lists the TLS details of the hosts of HTTPS links, once per host
+// and sorted by host.
+func tlsHosts(results []LinkCheckResult, now time.Time, window time.Duration) []TLSInfo {
+	seen := make(map[string]bool)
+	var hosts []TLSInfo
+	for _, result := range results {
+		if result.tls == nil || seen[result.tls.Host] {
+			continue
+		}
+		seen[result.tls.Host] = true
+		info := *result.tls
+		info.assess(now, window)
+		hosts = append(hosts, info)
+	}
+	slices.SortFunc(hosts, func(a, b TLSInfo) int { return strings.Compare(a.Host, b.Host) })
+	return hosts
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newExpiredTLSServer starts a TLS server with a self-signed certificate that
+// expired yesterday.
+func newExpiredTLSServer(t *testing.T) (*httptest.Server, *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "expired.test"},
+		DNSNames:              []string{"expired.test"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              time.Now().Add(-24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server, cert
+}
+
+func TestAnalyzePage_TLS(t *testing.T) {
+	expired, expiredCert := newExpiredTLSServer(t)
+	page := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>TLS</title></head><body><a href="` + expired.URL + `/gone">Expired</a></body></html>`))
+	}))
+	defer page.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(page.Certificate())
+	roots.AddCert(expiredCert)
+	service := &AnalysisService{
+		httpClient: &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}},
+		// The test certificate is valid for decades.
+		tlsExpiryWindow: 100 * 365 * 24 * time.Hour,
+	}
+	result, err := service.AnalyzePage(context.Background(), page.URL)
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	info := result.TLS
+	if info == nil || !info.ChainValid || info.Version == "" || info.CipherSuite == "" || len(info.DNSNames) == 0 || info.Issuer == "" {
+		t.Fatalf("Expected the page's TLS details, got %+v", info)
+	}
+	if !info.ExpiresSoon || info.Expired || info.DaysRemaining <= 0 {
+		t.Errorf("Expected the certificate to expire within the window, got %+v", info)
+	}
+
+	if len(result.LinkResults) != 1 || result.LinkResults[0].Accessible || result.LinkResults[0].TLSFailure != TLSExpired {
+		t.Fatalf("Expected the link to fail with an expired certificate, got %+v", result.LinkResults)
+	}
+	if len(result.LinkHostsTLS) != 1 {
+		t.Fatalf("Expected the link host's TLS details, got %+v", result.LinkHostsTLS)
+	}
+	host := result.LinkHostsTLS[0]
+	if host.Host != hostOf(expired.URL) || host.ChainValid || !host.Expired || host.Failure != TLSExpired || host.Subject != "CN=expired.test" {
+		t.Errorf("Unexpected link host TLS details %+v", host)
+	}
+
+	var expiring, broken string
+	for _, check := range reportChecks(result) {
+		switch check.Rule {
+		case RuleCertificateExpiring:
+			expiring = check.Name
+		case RuleBrokenLink:
+			broken = check.Message
+		}
+	}
+	if expiring != hostOf(page.URL) {
+		t.Errorf("Expected an expiring certificate check for the page host, got %q", expiring)
+	}
+	if !strings.HasPrefix(broken, "Link is inaccessible: TLS expired") {
+		t.Errorf("Expected the broken link to name the TLS failure, got %q", broken)
+	}
+}
+
+func TestFetchPage_TLSFailure(t *testing.T) {
+	page := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer page.Close()
+
+	// The default roots do not trust the test certificate.
+	service := &AnalysisService{httpClient: &http.Client{}}
+	_, err := service.AnalyzePage(context.Background(), page.URL)
+	if err == nil || classifyTLSError(err) != TLSUnknownAuthority {
+		t.Fatalf("Expected an unknown authority failure, got %v", err)
+	}
+	if got := err.Error(); !strings.HasPrefix(got, "TLS unknown-authority: ") {
+		t.Errorf("Expected the error to name the TLS failure, got %q", got)
+	}
+}
+
+func TestAnalyzePage_LinkHostsTLSFollowRedirects(t *testing.T) {
+	expired, expiredCert := newExpiredTLSServer(t)
+	target := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+	toTarget := httptest.NewTLSServer(http.RedirectHandler(target.URL+"/moved", http.StatusFound))
+	defer toTarget.Close()
+	toExpired := httptest.NewServer(http.RedirectHandler(expired.URL+"/gone", http.StatusFound))
+	defer toExpired.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+
+	var links strings.Builder
+	for _, link := range []string{toTarget.URL, toExpired.URL, plain.URL} {
+		links.WriteString(`<a href="` + link + `/">link</a>`)
+	}
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Redirects</title></head><body>` + links.String() + `</body></html>`))
+	}))
+	defer page.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(target.Certificate())
+	roots.AddCert(expiredCert)
+	service := &AnalysisService{
+		httpClient: &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}},
+	}
+	result, err := service.AnalyzePage(context.Background(), page.URL)
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	// Each redirect reports the host it ended on; the HTTP link and the hosts
+	// that only redirected report nothing.
+	hosts := make(map[string]TLSInfo)
+	for _, info := range result.LinkHostsTLS {
+		hosts[info.Host] = info
+	}
+	if len(hosts) != 2 {
+		t.Fatalf("Expected the TLS details of 2 hosts, got %+v", result.LinkHostsTLS)
+	}
+	if info, ok := hosts[hostOf(target.URL)]; !ok || !info.ChainValid {
+		t.Errorf("Expected the redirect target's TLS details, got %+v", result.LinkHostsTLS)
+	}
+	if info, ok := hosts[hostOf(expired.URL)]; !ok || !info.Expired || info.Failure != TLSExpired {
+		t.Errorf("Expected the expired redirect target's TLS details, got %+v", result.LinkHostsTLS)
+	}
+}
+
+func TestAnalyzePage_TLSFailuresDoNotOpenBreaker(t *testing.T) {
+	expired, expiredCert := newExpiredTLSServer(t)
+	const links = 8
+	var html strings.Builder
+	for i := range links {
+		html.WriteString(`<a href="` + expired.URL + `/` + strconv.Itoa(i) + `">link</a>`)
+	}
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<html><head><title>Expired</title></head><body>` + html.String() + `</body></html>`))
+	}))
+	defer page.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(expiredCert)
+	service := &AnalysisService{
+		httpClient: &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}},
+		scheduler:  NewLinkScheduler(LinkSchedulerConfig{MaxConcurrent: 1, MaxPerHost: 1}),
+		breaker:    NewCircuitBreaker(DefaultCircuitBreakerConfig()),
+	}
+	result, err := service.AnalyzePage(context.Background(), page.URL)
+	if err != nil {
+		t.Fatalf("AnalyzePage() returned error: %v", err)
+	}
+
+	if len(result.LinkResults) != links {
+		t.Fatalf("Expected %d link results, got %d", links, len(result.LinkResults))
+	}
+	for _, link := range result.LinkResults {
+		if link.HostUnavailable || link.TLSFailure != TLSExpired {
+			t.Errorf("Expected %s to fail with an expired certificate, got %+v", link.URL, link)
+		}
+	}
+	if len(result.HostUnavailableLinks) != 0 {
+		t.Errorf("Expected no host unavailable links, got %v", result.HostUnavailableLinks)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers set on every webhook delivery.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Webhook posts JSON payloads to a URL. When Secret is set every request is
+// signed with HMAC-SHA256 over "<timestamp>.<body>", sent as
+// "sha256=<hex>" in X-Webhook-Signature alongside X-Webhook-Timestamp so
+// that receivers can reject forged or replayed deliveries.
+type Webhook struct {
+	URL    string
+	Secret string
+
+	client interface {
+		Do(req *http.Request) (*http.Response, error)
+	}
+	retry RetryPolicy
+	now   func() time.Time
+}
+
+// NewWebhook creates a webhook that retries transient failures with the
+// link check retry policy.
+func NewWebhook(url, secret string) *Webhook {
+	return &Webhook{
+		URL:    url,
+		Secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+		retry:  RetryPolicyFromEnv(),
+		now:    time.Now,
+	}
+}
+
+// Send delivers payload, identified by deliveryID so that receivers can
+// discard duplicates of a retried delivery.
+func (w *Webhook) Send(ctx context.Context, deliveryID string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+
+	resp, _, err := w.retry.do(ctx, w.client, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookDeliveryHeader, deliveryID)
+		if w.Secret != "" {
+			req.Header.Set(WebhookSignatureHeader, SignWebhook(w.Secret, timestamp, body))
+		}
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status code: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook returns the X-Webhook-Signature value for body sent at timestamp.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook reports whether signature is valid for body sent at timestamp.
+func VerifyWebhook(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhook_SignsAndRetries(t *testing.T) {
+	var attempts atomic.Int32
+	var verified atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verified.Store(VerifyWebhook("s3cret", r.Header.Get(WebhookTimestampHeader), body, r.Header.Get(WebhookSignatureHeader)) &&
+			r.Header.Get(WebhookDeliveryHeader) == "delivery-1")
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL, "s3cret")
+	webhook.retry = RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	if err := webhook.Send(context.Background(), "delivery-1", map[string]string{"event": "test"}); err != nil {
+		t.Fatalf("Send() returned error: %v", err)
+	}
+	if attempts.Load() != 2 {
+		t.Errorf("Expected a retry after 503, got %d attempts", attempts.Load())
+	}
+	if !verified.Load() {
+		t.Error("Expected a valid signature and delivery ID")
+	}
+}
+
+func TestWebhook_FailureStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	webhook := NewWebhook(server.URL, "")
+	if err := webhook.Send(context.Background(), "id", struct{}{}); err == nil {
+		t.Error("Expected an error for a 400 response")
+	}
+}
+
+func TestVerifyWebhook_RejectsTampering(t *testing.T) {
+	body := []byte(`{"event":"alert.firing"}`)
+	signature := SignWebhook("s3cret", "1700000000", body)
+
+	if !VerifyWebhook("s3cret", "1700000000", body, signature) {
+		t.Fatal("Expected signature to verify")
+	}
+	if VerifyWebhook("s3cret", "1700000001", body, signature) {
+		t.Error("Expected a different timestamp to be rejected")
+	}
+	if VerifyWebhook("other", "1700000000", body, signature) {
+		t.Error("Expected a different secret to be rejected")
+	}
+	if VerifyWebhook("s3cret", "1700000000", []byte(`{}`), signature) {
+		t.Error("Expected a different body to be rejected")
+	}
+}
@@ -0,0 +1,123 @@
+// Package tracing sets up OpenTelemetry tracing for the service. Init installs
+// a tracer provider that exports spans to stdout or to an OTLP/HTTP collector
+// together with the W3C trace context propagator, and Start begins spans with
+// the service's tracer.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultOTLPEndpoint = "http://localhost:4318"
+	defaultServiceName  = "web-page-analyzer"
+	instrumentationName = "github.com/snpiyasooriya/web-page-analyzer"
+)
+
+// Exporter names accepted in Config.Exporter.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// Config selects where spans are exported.
+type Config struct {
+	// Exporter is "none", "stdout" or "otlp".
+	Exporter string
+	// Endpoint is the base URL of an OTLP/HTTP collector.
+	Endpoint string
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+	// OnError is called when spans cannot be exported.
+	OnError func(error)
+}
+
+// ConfigFromEnv reads the standard OTEL_TRACES_EXPORTER,
+// OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_SERVICE_NAME variables. Tracing is
+// disabled unless OTEL_TRACES_EXPORTER is set.
+func ConfigFromEnv() Config {
+	config := Config{
+		Exporter:    strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER")),
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	}
+	if config.Exporter == "" {
+		config.Exporter = ExporterNone
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = defaultOTLPEndpoint
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = defaultServiceName
+	}
+	return config
+}
+
+// Init installs the W3C trace context propagator and a tracer provider
+// exporting to the backend selected by config. It returns a function that
+// flushes pending spans and stops the exporter. With the "none" exporter
+// spans are not recorded, but incoming trace context is still propagated.
+func Init(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if config.OnError != nil {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(config.OnError))
+	}
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(strings.TrimSuffix(config.Endpoint, "/")+"/v1/traces"))
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q", config.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start creates a span as a child of the span or remote span context in ctx
+// and returns a context carrying the new span.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// RecordError records err on span and marks the span as failed. A nil error
+// is ignored.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// installRecorder routes spans to an in-memory exporter for the duration of
+// the test.
+func installRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+		provider.Shutdown(context.Background())
+	})
+	return exporter
+}
+
+func TestStart_ChildSpansShareTrace(t *testing.T) {
+	exporter := installRecorder(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 exported spans, got %d", len(spans))
+	}
+	childData, parentData := spans[0], spans[1]
+	if childData.SpanContext.TraceID() != parentData.SpanContext.TraceID() {
+		t.Error("Expected child to share the parent's trace ID")
+	}
+	if childData.Parent.SpanID() != parentData.SpanContext.SpanID() {
+		t.Error("Expected child's parent span ID to be the parent's span ID")
+	}
+}
+
+func TestRecordError(t *testing.T) {
+	exporter := installRecorder(t)
+
+	_, span := Start(context.Background(), "failing")
+	RecordError(span, nil)
+	RecordError(span, errors.New("boom"))
+	span.End()
+
+	data := exporter.GetSpans()[0]
+	if data.Status.Code != codes.Error || data.Status.Description != "boom" {
+		t.Errorf("Expected an error status, got %+v", data.Status)
+	}
+	if len(data.Events) != 1 {
+		t.Errorf("Expected the error to be recorded once, got %d events", len(data.Events))
+	}
+}
+
+func TestInit_PropagatesTraceContext(t *testing.T) {
+	shutdown, err := Init(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	incoming := http.Header{}
+	incoming.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(incoming))
+
+	outgoing := http.Header{}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(outgoing))
+	if got := outgoing.Get("traceparent"); got != incoming.Get("traceparent") {
+		t.Errorf("Expected the incoming trace context to be propagated, got %q", got)
+	}
+}
+
+func TestInit_OTLPExporterPostsToCollector(t *testing.T) {
+	received := make(chan string, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.URL.Path
+	}))
+	defer collector.Close()
+
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+	shutdown, err := Init(context.Background(), Config{Exporter: ExporterOTLP, Endpoint: collector.URL + "/", ServiceName: "test"})
+	if err != nil {
+		t.Fatalf("Init() returned error: %v", err)
+	}
+	_, span := Start(context.Background(), "exported")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown returned error: %v", err)
+	}
+	select {
+	case path := <-received:
+		if path != "/v1/traces" {
+			t.Errorf("Expected spans to be posted to /v1/traces, got %s", path)
+		}
+	default:
+		t.Error("Expected spans to be posted to the collector")
+	}
+}
+
+func TestInit_UnknownExporter(t *testing.T) {
+	if _, err := Init(context.Background(), Config{Exporter: "zipkin"}); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+}